@@ -0,0 +1,313 @@
+// Package carstore layers read-only CAR files under a blockstore, so that
+// large archives can be served without copying their blocks into the repo.
+package carstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	blockstore "github.com/ipfs/boxo/blockstore"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+)
+
+var log = logging.Logger("carstore")
+
+// attachedKey is the datastore key holding the JSON list of the paths of the
+// attached CAR files.
+var attachedKey = datastore.NewKey("/local/cars")
+
+// ErrNotAttached is returned when detaching a CAR file that is not attached.
+var ErrNotAttached = errors.New("CAR file is not attached")
+
+// CarInfo describes an attached CAR file.
+type CarInfo struct {
+	Path  string
+	Roots []cid.Cid
+	// Error is set if the CAR file could not be opened. Its blocks are then
+	// not available until it is detached and attached again.
+	Error string `json:",omitempty"`
+}
+
+type attachedCar struct {
+	path string
+	bs   *carblockstore.ReadOnly
+	err  error
+}
+
+// Store is a blockstore that looks up the blocks missing from the underlying
+// blockstore in the attached CAR files. Blocks are only written to, deleted
+// from and listed by the underlying blockstore: attached CAR files are never
+// modified, and their blocks are not visible to garbage collection. Blocks
+// held by an attached CAR file are not written again.
+type Store struct {
+	blockstore.Blockstore
+
+	ds         datastore.Datastore
+	hashOnRead atomic.Bool
+
+	mu   sync.RWMutex
+	cars []*attachedCar
+}
+
+var _ blockstore.Blockstore = (*Store)(nil)
+
+// New creates a Store on top of bs, and opens the CAR files previously
+// attached, as recorded in ds. CAR files that cannot be opened are logged
+// and kept attached, so that they can be listed and detached.
+func New(ctx context.Context, bs blockstore.Blockstore, ds datastore.Datastore) (*Store, error) {
+	s := &Store{
+		Blockstore: bs,
+		ds:         ds,
+	}
+
+	paths, err := s.loadPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		c := &attachedCar{path: p}
+		if c.bs, c.err = carblockstore.OpenReadOnly(p); c.err != nil {
+			log.Errorf("cannot open attached CAR file %s: %s", p, c.err)
+		}
+		s.cars = append(s.cars, c)
+	}
+	return s, nil
+}
+
+// Attach opens the CAR file at the given absolute path, and serves its
+// blocks. The index of CARv2 files is used as is, while CARv1 files, and
+// CARv2 files without an index, are indexed in memory.
+func (s *Store) Attach(ctx context.Context, path string) (CarInfo, error) {
+	if !filepath.IsAbs(path) {
+		return CarInfo{}, fmt.Errorf("path %q is not absolute", path)
+	}
+	path = filepath.Clean(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.cars, func(c *attachedCar) bool { return c.path == path }) {
+		return CarInfo{}, fmt.Errorf("CAR file %s is already attached", path)
+	}
+
+	bs, err := carblockstore.OpenReadOnly(path)
+	if err != nil {
+		return CarInfo{}, fmt.Errorf("cannot open CAR file: %w", err)
+	}
+	c := &attachedCar{path: path, bs: bs}
+	cars := append(slices.Clip(s.cars), c)
+	if err = s.savePaths(ctx, cars); err != nil {
+		bs.Close()
+		return CarInfo{}, err
+	}
+	s.cars = cars
+	return c.info(), nil
+}
+
+// Detach stops serving the blocks of the CAR file at the given path, and
+// closes it.
+func (s *Store) Detach(ctx context.Context, path string) error {
+	path = filepath.Clean(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.cars, func(c *attachedCar) bool { return c.path == path })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotAttached, path)
+	}
+	c := s.cars[i]
+	cars := slices.Delete(slices.Clone(s.cars), i, i+1)
+	if err := s.savePaths(ctx, cars); err != nil {
+		return err
+	}
+	s.cars = cars
+
+	if c.bs != nil {
+		return c.bs.Close()
+	}
+	return nil
+}
+
+// List returns the attached CAR files, in the order they were attached.
+func (s *Store) List() []CarInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]CarInfo, len(s.cars))
+	for i, c := range s.cars {
+		infos[i] = c.info()
+	}
+	return infos
+}
+
+// Close closes the attached CAR files.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, c := range s.cars {
+		if c.bs != nil {
+			errs = append(errs, c.bs.Close())
+		}
+	}
+	s.cars = nil
+	return errors.Join(errs...)
+}
+
+func (s *Store) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	has, err := s.Blockstore.Has(ctx, c)
+	if has || err != nil {
+		return has, err
+	}
+	_, err = s.findCar(ctx, c)
+	if err != nil {
+		if ipld.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Store) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	blk, err := s.Blockstore.Get(ctx, c)
+	if !ipld.IsNotFound(err) {
+		return blk, err
+	}
+	bs, err := s.findCar(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	blk, err = bs.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if s.hashOnRead.Load() {
+		sum, err := c.Prefix().Sum(blk.RawData())
+		if err != nil {
+			return nil, err
+		}
+		if !sum.Equals(c) {
+			return nil, blockstore.ErrHashMismatch
+		}
+	}
+	return blk, nil
+}
+
+func (s *Store) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	size, err := s.Blockstore.GetSize(ctx, c)
+	if !ipld.IsNotFound(err) {
+		return size, err
+	}
+	bs, err := s.findCar(ctx, c)
+	if err != nil {
+		return -1, err
+	}
+	return bs.GetSize(ctx, c)
+}
+
+// Put writes the block to the underlying blockstore, unless an attached CAR
+// file holds it already.
+func (s *Store) Put(ctx context.Context, blk blocks.Block) error {
+	if _, err := s.findCar(ctx, blk.Cid()); err == nil {
+		return nil
+	}
+	return s.Blockstore.Put(ctx, blk)
+}
+
+// PutMany writes the blocks that are not held by an attached CAR file to the
+// underlying blockstore.
+func (s *Store) PutMany(ctx context.Context, blks []blocks.Block) error {
+	missing := blks[:0:0]
+	for _, blk := range blks {
+		if _, err := s.findCar(ctx, blk.Cid()); err != nil {
+			missing = append(missing, blk)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return s.Blockstore.PutMany(ctx, missing)
+}
+
+func (s *Store) HashOnRead(enabled bool) {
+	s.hashOnRead.Store(enabled)
+	s.Blockstore.HashOnRead(enabled)
+}
+
+// findCar returns the first attached CAR file holding the block c.
+func (s *Store) findCar(ctx context.Context, c cid.Cid) (*carblockstore.ReadOnly, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, car := range s.cars {
+		if car.bs == nil {
+			continue
+		}
+		has, err := car.bs.Has(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("attached CAR file %s: %w", car.path, err)
+		}
+		if has {
+			return car.bs, nil
+		}
+	}
+	return nil, ipld.ErrNotFound{Cid: c}
+}
+
+func (s *Store) loadPaths(ctx context.Context) ([]string, error) {
+	data, err := s.ds.Get(ctx, attachedKey)
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var paths []string
+	if err = json.Unmarshal(data, &paths); err != nil {
+		return nil, fmt.Errorf("cannot read the list of attached CAR files: %w", err)
+	}
+	return paths, nil
+}
+
+func (s *Store) savePaths(ctx context.Context, cars []*attachedCar) error {
+	paths := make([]string, len(cars))
+	for i, c := range cars {
+		paths[i] = c.path
+	}
+	data, err := json.Marshal(paths)
+	if err != nil {
+		return err
+	}
+	if err = s.ds.Put(ctx, attachedKey, data); err != nil {
+		return err
+	}
+	return s.ds.Sync(ctx, attachedKey)
+}
+
+func (c *attachedCar) info() CarInfo {
+	info := CarInfo{Path: c.path}
+	if c.err != nil {
+		info.Error = c.err.Error()
+		return info
+	}
+	roots, err := c.bs.Roots()
+	if err != nil {
+		info.Error = err.Error()
+	}
+	info.Roots = roots
+	return info
+}
@@ -0,0 +1,141 @@
+package carstore
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blockstore "github.com/ipfs/boxo/blockstore"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ipld "github.com/ipfs/go-ipld-format"
+	carv2 "github.com/ipld/go-car/v2"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+)
+
+func writeCar(t *testing.T, path string, blks ...blocks.Block) {
+	rw, err := carblockstore.OpenReadWrite(path, []cid.Cid{blks[0].Cid()}, carv2.WriteAsCarV1(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rw.PutMany(context.Background(), blks); err != nil {
+		t.Fatal(err)
+	}
+	if err = rw.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	base := blockstore.NewBlockstore(ds)
+
+	inCar := blocks.NewBlock([]byte("in car"))
+	inBase := blocks.NewBlock([]byte("in base"))
+	carPath := filepath.Join(t.TempDir(), "test.car")
+	writeCar(t, carPath, inCar)
+
+	s, err := New(ctx, base, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Attach(ctx, "test.car"); err == nil {
+		t.Fatal("expected relative path to fail")
+	}
+	info, err := s.Attach(ctx, carPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Roots) != 1 || info.Roots[0] != inCar.Cid() {
+		t.Fatalf("unexpected roots %v", info.Roots)
+	}
+	if _, err = s.Attach(ctx, carPath); err == nil {
+		t.Fatal("expected attaching twice to fail")
+	}
+
+	if err = s.Put(ctx, inBase); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []blocks.Block{inCar, inBase} {
+		got, err := s.Get(ctx, b.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.RawData(), b.RawData()) {
+			t.Fatal("unexpected block data")
+		}
+		if has, _ := s.Has(ctx, b.Cid()); !has {
+			t.Fatal("expected block to be found")
+		}
+		if size, _ := s.GetSize(ctx, b.Cid()); size != len(b.RawData()) {
+			t.Fatal("unexpected block size", size)
+		}
+	}
+
+	// Blocks of attached CAR files are not copied, listed, or deleted.
+	if err = s.PutMany(ctx, []blocks.Block{inCar}); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := s.AllKeysChan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for range keys {
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 key, got %d", n)
+	}
+	if err = s.DeleteBlock(ctx, inCar.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := s.Has(ctx, inCar.Cid()); !has {
+		t.Fatal("expected block of CAR file to remain")
+	}
+
+	// Attached CAR files are persisted.
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = New(ctx, base, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := s.List(); len(l) != 1 || l[0].Path != carPath || l[0].Error != "" {
+		t.Fatalf("unexpected attached CAR files %v", l)
+	}
+	if err = s.Detach(ctx, carPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Get(ctx, inCar.Cid()); !ipld.IsNotFound(err) {
+		t.Fatal("expected not found after detach, got", err)
+	}
+	if err = s.Detach(ctx, carPath); err == nil {
+		t.Fatal("expected detaching twice to fail")
+	}
+
+	// A missing CAR file is kept attached, with an error.
+	if _, err = s.Attach(ctx, carPath); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if err = os.Remove(carPath); err != nil {
+		t.Fatal(err)
+	}
+	s, err = New(ctx, base, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := s.List(); len(l) != 1 || l[0].Error == "" {
+		t.Fatalf("expected attached CAR file with error, got %v", l)
+	}
+	if err = s.Detach(ctx, carPath); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ipfs/kubo/core/events"
+)
+
+// EventSubscription receives the events of the daemon.
+type EventSubscription struct {
+	resp        *Response
+	dec         *json.Decoder
+	lastEventID string
+}
+
+// Events subscribes to the events of the daemon of the given types, all the
+// types when none are given. Types ending with "*" match any type with the
+// same prefix. When lastEventID is set, the events emitted after it which
+// are still held by the daemon are received first, which lets subscribers
+// resume with the LastEventID of a previous subscription.
+func (api *HttpApi) Events(ctx context.Context, types []string, lastEventID string) (*EventSubscription, error) {
+	req := api.Request("events")
+	if len(types) > 0 {
+		req = req.Option("type", strings.Join(types, ","))
+	}
+	if lastEventID != "" {
+		req = req.Option("last-event-id", lastEventID)
+	}
+	resp, err := req.Option("enc", "json").Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return &EventSubscription{
+		resp:        resp,
+		dec:         json.NewDecoder(resp.Output),
+		lastEventID: lastEventID,
+	}, nil
+}
+
+// Next blocks until the next event is received. The data of the events of
+// known types has their data type, such as events.PinEvent. It returns
+// io.EOF once the subscription ends.
+func (s *EventSubscription) Next() (events.Event, error) {
+	var ev events.Event
+	if err := s.dec.Decode(&ev); err != nil {
+		return ev, err
+	}
+	if ev.ID != "" {
+		s.lastEventID = ev.ID
+	}
+	return ev, nil
+}
+
+// LastEventID returns the ID of the last event received, to resume from.
+func (s *EventSubscription) LastEventID() string {
+	return s.lastEventID
+}
+
+// Close ends the subscription.
+func (s *EventSubscription) Close() error {
+	return s.resp.Cancel()
+}
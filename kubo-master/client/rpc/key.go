@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/ipfs/boxo/ipns"
 	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/kubo/core/commands/keyencode"
 	iface "github.com/ipfs/kubo/core/coreiface"
 	caopts "github.com/ipfs/kubo/core/coreiface/options"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -58,11 +60,20 @@ func (api *KeyAPI) Generate(ctx context.Context, name string, opts ...caopts.Key
 		return nil, err
 	}
 
-	var out keyOutput
-	err = api.core().Request("key/gen", name).
+	req := api.core().Request("key/gen", name).
 		Option("type", options.Algorithm).
-		Option("size", options.Size).
-		Exec(ctx, &out)
+		Option("size", options.Size)
+	if options.Seed != nil {
+		mnemonic, err := keyencode.MnemonicFromSeed(options.Seed)
+		if err != nil {
+			return nil, err
+		}
+		req = req.Option("from-mnemonic", true).
+			FileBody(strings.NewReader(mnemonic))
+	}
+
+	var out keyOutput
+	err = req.Exec(ctx, &out)
 	if err != nil {
 		return nil, err
 	}
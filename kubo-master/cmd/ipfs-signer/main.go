@@ -0,0 +1,125 @@
+//go:build !plan9
+
+// ipfs-signer is a reference implementation of the remote signer of Kubo (see
+// Keystore.RemoteSigner). It holds keys in a local keystore directory, and
+// signs with them on behalf of the daemon.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	keystore "github.com/ipfs/boxo/keystore"
+	"github.com/ipfs/kubo/config"
+	serialize "github.com/ipfs/kubo/config/serialize"
+	"github.com/ipfs/kubo/keystore/remotesigner"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+var (
+	socketPath     = flag.String("socket", "signer.sock", "path of the unix socket to listen on")
+	keystorePath   = flag.String("keystore", "signer-keystore", "directory holding the keys")
+	genKey         = flag.String("gen", "", "generate an Ed25519 key with the given name, print its peer ID and exit")
+	importIdentity = flag.String("import-identity", "", "move Identity.PrivKey of the given Kubo config file to the \"self\" key, and exit")
+)
+
+func main() {
+	flag.Parse()
+
+	ks, err := keystore.NewFSKeystore(*keystorePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case *genKey != "":
+		err = gen(ks, *genKey)
+	case *importIdentity != "":
+		err = moveIdentity(ks, *importIdentity)
+	default:
+		err = serve(ks, *socketPath)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func gen(ks keystore.Keystore, name string) error {
+	sk, _, err := ci.GenerateEd25519Key(nil)
+	if err != nil {
+		return err
+	}
+	if err := ks.Put(name, sk); err != nil {
+		return err
+	}
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
+
+// moveIdentity stores the identity of the config file at path in ks, and
+// removes it from the config file.
+func moveIdentity(ks keystore.Keystore, path string) error {
+	var cfg map[string]interface{}
+	if err := serialize.ReadConfigFile(path, &cfg); err != nil {
+		return err
+	}
+	identity, _ := cfg[config.IdentityTag].(map[string]interface{})
+	privKey, _ := identity[config.PrivKeyTag].(string)
+	if privKey == "" {
+		return fmt.Errorf("%s has no %s", path, config.PrivKeySelector)
+	}
+
+	sk, err := (&config.Identity{PrivKey: privKey}).DecodePrivateKey("")
+	if err != nil {
+		return err
+	}
+	if err := ks.Put(remotesigner.SelfKey, sk); err != nil {
+		return err
+	}
+	delete(identity, config.PrivKeyTag)
+	if err := serialize.WriteConfigFile(path, cfg); err != nil {
+		return err
+	}
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
+
+func serve(ks keystore.Keystore, path string) error {
+	// a socket left by a previous run would prevent listening
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	// only the user running the signer may use it
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		l.Close()
+	}()
+
+	log.Printf("signing with the keys of %s, listening on %s", *keystorePath, path)
+	return remotesigner.Serve(l, ks)
+}
@@ -25,7 +25,9 @@ import (
 	config "github.com/ipfs/kubo/config"
 	cserial "github.com/ipfs/kubo/config/serialize"
 	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/auditlog"
 	commands "github.com/ipfs/kubo/core/commands"
+	"github.com/ipfs/kubo/core/commands/cmdutils"
 	"github.com/ipfs/kubo/core/coreapi"
 	corehttp "github.com/ipfs/kubo/core/corehttp"
 	options "github.com/ipfs/kubo/core/coreiface/options"
@@ -75,6 +77,7 @@ const (
 	enablePubSubKwd            = "enable-pubsub-experiment"
 	enableIPNSPubSubKwd        = "enable-namesys-pubsub"
 	enableMultiplexKwd         = "enable-mplex-experiment"
+	keystorePassphraseFileKwd  = "keystore-passphrase-file"
 	agentVersionSuffix         = "agent-version-suffix"
 	// apiAddrKwd    = "address-api"
 	// swarmAddrKwd  = "address-swarm".
@@ -154,6 +157,13 @@ environment variable:
 
   export IPFS_PATH=/path/to/ipfsrepo
 
+Encrypted keystore
+
+When the keystore was encrypted with 'ipfs key encrypt-store', the daemon
+needs its passphrase to start. It is read from the file given with
+--keystore-passphrase-file, from the IPFS_KEYSTORE_PASSPHRASE or
+IPFS_KEYSTORE_PASSPHRASE_FILE environment variables, or from the terminal.
+
 DEPRECATION NOTICE
 
 Previously, Kubo used an environment variable as seen below:
@@ -183,6 +193,7 @@ Headers.
 		cmds.BoolOption(enablePubSubKwd, "DEPRECATED"),
 		cmds.BoolOption(enableIPNSPubSubKwd, "Enable IPNS over pubsub. Implicitly enables pubsub, overrides Ipns.UsePubsub config."),
 		cmds.BoolOption(enableMultiplexKwd, "DEPRECATED"),
+		cmds.StringOption(keystorePassphraseFileKwd, "Path to a file containing the passphrase of the encrypted keystore."),
 		cmds.StringOption(agentVersionSuffix, "Optional suffix to the AgentVersion presented by `ipfs id` and exposed via libp2p identify protocol."),
 
 		// TODO: add way to override addresses. tricky part: updating the config if also --init.
@@ -319,7 +330,7 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 		fetchDistPath := migrations.GetDistPathEnv(migrations.CurrentIpfsDist)
 
 		// Create fetchers according to migrationCfg.DownloadSources
-		fetcher, err = migrations.GetMigrationFetcher(migrationCfg.DownloadSources, fetchDistPath, newIpfsFetcher)
+		fetcher, err = migrations.GetMigrationFetcher(migrationCfg.DownloadSources, fetchDistPath, newIpfsFetcher, migrationCfg.TrustedKeys...)
 		if err != nil {
 			return err
 		}
@@ -367,6 +378,11 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 	// fail before we get to that. It can't hurt to close it twice.
 	defer repo.Close()
 
+	passphraseFile, _ := req.Options[keystorePassphraseFileKwd].(string)
+	if err := cmdutils.UnlockKeystore(repo, passphraseFile); err != nil {
+		return err
+	}
+
 	offline, _ := req.Options[offlineKwd].(bool)
 	ipnsps, ipnsPsSet := req.Options[enableIPNSPubSubKwd].(bool)
 	pubsub, psSet := req.Options[enablePubSubKwd].(bool)
@@ -478,6 +494,8 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 		return err
 	}
 	node.IsDaemon = true
+	// webhooks are only notified by the daemon
+	node.Webhooks.Start()
 
 	if node.PNetFingerprint != nil {
 		fmt.Println("Swarm is limited to private network of peers with the swarm key")
@@ -617,6 +635,16 @@ take effect.
 	// start MFS pinning thread
 	startPinMFS(cctx, daemonConfigPollInterval, &ipfsPinMFSNode{node})
 
+	// start IPNS publish policies thread
+	startPublishMFS(cctx, publishPolicyPollInterval, &ipfsPublishMFSNode{node})
+
+	// start filestore watcher
+	if cfg.Experimental.FilestoreEnabled && cfg.Experimental.FilestoreAutoReindex {
+		if err := startFilestoreWatch(req.Context, node); err != nil {
+			return fmt.Errorf("starting filestore watcher: %w", err)
+		}
+	}
+
 	// The daemon is *finally* ready.
 	fmt.Printf("Daemon is ready\n")
 	notifyReady()
@@ -711,6 +739,15 @@ func serveHTTPApi(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, error
 		return nil, fmt.Errorf("serveHTTPApi: GetConfig() failed: %s", err)
 	}
 
+	if err := cfg.API.CheckAuthorizations(); err != nil {
+		return nil, fmt.Errorf("serveHTTPApi: %w", err)
+	}
+	if cfg.API.AuditLog.Enabled.WithDefault(config.DefaultAuditLogEnabled) {
+		if _, err := auditlog.Open(cctx.ConfigRoot, cfg.API.AuditLog); err != nil {
+			return nil, fmt.Errorf("serveHTTPApi: opening audit log: %w", err)
+		}
+	}
+
 	listeners, err := sockets.TakeListeners("io.ipfs.api")
 	if err != nil {
 		return nil, fmt.Errorf("serveHTTPApi: socket activation failed: %s", err)
@@ -966,6 +1003,10 @@ func serveHTTPGateway(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, e
 		opts = append(opts, corehttp.RoutingOption())
 	}
 
+	if cfg.Gateway.ExposeAliases.WithDefault(config.DefaultExposeAliases) {
+		opts = append(opts, corehttp.AliasOption())
+	}
+
 	if len(cfg.Gateway.RootRedirect) > 0 {
 		opts = append(opts, corehttp.RedirectOption("", cfg.Gateway.RootRedirect))
 	}
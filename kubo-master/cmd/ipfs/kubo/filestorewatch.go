@@ -0,0 +1,196 @@
+package kubo
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/coreapi"
+	"github.com/ipfs/kubo/core/corerepo"
+)
+
+// fswatchlog is the logger for the filestore watcher.
+var fswatchlog = logging.Logger("filestore/watch")
+
+const (
+	// filestoreWatchDebounce is how long the backing files must be left
+	// alone before the changed ones are reindexed.
+	filestoreWatchDebounce = 5 * time.Second
+	// filestoreWatchRescanInterval is how often the filestore is listed to
+	// pick up files added since the last scan.
+	filestoreWatchRescanInterval = time.Minute
+)
+
+// filestoreWatcher reindexes the backing files of the filestore when they
+// change on disk, and updates the pins that referenced them.
+type filestoreWatcher struct {
+	node    *core.IpfsNode
+	watcher *fsnotify.Watcher
+
+	mu    sync.Mutex
+	files map[string]struct{}
+	dirs  map[string]struct{}
+	// pending holds the changed files, reindexed together when timer fires.
+	pending map[string]struct{}
+	timer   *time.Timer
+}
+
+func startFilestoreWatch(ctx context.Context, node *core.IpfsNode) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	fw := &filestoreWatcher{
+		node:    node,
+		watcher: w,
+		files:   make(map[string]struct{}),
+		dirs:    make(map[string]struct{}),
+		pending: make(map[string]struct{}),
+	}
+	if err := fw.rescan(ctx); err != nil {
+		w.Close()
+		return err
+	}
+
+	go fw.run(ctx)
+	return nil
+}
+
+// rescan lists the files referenced by the filestore and watches their
+// parent directories. fsnotify cannot watch files that are replaced by
+// renaming, which is how most editors save them.
+func (fw *filestoreWatcher) rescan(ctx context.Context) error {
+	files, err := corerepo.FilestoreFiles(ctx, fw.node)
+	if err != nil {
+		return err
+	}
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.files = files
+	for f := range files {
+		dir := filepath.Dir(f)
+		if _, ok := fw.dirs[dir]; ok {
+			continue
+		}
+		if err := fw.watcher.Add(dir); err != nil {
+			fswatchlog.Warnf("cannot watch %s: %s", dir, err)
+			continue
+		}
+		fw.dirs[dir] = struct{}{}
+	}
+	return nil
+}
+
+func (fw *filestoreWatcher) run(ctx context.Context) {
+	defer fw.watcher.Close()
+
+	rescan := time.NewTicker(filestoreWatchRescanInterval)
+	defer rescan.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fw.mu.Lock()
+			if fw.timer != nil {
+				fw.timer.Stop()
+			}
+			fw.mu.Unlock()
+			return
+		case <-rescan.C:
+			if err := fw.rescan(ctx); err != nil {
+				fswatchlog.Errorf("listing filestore: %s", err)
+			}
+		case ev, ok := <-fw.watcher.Events:
+			if !ok {
+				return
+			}
+			fw.handle(ctx, ev)
+		case err, ok := <-fw.watcher.Errors:
+			if !ok {
+				return
+			}
+			fswatchlog.Error(err)
+		}
+	}
+}
+
+// handle schedules a reindex of the file an event is about, restarting the
+// debounce timer if one is already pending. Files changed within the same
+// debounce window are reindexed together, as the pins they share can only be
+// updated at once.
+func (fw *filestoreWatcher) handle(ctx context.Context, ev fsnotify.Event) {
+	if !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) &&
+		!ev.Has(fsnotify.Remove) && !ev.Has(fsnotify.Rename) {
+		return
+	}
+
+	path := filepath.Clean(ev.Name)
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if _, ok := fw.files[path]; !ok {
+		return
+	}
+	fw.pending[path] = struct{}{}
+	if fw.timer != nil {
+		fw.timer.Reset(filestoreWatchDebounce)
+		return
+	}
+	fw.timer = time.AfterFunc(filestoreWatchDebounce, func() {
+		fw.mu.Lock()
+		paths := slices.Collect(maps.Keys(fw.pending))
+		fw.pending = make(map[string]struct{})
+		fw.timer = nil
+		fw.mu.Unlock()
+
+		fw.reindex(ctx, paths)
+	})
+}
+
+func (fw *filestoreWatcher) reindex(ctx context.Context, paths []string) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	paths = slices.DeleteFunc(paths, func(path string) bool {
+		_, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			fswatchlog.Warnf("backing file %s was removed, run 'ipfs filestore gc' to drop its references", path)
+			return true
+		}
+		return false
+	})
+	if len(paths) == 0 {
+		return
+	}
+
+	api, err := coreapi.NewCoreAPI(fw.node)
+	if err != nil {
+		fswatchlog.Error(err)
+		return
+	}
+
+	updates, err := corerepo.FilestoreReindex(ctx, fw.node, api, paths...)
+	if err != nil {
+		fswatchlog.Errorf("reindexing %s: %s", strings.Join(paths, ", "), err)
+		return
+	}
+	for _, u := range updates {
+		for _, path := range u.Paths {
+			fswatchlog.Infof("reindexed %s: pin updated %s -> %s", path, u.From, u.To)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package kubo
+
+import (
+	"context"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ipfs/boxo/mfs"
+	"github.com/ipfs/boxo/path"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+	config "github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/coreapi"
+	"github.com/ipfs/kubo/core/coreiface/options"
+	"github.com/ipfs/kubo/namesys/publishpolicy"
+)
+
+// publishmfslog is the logger for the IPNS publish policies.
+var publishmfslog = logging.Logger("ipns/publish-policy")
+
+var publishPolicyPollInterval = 5 * time.Second
+
+func init() {
+	// this environment variable is solely for testing, use at your own risk
+	if pollDurStr := os.Getenv("IPNS_PUBLISH_POLICY_POLL_INTERVAL"); pollDurStr != "" {
+		d, err := time.ParseDuration(pollDurStr)
+		if err != nil {
+			publishmfslog.Error("error parsing IPNS_PUBLISH_POLICY_POLL_INTERVAL, using default:", err)
+			return
+		}
+		publishPolicyPollInterval = d
+	}
+}
+
+type publishMFSNode interface {
+	// LookupMFS returns the CID of the given MFS path.
+	LookupMFS(p string) (cid.Cid, error)
+	// Publish publishes c under the given key.
+	Publish(ctx context.Context, key string, c cid.Cid) error
+	Datastore() datastore.Datastore
+}
+
+type ipfsPublishMFSNode struct {
+	node *core.IpfsNode
+}
+
+func (x *ipfsPublishMFSNode) LookupMFS(p string) (cid.Cid, error) {
+	fsn, err := mfs.Lookup(x.node.FilesRoot, p)
+	if err != nil {
+		return cid.Undef, err
+	}
+	nd, err := fsn.GetNode()
+	if err != nil {
+		return cid.Undef, err
+	}
+	return nd.Cid(), nil
+}
+
+func (x *ipfsPublishMFSNode) Publish(ctx context.Context, key string, c cid.Cid) error {
+	api, err := coreapi.NewCoreAPI(x.node)
+	if err != nil {
+		return err
+	}
+	_, err = api.Name().Publish(ctx, path.FromCid(c), options.Name.Key(key), options.Name.AllowOffline(true))
+	return err
+}
+
+func (x *ipfsPublishMFSNode) Datastore() datastore.Datastore {
+	return x.node.Repo.Datastore()
+}
+
+func startPublishMFS(cctx pinMFSContext, pollInterval time.Duration, node publishMFSNode) {
+	go publishMFSOnChange(cctx, pollInterval, node)
+}
+
+func publishMFSOnChange(cctx pinMFSContext, pollInterval time.Duration, node publishMFSNode) {
+	tmo := time.NewTimer(pollInterval)
+	defer tmo.Stop()
+
+	states := map[string]publishpolicy.State{}
+	for {
+		select {
+		case <-cctx.Context().Done():
+			return
+		case <-tmo.C:
+			// reread the config, which may have changed in the meantime
+			cfg, err := cctx.GetConfig()
+			if err != nil {
+				publishmfslog.Errorf("reading config (%v)", err)
+			} else {
+				publishAllMFS(cctx.Context(), node, cfg, states, time.Now())
+			}
+		}
+		tmo.Reset(pollInterval)
+	}
+}
+
+// publishAllMFS applies every publish policy of cfg. states holds the state
+// of the policies, loaded from the datastore the first time they are seen.
+func publishAllMFS(ctx context.Context, node publishMFSNode, cfg *config.Config, states map[string]publishpolicy.State, now time.Time) {
+	keys := make([]string, 0, len(cfg.Ipns.PublishPolicies))
+	for key := range cfg.Ipns.PublishPolicies {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return
+		}
+		st, ok := states[key]
+		if !ok {
+			var err error
+			if st, err = publishpolicy.Load(ctx, node.Datastore(), key); err != nil {
+				publishmfslog.Errorf("loading state of the policy of %q (%v)", key, err)
+				continue
+			}
+		}
+		next := publishMFS(ctx, node, key, cfg.Ipns.PublishPolicies[key], st, now)
+		if next != st {
+			if err := publishpolicy.Save(ctx, node.Datastore(), key, next); err != nil {
+				publishmfslog.Errorf("saving state of the policy of %q (%v)", key, err)
+			}
+		}
+		states[key] = next
+	}
+}
+
+// publishMFS publishes the MFS path of the policy of key when it has changed,
+// and returns the new state of the policy.
+func publishMFS(ctx context.Context, node publishMFSNode, key string, policy config.IpnsPublishPolicy, st publishpolicy.State, now time.Time) publishpolicy.State {
+	st.MFS = policy.MFS
+
+	c, err := node.LookupMFS(policy.MFS)
+	if err != nil {
+		// the path may not exist yet, only log when the error changes
+		if st.Error != err.Error() {
+			publishmfslog.Errorf("looking up MFS path %q of the policy of %q (%v)", policy.MFS, key, err)
+		}
+		st.Error = err.Error()
+		return st
+	}
+	value := path.FromCid(c).String()
+	if value == st.Value {
+		st.Pending, st.PendingSince, st.Error = "", time.Time{}, ""
+		return st
+	}
+	if value != st.Pending {
+		publishmfslog.Debugf("MFS path %q of the policy of %q changed to %s", policy.MFS, key, c)
+		st.Pending, st.PendingSince, st.Error = value, now, ""
+	}
+
+	debounce := policy.Debounce.WithDefault(config.DefaultIpnsPublishPolicyDebounce)
+	if remaining := debounce - now.Sub(st.PendingSince); remaining > 0 {
+		publishmfslog.Debugf("publishing %s to %q: waiting for Debounce (remaining: %s)", c, key, remaining)
+		return st
+	}
+	minInterval := policy.MinInterval.WithDefault(config.DefaultIpnsPublishPolicyMinInterval)
+	if remaining := minInterval - now.Sub(st.Published); remaining > 0 {
+		publishmfslog.Debugf("publishing %s to %q: waiting for MinInterval (remaining: %s)", c, key, remaining)
+		return st
+	}
+
+	publishmfslog.Debugf("publishing %s to %q", c, key)
+	if err = node.Publish(ctx, key, c); err != nil {
+		publishmfslog.Errorf("publishing %s to %q (%v)", c, key, err)
+		st.Error = err.Error()
+		return st
+	}
+	st.Value, st.Published = value, now
+	st.Pending, st.PendingSince, st.Error = "", time.Time{}, ""
+	return st
+}
@@ -0,0 +1,122 @@
+package kubo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	merkledag "github.com/ipfs/boxo/ipld/merkledag"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	config "github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/namesys/publishpolicy"
+)
+
+type testPublishMFSNode struct {
+	ds        datastore.Datastore
+	root      cid.Cid
+	published []cid.Cid
+	err       error
+}
+
+func (x *testPublishMFSNode) LookupMFS(p string) (cid.Cid, error) {
+	if !x.root.Defined() {
+		return cid.Undef, errors.New("file does not exist")
+	}
+	return x.root, nil
+}
+
+func (x *testPublishMFSNode) Publish(ctx context.Context, key string, c cid.Cid) error {
+	if x.err != nil {
+		return x.err
+	}
+	x.published = append(x.published, c)
+	return nil
+}
+
+func (x *testPublishMFSNode) Datastore() datastore.Datastore {
+	return x.ds
+}
+
+func TestPublishMFS(t *testing.T) {
+	ctx := context.Background()
+	node := &testPublishMFSNode{ds: datastore.NewMapDatastore()}
+	cfg := &config.Config{}
+	cfg.Ipns.PublishPolicies = map[string]config.IpnsPublishPolicy{
+		"site": {
+			MFS:         "/published/site",
+			Debounce:    config.NewOptionalDuration(10 * time.Second),
+			MinInterval: config.NewOptionalDuration(time.Minute),
+		},
+	}
+	states := map[string]publishpolicy.State{}
+	start := time.Now()
+	step := func(d time.Duration) publishpolicy.State {
+		publishAllMFS(ctx, node, cfg, states, start.Add(d))
+		return states["site"]
+	}
+
+	// A missing path is reported.
+	if st := step(0); st.Error == "" || st.MFS != "/published/site" {
+		t.Fatalf("expected lookup error, got %+v", st)
+	}
+
+	// Changes are published once they have settled for Debounce.
+	v1 := merkledag.NewRawNode([]byte("v1")).Cid()
+	node.root = v1
+	if st := step(time.Second); st.Pending != "/ipfs/"+v1.String() || st.Error != "" {
+		t.Fatalf("expected pending change, got %+v", st)
+	}
+	step(5 * time.Second)
+	if len(node.published) != 0 {
+		t.Fatal("expected no publication before Debounce")
+	}
+	st := step(11 * time.Second)
+	if len(node.published) != 1 || node.published[0] != v1 {
+		t.Fatalf("expected v1 to be published, got %v", node.published)
+	}
+	if st.Value != "/ipfs/"+v1.String() || st.Pending != "" {
+		t.Fatalf("unexpected state %+v", st)
+	}
+
+	// Another change waits for MinInterval.
+	v2 := merkledag.NewRawNode([]byte("v2")).Cid()
+	node.root = v2
+	step(20 * time.Second)
+	step(40 * time.Second)
+	if len(node.published) != 1 {
+		t.Fatal("expected no publication before MinInterval")
+	}
+	step(72 * time.Second)
+	if len(node.published) != 2 || node.published[1] != v2 {
+		t.Fatalf("expected v2 to be published, got %v", node.published)
+	}
+
+	// Errors are reported, and publishing is retried.
+	v3 := merkledag.NewRawNode([]byte("v3")).Cid()
+	node.root = v3
+	node.err = errors.New("offline")
+	step(3 * time.Minute)
+	if st = step(4 * time.Minute); st.Error != "offline" || st.Pending != "/ipfs/"+v3.String() {
+		t.Fatalf("expected publish error, got %+v", st)
+	}
+	node.err = nil
+	if st = step(5 * time.Minute); st.Error != "" || st.Value != "/ipfs/"+v3.String() {
+		t.Fatalf("expected v3 to be published, got %+v", st)
+	}
+
+	// The state is persisted, and nothing is published again after a restart.
+	states = map[string]publishpolicy.State{}
+	step(10 * time.Minute)
+	if len(node.published) != 3 {
+		t.Fatalf("expected no new publication, got %v", node.published)
+	}
+	saved, err := publishpolicy.Load(ctx, node.ds, "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Value != "/ipfs/"+v3.String() {
+		t.Fatalf("unexpected saved state %+v", saved)
+	}
+}
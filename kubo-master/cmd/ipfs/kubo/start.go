@@ -28,6 +28,7 @@ import (
 	config "github.com/ipfs/kubo/config"
 	"github.com/ipfs/kubo/core"
 	corecmds "github.com/ipfs/kubo/core/commands"
+	"github.com/ipfs/kubo/core/commands/cmdutils"
 	"github.com/ipfs/kubo/core/corehttp"
 	"github.com/ipfs/kubo/plugin/loader"
 	"github.com/ipfs/kubo/repo"
@@ -113,6 +114,7 @@ func BuildEnv(pl PluginPreloader) func(ctx context.Context, req *cmds.Request) (
 		return &oldcmds.Context{
 			ConfigRoot: repoPath,
 			ReqLog:     &oldcmds.ReqLog{},
+			Jobs:       &oldcmds.Jobs{},
 			Plugins:    plugins,
 			ConstructNode: func() (n *core.IpfsNode, err error) {
 				if req == nil {
@@ -123,6 +125,10 @@ func BuildEnv(pl PluginPreloader) func(ctx context.Context, req *cmds.Request) (
 				if err != nil { // repo is owned by the node
 					return nil, err
 				}
+				if err := cmdutils.UnlockKeystore(r, ""); err != nil {
+					r.Close()
+					return nil, err
+				}
 
 				// ok everything is good. set it on the invocation (for ownership)
 				// and return it.
@@ -242,6 +248,11 @@ func makeExecutor(req *cmds.Request, env interface{}) (cmds.Executor, error) {
 	exe := tracingWrappedExecutor{cmds.NewExecutor(req.Root)}
 	cctx := env.(*oldcmds.Context)
 
+	// Requests made with --async output the job running the command.
+	if async, _ := req.Options[cmdutils.AsyncOptionName].(bool); async {
+		req.Command = corecmds.AsyncCommand(req.Command)
+	}
+
 	// Check if the command is disabled.
 	if req.Command.NoLocal && req.Command.NoRemote {
 		return nil, fmt.Errorf("command disabled: %v", req.Path)
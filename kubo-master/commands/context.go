@@ -23,6 +23,7 @@ var log = logging.Logger("command")
 type Context struct {
 	ConfigRoot string
 	ReqLog     *ReqLog
+	Jobs       *Jobs
 
 	Plugins *loader.PluginLoader
 
@@ -0,0 +1,412 @@
+package commands
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// Statuses of background jobs.
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+const (
+	// jobMaxOutput is the number of values emitted by a job that are kept,
+	// older values being dropped.
+	jobMaxOutput = 100
+	// jobSaveInterval bounds how often the progress of a job is persisted.
+	jobSaveInterval = time.Second
+	// jobKeep is how long finished jobs are kept.
+	jobKeep = 7 * 24 * time.Hour
+)
+
+// ErrJobNotFound is returned for unknown job IDs.
+var ErrJobNotFound = errors.New("job not found")
+
+// Job is a command run in the background by the daemon.
+type Job struct {
+	ID      string
+	Command string
+	Args    []string
+	Status  string
+	Error   string `json:",omitempty"`
+	// Output holds the last values emitted by the command: the last one is
+	// its latest progress while the job runs, and its result once the job
+	// succeeded.
+	Output    []json.RawMessage `json:",omitempty"`
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (j *Job) copy() Job {
+	out := *j
+	out.Args = slices.Clone(j.Args)
+	out.Output = slices.Clone(j.Output)
+	return out
+}
+
+// Jobs runs background jobs, and persists their state in a directory of the
+// repo so that it is still available once they finished or the daemon
+// restarted. Jobs which were running when the daemon stopped are failed.
+type Jobs struct {
+	lock sync.Mutex
+	dir  string
+	jobs map[string]*jobState
+}
+
+type jobState struct {
+	job      Job
+	cancel   context.CancelFunc
+	canceled bool
+	done     chan struct{}
+	saved    time.Time
+}
+
+// load reads the jobs persisted in dir, the first time it is called.
+func (js *Jobs) load(dir string) error {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+
+	if js.jobs != nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	js.dir = dir
+	js.jobs = make(map[string]*jobState)
+	for _, e := range entries {
+		// directories hold the input of jobs which are no longer running
+		if e.IsDir() {
+			if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+				log.Errorf("removing input of job %s: %s", e.Name(), err)
+			}
+			continue
+		}
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		st := &jobState{done: make(chan struct{})}
+		if err := json.Unmarshal(data, &st.job); err != nil || st.job.ID != id {
+			log.Errorf("ignoring invalid job file %s", e.Name())
+			continue
+		}
+		close(st.done)
+		js.jobs[id] = st
+		if st.job.Status == JobRunning {
+			st.job.Status = JobFailed
+			st.job.Error = "interrupted: the daemon stopped before the job completed"
+			st.job.EndTime = time.Now()
+			js.save(st)
+		}
+	}
+	js.prune(time.Now())
+	return nil
+}
+
+// prune removes the jobs which finished more than jobKeep ago.
+func (js *Jobs) prune(now time.Time) {
+	for id, st := range js.jobs {
+		if st.job.Status != JobRunning && now.Sub(st.job.EndTime) > jobKeep {
+			delete(js.jobs, id)
+			if err := os.Remove(js.jobPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Errorf("removing job %s: %s", id, err)
+			}
+		}
+	}
+}
+
+func (js *Jobs) jobPath(id string) string {
+	return filepath.Join(js.dir, id+".json")
+}
+
+// save persists the state of a job. It must be called with the lock held.
+func (js *Jobs) save(st *jobState) {
+	data, err := json.Marshal(&st.job)
+	if err != nil {
+		log.Errorf("saving job %s: %s", st.job.ID, err)
+		return
+	}
+	tmp := js.jobPath(st.job.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err == nil {
+		err = os.Rename(tmp, js.jobPath(st.job.ID))
+	}
+	if err != nil {
+		log.Errorf("saving job %s: %s", st.job.ID, err)
+	}
+	st.saved = time.Now()
+}
+
+// List returns all the jobs, oldest first.
+func (js *Jobs) List() []Job {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+
+	out := make([]Job, 0, len(js.jobs))
+	for _, st := range js.jobs {
+		out = append(out, st.job.copy())
+	}
+	slices.SortFunc(out, func(a, b Job) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return out
+}
+
+// Get returns the job with the given ID.
+func (js *Jobs) Get(id string) (Job, error) {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+
+	st, ok := js.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return st.job.copy(), nil
+}
+
+// Cancel cancels the running job with the given ID.
+func (js *Jobs) Cancel(id string) error {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+
+	st, ok := js.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if st.job.Status != JobRunning {
+		return fmt.Errorf("job %s is not running: %s", id, st.job.Status)
+	}
+	st.canceled = true
+	st.cancel()
+	return nil
+}
+
+// Wait waits for the job with the given ID to finish, and returns it.
+func (js *Jobs) Wait(ctx context.Context, id string) (Job, error) {
+	js.lock.Lock()
+	st, ok := js.jobs[id]
+	js.lock.Unlock()
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+
+	select {
+	case <-st.done:
+		return js.Get(id)
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// GetJobs returns the background jobs of the node.
+func (c *Context) GetJobs() (*Jobs, error) {
+	if c.Jobs == nil {
+		return nil, errors.New("background jobs are not supported")
+	}
+	n, err := c.GetNode()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Jobs.load(filepath.Join(n.Repo.Path(), "jobs")); err != nil {
+		return nil, fmt.Errorf("loading jobs: %w", err)
+	}
+	return c.Jobs, nil
+}
+
+// StartJob runs the command at path in the background, with the given
+// options, arguments and input files, and returns the job running it. The job
+// is added to the request log while it runs. The input files are copied to
+// the repo first, as they are usually read from a request that ends before
+// the job.
+func (c *Context) StartJob(path []string, opts cmds.OptMap, args []string, input files.Directory, root *cmds.Command) (Job, error) {
+	js, err := c.GetJobs()
+	if err != nil {
+		return Job{}, err
+	}
+
+	var idBytes [8]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return Job{}, err
+	}
+	id := hex.EncodeToString(idBytes[:])
+
+	inputDir := filepath.Join(js.dir, id)
+	if input != nil {
+		input, err = spoolJobInput(input, inputDir)
+		if err != nil {
+			_ = os.RemoveAll(inputDir)
+			return Job{}, fmt.Errorf("reading job input: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(c.Context())
+	if timeout, ok := opts[cmds.TimeoutOpt].(string); ok {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			cancel()
+			_ = os.RemoveAll(inputDir)
+			return Job{}, err
+		}
+		ctx, cancel = context.WithTimeout(ctx, d)
+	}
+	req, err := cmds.NewRequest(ctx, path, opts, args, input, root)
+	if err != nil {
+		cancel()
+		_ = os.RemoveAll(inputDir)
+		return Job{}, err
+	}
+
+	st := &jobState{
+		job: Job{
+			ID:        id,
+			Command:   strings.Join(path, "/"),
+			Args:      slices.Clone(args),
+			Status:    JobRunning,
+			StartTime: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	js.lock.Lock()
+	js.prune(st.job.StartTime)
+	js.jobs[id] = st
+	js.save(st)
+	job := st.job.copy()
+	js.lock.Unlock()
+
+	go js.run(st, req, c, inputDir)
+	return job, nil
+}
+
+func (js *Jobs) run(st *jobState, req *cmds.Request, env *Context, inputDir string) {
+	defer os.RemoveAll(inputDir)
+	defer st.cancel()
+	logDone := env.LogRequest(req)
+	defer logDone()
+
+	re, res := cmds.NewChanResponsePair(req)
+	execDone := make(chan struct{})
+	go func() {
+		defer close(execDone)
+		err := cmds.NewExecutor(req.Root).Execute(req, re, env)
+		if err != nil {
+			_ = re.CloseWithError(err)
+		}
+	}()
+
+	for {
+		v, err := res.Next()
+		if err != nil {
+			<-execDone
+			js.finish(st, err)
+			return
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			log.Errorf("job %s: encoding output: %s", st.job.ID, err)
+			continue
+		}
+
+		js.lock.Lock()
+		st.job.Output = append(st.job.Output, data)
+		if len(st.job.Output) > jobMaxOutput {
+			st.job.Output = slices.Delete(st.job.Output, 0, len(st.job.Output)-jobMaxOutput)
+		}
+		if time.Since(st.saved) >= jobSaveInterval {
+			js.save(st)
+		}
+		js.lock.Unlock()
+	}
+}
+
+func (js *Jobs) finish(st *jobState, err error) {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+
+	switch {
+	case err == io.EOF:
+		st.job.Status = JobSucceeded
+	case st.canceled:
+		st.job.Status = JobCanceled
+	default:
+		st.job.Status = JobFailed
+		var cmdsErr *cmds.Error
+		if errors.As(err, &cmdsErr) {
+			st.job.Error = cmdsErr.Message
+		} else {
+			st.job.Error = err.Error()
+		}
+	}
+	st.job.EndTime = time.Now()
+	js.save(st)
+	close(st.done)
+}
+
+// spoolJobInput copies the files of dir to the directory at path, and returns
+// a directory with the same entries, in the same order, reading the copies.
+func spoolJobInput(dir files.Directory, path string) (files.Directory, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, err
+	}
+
+	var entries []files.DirEntry
+	it := dir.Entries()
+	for i := 0; it.Next(); i++ {
+		entryPath := filepath.Join(path, fmt.Sprint(i))
+		switch n := it.Node().(type) {
+		case files.File:
+			f, err := os.Create(entryPath)
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(f, n)
+			if err == nil {
+				_, err = f.Seek(0, io.SeekStart)
+			}
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			entries = append(entries, files.FileEntry(it.Name(), files.NewReaderFile(f)))
+		case files.Directory:
+			sub, err := spoolJobInput(n, entryPath)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, files.FileEntry(it.Name(), sub))
+		default:
+			return nil, fmt.Errorf("unsupported file type of %q", it.Name())
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return files.NewSliceDirectory(entries), nil
+}
@@ -2,12 +2,23 @@ package config
 
 import (
 	"encoding/base64"
+	"fmt"
 	"strings"
+	"time"
 )
 
 const (
 	APITag           = "API"
 	AuthorizationTag = "Authorizations"
+
+	// DefaultRPCJWTLeeway is the default value of RPCJWT.Leeway.
+	DefaultRPCJWTLeeway = time.Minute
+
+	// Defaults of RPCAuditLog.
+	DefaultAuditLogEnabled  = false
+	DefaultAuditLogPath     = "audit.log"
+	DefaultAuditLogMaxSize  = "100MiB"
+	DefaultAuditLogMaxFiles = 5
 )
 
 type RPCAuthScope struct {
@@ -19,6 +30,79 @@ type RPCAuthScope struct {
 	// AllowedPaths is an explicit list of RPC path prefixes to allow.
 	// By default, none are allowed. ["/api/v0"] exposes all RPCs.
 	AllowedPaths []string
+
+	// Roles are the names of the API.Roles granted to the user, in addition
+	// to AllowedPaths.
+	Roles []string `json:",omitempty"`
+
+	// JWT lets the user authenticate with JSON Web Tokens sent as bearer
+	// tokens, instead of AuthSecret.
+	JWT *RPCJWT `json:",omitempty"`
+}
+
+// RPCJWT describes the JSON Web Tokens accepted from a user of the RPC API.
+type RPCJWT struct {
+	// JWKS is the path of a local JSON Web Key Set file, or the https:// URL
+	// of one, holding the public keys the tokens are signed with.
+	JWKS string
+
+	// Issuer is the expected "iss" claim of the tokens.
+	Issuer string
+
+	// Audience is the value expected in the "aud" claim of the tokens.
+	Audience string
+
+	// PathsClaim is the name of a claim listing RPC path prefixes allowed in
+	// addition to AllowedPaths.
+	PathsClaim *OptionalString `json:",omitempty"`
+
+	// RolesClaim is the name of a claim listing names of API.Roles granted
+	// in addition to Roles.
+	RolesClaim *OptionalString `json:",omitempty"`
+
+	// Leeway is the clock skew tolerated when checking the expiry of the
+	// tokens.
+	Leeway *OptionalDuration `json:",omitempty"`
+}
+
+// RPCRole is a named set of rules granting or denying access to RPC requests.
+// A request is allowed when it matches an Allow rule of one of the roles of the
+// user (or one of its AllowedPaths), and no Deny rule of its roles.
+type RPCRole struct {
+	// Allow is the list of rules granting access to requests.
+	Allow []RPCRule `json:",omitempty"`
+
+	// Deny is the list of rules denying access to requests, even when they
+	// are allowed by AllowedPaths or another role.
+	Deny []RPCRule `json:",omitempty"`
+}
+
+// RPCRule matches RPC requests. All the fields that are set must match.
+//
+// Args and Options are lists of patterns: a pattern ending with "*" matches
+// the values starting with the rest of the pattern, any other pattern only
+// matches the exact value. Values starting with "/", such as MFS paths, are
+// cleaned before being matched.
+type RPCRule struct {
+	// Path is the RPC path of a command, such as "/api/v0/pin/add". It
+	// matches the command and its subcommands.
+	Path string
+
+	// Methods are the HTTP methods matched by the rule. Any method matches
+	// when empty.
+	Methods []string `json:",omitempty"`
+
+	// Args are patterns for the positional arguments of the request, such as
+	// CIDs, MFS paths or key names. An Allow rule requires the request to
+	// have arguments, all matching a pattern. A Deny rule matches when any
+	// argument matches a pattern.
+	Args []string `json:",omitempty"`
+
+	// Options maps option names to patterns for their values. An Allow rule
+	// requires every listed option to be set, with values matching a
+	// pattern. A Deny rule matches when any listed option has a value
+	// matching a pattern.
+	Options map[string][]string `json:",omitempty"`
 }
 
 type API struct {
@@ -29,6 +113,76 @@ type API struct {
 	// If the map is empty, then the RPC API is exposed to everyone. Check the
 	// documentation for more details.
 	Authorizations map[string]*RPCAuthScope `json:",omitempty"`
+
+	// Roles is a map of named roles that can be granted to the users of
+	// Authorizations.
+	Roles map[string]*RPCRole `json:",omitempty"`
+
+	// AuditLog configures the log of the requests made to the RPC API.
+	AuditLog RPCAuditLog
+}
+
+// RPCAuditLog configures the audit log, where a JSON line is written for each
+// RPC request.
+type RPCAuditLog struct {
+	// Enabled turns the audit log on.
+	Enabled Flag `json:",omitempty"`
+
+	// Path is the path of the log file, relative to the repo unless
+	// absolute.
+	Path *OptionalString `json:",omitempty"`
+
+	// MaxSize is the size, such as "100MiB", above which the log file is
+	// rotated.
+	MaxSize *OptionalString `json:",omitempty"`
+
+	// MaxFiles is the number of rotated log files kept, in addition to the
+	// current one.
+	MaxFiles *OptionalInteger `json:",omitempty"`
+}
+
+// AuthorizationRoles returns the roles granted to the given user of
+// Authorizations, or an error if one of them is not defined in Roles.
+func (a *API) AuthorizationRoles(user string) ([]*RPCRole, error) {
+	scope := a.Authorizations[user]
+	if scope == nil {
+		return nil, nil
+	}
+	roles := make([]*RPCRole, 0, len(scope.Roles))
+	for _, name := range scope.Roles {
+		role := a.Roles[name]
+		if role == nil {
+			return nil, fmt.Errorf("API.Authorizations: user %q has role %q, which is not defined in API.Roles", user, name)
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// CheckAuthorizations returns an error when a user of Authorizations has a
+// role which is not defined in Roles, or an incomplete JWT configuration.
+func (a *API) CheckAuthorizations() error {
+	for user, scope := range a.Authorizations {
+		if _, err := a.AuthorizationRoles(user); err != nil {
+			return err
+		}
+		if scope == nil || scope.JWT == nil {
+			continue
+		}
+		switch {
+		case scope.AuthSecret != "":
+			return fmt.Errorf("API.Authorizations: user %q cannot have both AuthSecret and JWT", user)
+		case scope.JWT.JWKS == "":
+			return fmt.Errorf("API.Authorizations: user %q has no JWT.JWKS", user)
+		case strings.HasPrefix(scope.JWT.JWKS, "http://"):
+			return fmt.Errorf("API.Authorizations: JWT.JWKS of user %q must be a local file or an https:// URL", user)
+		case scope.JWT.Issuer == "":
+			return fmt.Errorf("API.Authorizations: user %q has no JWT.Issuer", user)
+		case scope.JWT.Audience == "":
+			return fmt.Errorf("API.Authorizations: user %q has no JWT.Audience", user)
+		}
+	}
+	return nil
 }
 
 // ConvertAuthSecret converts the given secret in the format "type:value" into an
@@ -41,6 +41,8 @@ type Config struct {
 	Pinning       Pinning
 	Import        Import
 	Version       Version
+	Keystore      Keystore
+	Webhooks      map[string]*Webhook `json:",omitempty"`
 
 	Internal Internal // experimental/unstable options
 
@@ -5,6 +5,7 @@ const (
 	DefaultDeserializedResponses = true
 	DefaultDisableHTMLErrors     = false
 	DefaultExposeRoutingAPI      = false
+	DefaultExposeAliases         = false
 )
 
 type GatewaySpec struct {
@@ -73,4 +74,8 @@ type Gateway struct {
 	// ExposeRoutingAPI configures the gateway port to expose
 	// routing system as HTTP API at /routing/v1 (https://specs.ipfs.tech/routing/http-routing-v1/).
 	ExposeRoutingAPI Flag
+
+	// ExposeAliases configures the gateway port to redirect /alias/<name>
+	// to the target of the alias, as registered with 'ipfs alias set'.
+	ExposeAliases Flag
 }
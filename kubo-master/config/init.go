@@ -158,6 +158,14 @@ func pebbleSpecMeasure() map[string]interface{} {
 	}
 }
 
+func sqliteSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "sqliteds",
+		"prefix": "sqlite.datastore",
+		"path":   "datastore.sqlite",
+	}
+}
+
 func badgerSpec() map[string]interface{} {
 	return map[string]interface{}{
 		"type":       "badgerds",
@@ -7,6 +7,9 @@ import (
 
 const (
 	DefaultIpnsMaxCacheTTL = time.Duration(math.MaxInt64)
+
+	DefaultIpnsPublishPolicyDebounce    = 10 * time.Second
+	DefaultIpnsPublishPolicyMinInterval = time.Minute
 )
 
 type Ipns struct {
@@ -20,4 +23,20 @@ type Ipns struct {
 
 	// Enable namesys pubsub (--enable-namesys-pubsub)
 	UsePubsub Flag `json:",omitempty"`
+
+	// PublishPolicies keeps IPNS names pointing to MFS paths, by key name.
+	PublishPolicies map[string]IpnsPublishPolicy `json:",omitempty"`
+}
+
+// IpnsPublishPolicy republishes an IPNS key when an MFS path changes.
+type IpnsPublishPolicy struct {
+	// MFS is the MFS path the key points to, such as /published/site.
+	MFS string
+
+	// Debounce is how long the MFS path must remain unchanged before it is
+	// published.
+	Debounce *OptionalDuration `json:",omitempty"`
+
+	// MinInterval is the minimum duration between two publications.
+	MinInterval *OptionalDuration `json:",omitempty"`
 }
@@ -0,0 +1,9 @@
+package config
+
+// Keystore configures where the keys of the node are held.
+type Keystore struct {
+	// RemoteSigner is the path of the unix socket of an external signer
+	// holding the keys. When set, the keys never enter the daemon: signatures
+	// are delegated to the signer, and the local keystore is not used.
+	RemoteSigner *OptionalString `json:",omitempty"`
+}
@@ -8,9 +8,13 @@ var DefaultMigrationDownloadSources = []string{"HTTPS", "IPFS"}
 // added to IPFS locally.
 type Migration struct {
 	// Sources in order of preference, where "IPFS" means use IPFS and "HTTPS"
-	// means use default gateways. Any other values are interpreted as
-	// hostnames for custom gateways. Empty list means "use default sources".
+	// means use default gateways. Absolute paths and file:// URLs are local
+	// migration bundles. Any other values are interpreted as hostnames for
+	// custom gateways. Empty list means "use default sources".
 	DownloadSources []string
+	// TrustedKeys are the peer IDs of the keys allowed to sign the manifest
+	// of a local migration bundle.
+	TrustedKeys []string `json:",omitempty"`
 	// Whether or not to keep the migration after downloading it.
 	// Options are "discard", "cache", "pin".  Empty string for default.
 	Keep string
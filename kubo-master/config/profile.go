@@ -204,6 +204,31 @@ NOTE: This profile may only be applied when first initializing node at IPFS_PATH
 			return nil
 		},
 	},
+	"sqliteds": {
+		Description: `Configures the node to use the SQLite datastore.
+
+All the blocks and metadata are kept in a single SQLite database file, opened
+in WAL mode. The SQLite driver is written in Go, so no C toolchain is needed.
+You should use this datastore if:
+
+- You run on small devices where one file per block exhausts the inodes of
+  the filesystem.
+- You want to avoid the compaction spikes of LSM-tree datastores.
+- You want the whole repository data in one file that is easy to back up.
+
+See configuration documentation at:
+https://github.com/ipfs/kubo/blob/master/docs/datastores.md#sqliteds
+
+NOTE: This profile may only be applied when first initializing node at IPFS_PATH
+      via 'ipfs init --profile sqliteds'
+`,
+
+		InitOnly: true,
+		Transform: func(c *Config) error {
+			c.Datastore.Spec = sqliteSpec()
+			return nil
+		},
+	},
 	"badgerds": {
 		Description: `Configures the node to use the legacy badgerv1 datastore.
 
@@ -0,0 +1,34 @@
+package config
+
+import "time"
+
+// WebhooksConcealSelector selects the secrets of the webhooks, which are
+// omitted by 'ipfs config show'.
+var WebhooksConcealSelector = []string{"Webhooks", "*", "Secret"}
+
+// Webhook configures an HTTP endpoint notified of the events of the daemon.
+type Webhook struct {
+	// URL receives the events, as JSON POST requests.
+	URL string
+	// Events filters the types of the events sent to URL. A trailing "*"
+	// matches any suffix. All the events are sent when empty.
+	Events []string `json:",omitempty"`
+	// Secret signs the requests with HMAC-SHA256, in the X-Kubo-Signature
+	// header.
+	Secret string `json:",omitempty"`
+	// MaxRetries is the number of times a failed delivery is retried.
+	MaxRetries *OptionalInteger `json:",omitempty"`
+	// RetryBackoff is the delay before the first retry. It doubles with every
+	// retry, up to MaxBackoff.
+	RetryBackoff *OptionalDuration `json:",omitempty"`
+	MaxBackoff   *OptionalDuration `json:",omitempty"`
+	// Timeout bounds each delivery attempt.
+	Timeout *OptionalDuration `json:",omitempty"`
+}
+
+const (
+	DefaultWebhookMaxRetries   = 5
+	DefaultWebhookRetryBackoff = time.Second
+	DefaultWebhookMaxBackoff   = 5 * time.Minute
+	DefaultWebhookTimeout      = 10 * time.Second
+)
@@ -0,0 +1,218 @@
+// Package auditlog writes and reads the audit log of the RPC API: a file of
+// JSON lines, one per request, rotated when it grows too large.
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	config "github.com/ipfs/kubo/config"
+)
+
+// Outcomes of requests.
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied"
+	OutcomeError   = "error"
+)
+
+// Redacted replaces the secrets in arguments and options.
+const Redacted = "<redacted>"
+
+// Entry is a line of the audit log, describing an RPC request.
+type Entry struct {
+	Time time.Time
+	// User is the name of the user in API.Authorizations, if the request
+	// was authenticated.
+	User string `json:",omitempty"`
+	// Subject is the subject of the JSON Web Token of the request, if any.
+	Subject string `json:",omitempty"`
+	Remote  string `json:",omitempty"`
+	Method  string
+	// Command is the path of the command, such as "pin/rm".
+	Command  string
+	Args     []string            `json:",omitempty"`
+	Options  map[string][]string `json:",omitempty"`
+	Status   int
+	Outcome  string
+	Error    string `json:",omitempty"`
+	Duration time.Duration
+}
+
+// Path returns the path of the audit log of the repo at repoPath.
+func Path(repoPath string, cfg config.RPCAuditLog) string {
+	p := cfg.Path.WithDefault(config.DefaultAuditLogPath)
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(repoPath, p)
+}
+
+// Writer appends entries to a log file, and rotates it when it grows larger
+// than its maximum size: the file is renamed with the suffix ".1", the
+// previous ".1" file becomes ".2", and so on up to the maximum number of
+// files.
+type Writer struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+var (
+	writersMu sync.Mutex
+	writers   = map[string]*Writer{}
+)
+
+// Open returns the writer of the audit log configured by cfg, for the repo at
+// repoPath. The writers of a path are shared within the process, so that the
+// file is rotated by a single writer.
+func Open(repoPath string, cfg config.RPCAuditLog) (*Writer, error) {
+	maxSize, err := humanize.ParseBytes(cfg.MaxSize.WithDefault(config.DefaultAuditLogMaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("API.AuditLog.MaxSize: %w", err)
+	}
+	path, err := filepath.Abs(Path(repoPath, cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	writersMu.Lock()
+	defer writersMu.Unlock()
+	if w, ok := writers[path]; ok {
+		return w, nil
+	}
+	w := &Writer{
+		path:     path,
+		maxSize:  int64(maxSize),
+		maxFiles: int(cfg.MaxFiles.WithDefault(config.DefaultAuditLogMaxFiles)),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	writers[path] = w
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = st.Size()
+	return nil
+}
+
+// Log appends e to the log.
+func (w *Writer) Log(e *Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return fmt.Errorf("rotating %s: %w", w.path, err)
+		}
+	}
+	n, err := w.f.Write(line)
+	w.size += int64(n)
+	return err
+}
+
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	if w.maxFiles < 1 {
+		if err := os.Remove(w.path); err != nil {
+			return err
+		}
+		return w.open()
+	}
+	if err := os.Remove(rotatedPath(w.path, w.maxFiles)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedPath(w.path, i), rotatedPath(w.path, i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, rotatedPath(w.path, 1)); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func rotatedPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Read calls fn with the entries of the log at path and of its rotated files,
+// oldest first, until fn returns an error. Malformed lines, such as a line
+// left incomplete by a crash, are skipped.
+func Read(path string, fn func(*Entry) error) error {
+	var files []string
+	for i := 1; ; i++ {
+		p := rotatedPath(path, i)
+		if _, err := os.Stat(p); err != nil {
+			break
+		}
+		files = append([]string{p}, files...)
+	}
+	files = append(files, path)
+
+	for _, p := range files {
+		if err := readFile(p, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(path string, fn func(*Entry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,74 @@
+package auditlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "github.com/ipfs/kubo/config"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, path string) []string {
+	var commands []string
+	require.NoError(t, Read(path, func(e *Entry) error {
+		commands = append(commands, e.Command)
+		return nil
+	}))
+	return commands
+}
+
+func TestWriterRotation(t *testing.T) {
+	repo := t.TempDir()
+	cfg := config.RPCAuditLog{
+		Path:     config.NewOptionalString("logs/audit.log"),
+		MaxSize:  config.NewOptionalString("300B"),
+		MaxFiles: config.NewOptionalInteger(2),
+	}
+	w, err := Open(repo, cfg)
+	require.NoError(t, err)
+
+	// writers are shared by path
+	w2, err := Open(repo, cfg)
+	require.NoError(t, err)
+	require.Same(t, w, w2)
+
+	path := Path(repo, cfg)
+	require.Equal(t, filepath.Join(repo, "logs", "audit.log"), path)
+
+	var written []string
+	for i := 0; i < 10; i++ {
+		cmd := fmt.Sprintf("cmd%d", i)
+		require.NoError(t, w.Log(&Entry{Time: time.Now(), Method: "POST", Command: cmd, Outcome: OutcomeSuccess}))
+		written = append(written, cmd)
+	}
+
+	_, err = os.Stat(path + ".1")
+	require.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// entries are read oldest first, the oldest ones being dropped
+	commands := readAll(t, path)
+	require.NotEmpty(t, commands)
+	require.Less(t, len(commands), len(written))
+	require.Equal(t, written[len(written)-len(commands):], commands)
+}
+
+func TestReadSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"Command":"id"}
+not json
+{"Command":"pin/rm"}
+{"Command":"ke`), 0o600))
+	require.Equal(t, []string{"id", "pin/rm"}, readAll(t, path))
+
+	require.Empty(t, readAll(t, filepath.Join(t.TempDir(), "missing.log")))
+}
+
+func TestOpenInvalidMaxSize(t *testing.T) {
+	_, err := Open(t.TempDir(), config.RPCAuditLog{MaxSize: config.NewOptionalString("big")})
+	require.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/core/commands/cmdutils"
+	"github.com/ipfs/kubo/namesys/alias"
+)
+
+const aliasForceOptionName = "force"
+
+// AliasList is the output type of 'alias ls' and 'alias import'.
+type AliasList struct {
+	Aliases []alias.Alias
+}
+
+var AliasCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Manage local names for content paths.",
+		ShortDescription: `
+Aliases are local names for CIDs, IPNS names and content paths, stored in the
+repo. A path starting with 'alias:<name>' can be used wherever a content path
+is accepted. With Gateway.ExposeAliases enabled, the gateway also redirects
+/alias/<name>/<path> to the target of the alias.
+`,
+		LongDescription: `
+Aliases are local names for CIDs, IPNS names and content paths, stored in the
+repo. A path starting with 'alias:<name>' can be used wherever a content path
+is accepted. With Gateway.ExposeAliases enabled, the gateway also redirects
+/alias/<name>/<path> to the target of the alias.
+
+Examples:
+
+  > ipfs alias set docs /ipns/<ipns-name>
+  > ipfs cat alias:docs/readme.md
+
+Share the alias table with others:
+
+  > ipfs alias export > aliases.json
+  > ipfs alias import aliases.json
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"set":    aliasSetCmd,
+		"ls":     aliasLsCmd,
+		"rm":     aliasRmCmd,
+		"export": aliasExportCmd,
+		"import": aliasImportCmd,
+	},
+}
+
+var aliasSetCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Register an alias for a content path.",
+		ShortDescription: `
+Registers <name> as an alias of <target>, replacing its previous target if
+any. The target can be a CID or a /ipfs, /ipns or /ipld path.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "Name of the alias."),
+		cmds.StringArg("target", true, false, "Content path the alias points to."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		target, err := cmdutils.PathOrCidPath(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+		name := req.Arguments[0]
+		if err = alias.Set(req.Context, nd.Repo.Datastore(), name, target); err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &alias.Alias{Name: name, Target: target.String()})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, a *alias.Alias) error {
+			_, err := fmt.Fprintf(w, "%s%s -> %s\n", alias.Prefix, a.Name, cmdenv.EscNonPrint(a.Target))
+			return err
+		}),
+	},
+	Type: alias.Alias{},
+}
+
+var aliasLsCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "List the registered aliases.",
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		aliases, err := alias.List(req.Context, nd.Repo.Datastore())
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &AliasList{Aliases: aliases})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(aliasListEncoder),
+	},
+	Type: AliasList{},
+}
+
+var aliasRmCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Remove aliases.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, true, "Names of the aliases to remove."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range req.Arguments {
+			if err = alias.Remove(req.Context, nd.Repo.Datastore(), name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var aliasExportCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Write the alias table to stdout, as JSON.",
+		ShortDescription: `
+Writes the registered aliases as a JSON table that can be read by
+'ipfs alias import'.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		data, err := alias.Export(req.Context, nd.Repo.Datastore())
+		if err != nil {
+			return err
+		}
+		return res.Emit(bytes.NewReader(append(data, '\n')))
+	},
+}
+
+var aliasImportCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Register the aliases of a table written by 'ipfs alias export'.",
+		ShortDescription: `
+Registers the aliases of the given JSON table, keeping the other registered
+aliases. Nothing is registered if an alias of the table is already registered
+with a different target, unless --force is passed.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("file", true, false, "The alias table to import.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(aliasForceOptionName, "f", "Replace the target of aliases already registered."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		file, err := cmdenv.GetFileArg(req.Files.Entries())
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+
+		force, _ := req.Options[aliasForceOptionName].(bool)
+		aliases, err := alias.Import(req.Context, nd.Repo.Datastore(), data, force)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &AliasList{Aliases: aliases})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(aliasListEncoder),
+	},
+	Type: AliasList{},
+}
+
+func aliasListEncoder(req *cmds.Request, w io.Writer, out *AliasList) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	for _, a := range out.Aliases {
+		fmt.Fprintf(tw, "%s\t%s\n", a.Name, cmdenv.EscNonPrint(a.Target))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,50 @@
+package cmdutils
+
+import (
+	"errors"
+	"maps"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	oldcmds "github.com/ipfs/kubo/commands"
+)
+
+const AsyncOptionName = "async"
+
+// AsyncOption lets long-running commands run as background jobs of the
+// daemon, managed with 'ipfs jobs'.
+var AsyncOption = cmds.BoolOption(AsyncOptionName, "Run the command as a background job of the daemon, and output the job ID right away. See 'ipfs jobs --help'.")
+
+// RunAsync starts req as a background job of the daemon when AsyncOption is
+// set, and emits the job. It reports whether the option was set, in which
+// case the command must return the error without running.
+func RunAsync(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) (bool, error) {
+	if async, _ := req.Options[AsyncOptionName].(bool); !async {
+		return false, nil
+	}
+
+	cctx, ok := env.(*oldcmds.Context)
+	if !ok {
+		return true, errors.New("background jobs are not supported")
+	}
+	nd, err := cctx.GetNode()
+	if err != nil {
+		return true, err
+	}
+	if !nd.IsDaemon {
+		return true, errors.New("--async requires a running daemon")
+	}
+
+	// the arguments read from the request body are needed before the
+	// request ends
+	if err := req.ParseBodyArgs(); err != nil {
+		return true, err
+	}
+
+	opts := maps.Clone(req.Options)
+	delete(opts, AsyncOptionName)
+	job, err := cctx.StartJob(req.Path, opts, req.Arguments, req.Files, req.Root)
+	if err != nil {
+		return true, err
+	}
+	return true, cmds.EmitOnce(res, &job)
+}
@@ -0,0 +1,97 @@
+package cmdutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ipfs/kubo/repo"
+	"golang.org/x/term"
+)
+
+const (
+	// KeystorePassphraseEnv is the environment variable holding the
+	// passphrase of the encrypted keystore.
+	KeystorePassphraseEnv = "IPFS_KEYSTORE_PASSPHRASE"
+	// KeystorePassphraseFileEnv is the environment variable holding the path
+	// of a file containing the passphrase of the encrypted keystore.
+	KeystorePassphraseFileEnv = "IPFS_KEYSTORE_PASSPHRASE_FILE"
+)
+
+// ReadPassphrase returns the content of file, without its trailing newline,
+// or reads the passphrase from the terminal after printing prompt when file is
+// empty. With confirm, the passphrase is asked twice.
+func ReadPassphrase(file, prompt string, confirm bool) ([]byte, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return checkPassphrase(bytes.TrimRight(data, "\r\n"))
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no passphrase file given, and stdin is not a terminal")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return checkPassphrase(pass)
+}
+
+func checkPassphrase(pass []byte) ([]byte, error) {
+	if len(pass) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+	return pass, nil
+}
+
+// KeystorePassphrase returns the passphrase of the encrypted keystore, read
+// from file if set, from the IPFS_KEYSTORE_PASSPHRASE or
+// IPFS_KEYSTORE_PASSPHRASE_FILE environment variables, or from the terminal.
+func KeystorePassphrase(file string, confirm bool) ([]byte, error) {
+	if file == "" {
+		if pass := os.Getenv(KeystorePassphraseEnv); pass != "" {
+			return []byte(pass), nil
+		}
+		file = os.Getenv(KeystorePassphraseFileEnv)
+	}
+	pass, err := ReadPassphrase(file, "Enter passphrase for the keystore: ", confirm)
+	if err != nil {
+		return nil, fmt.Errorf("reading keystore passphrase (set %s or %s): %w", KeystorePassphraseEnv, KeystorePassphraseFileEnv, err)
+	}
+	return pass, nil
+}
+
+// UnlockKeystore unlocks the keystore of r if it is encrypted, with the
+// passphrase returned by KeystorePassphrase.
+func UnlockKeystore(r repo.Repo, passphraseFile string) error {
+	ke, ok := r.(repo.KeystoreEncrypter)
+	if !ok || !ke.KeystoreLocked() {
+		return nil
+	}
+	pass, err := KeystorePassphrase(passphraseFile, false)
+	if err != nil {
+		return err
+	}
+	if err := ke.UnlockKeystore(pass); err != nil {
+		return fmt.Errorf("unlocking keystore: %w", err)
+	}
+	return nil
+}
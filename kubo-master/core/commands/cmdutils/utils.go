@@ -8,6 +8,7 @@ import (
 	"github.com/ipfs/boxo/path"
 	"github.com/ipfs/go-cid"
 	coreiface "github.com/ipfs/kubo/core/coreiface"
+	"github.com/ipfs/kubo/namesys/alias"
 )
 
 const (
@@ -51,8 +52,13 @@ func CheckBlockSize(req *cmds.Request, size uint64) error {
 }
 
 // PathOrCidPath returns a path.Path built from the argument. It keeps the old
-// behaviour by building a path from a CID string.
+// behaviour by building a path from a CID string. Paths starting with an
+// alias, such as "alias:docs/readme.md", are resolved by ResolvePath.
 func PathOrCidPath(str string) (path.Path, error) {
+	if alias.IsPath(str) {
+		return alias.NewPath(str)
+	}
+
 	p, err := path.NewPath(str)
 	if err == nil {
 		return p, nil
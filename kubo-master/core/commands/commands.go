@@ -7,6 +7,7 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -14,6 +15,8 @@ import (
 	"strings"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	version "github.com/ipfs/kubo"
+	"github.com/ipfs/kubo/core/commands/openapi"
 )
 
 type commandEncoder struct {
@@ -66,6 +69,7 @@ func CommandsCmd(root *cmds.Command) *cmds.Command {
 		},
 		Subcommands: map[string]*cmds.Command{
 			"completion": CompletionCmd(root),
+			"openapi":    OpenAPICmd(root),
 		},
 		Options: []cmds.Option{
 			cmds.BoolOption(flagsOptionName, "f", "Show command flags"),
@@ -227,6 +231,45 @@ To install the completions permanently, they can be moved to
 	}
 }
 
+// OpenAPI returns the OpenAPI document describing the commands of root
+// served over HTTP under apiPath.
+func OpenAPI(root *cmds.Command, apiPath string) *openapi.Document {
+	return openapi.Generate(root, apiPath, version.CurrentVersionNumber,
+		RepoDirOption, ConfigFileOption, ConfigOption, DebugOption, cmds.OptLongHelp,
+		cmds.OptShortHelp, LocalOption, OfflineOption, ApiOption, ApiAuthOption)
+}
+
+func OpenAPICmd(root *cmds.Command) *cmds.Command {
+	return &cmds.Command{
+		Helptext: cmds.HelpText{
+			Tagline:          "Generate the OpenAPI specification of the RPC API.",
+			ShortDescription: "Outputs an OpenAPI 3 document describing the RPC API.",
+			LongDescription: `
+Outputs an OpenAPI 3 document describing the RPC API, generated from the
+commands of this version of ipfs. It covers the arguments, options, output
+types and encodings of each command, and can be used to generate clients:
+
+  > ipfs commands openapi > openapi.json
+
+A running daemon also serves its document at /api/v0/openapi.json.
+`,
+		},
+		NoRemote: true,
+		Extra:    CreateCmdExtras(SetDoesNotUseRepo(true)),
+		Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+			return cmds.EmitOnce(res, OpenAPI(root, "/api/v0"))
+		},
+		Encoders: cmds.EncoderMap{
+			cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, doc *openapi.Document) error {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(doc)
+			}),
+		},
+		Type: openapi.Document{},
+	}
+}
+
 type nonFatalError string
 
 // streamResult is a helper function to stream results that possibly
@@ -1,10 +1,13 @@
 package commands
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func collectPaths(prefix string, cmd *cmds.Command, out map[string]struct{}) {
@@ -18,6 +21,12 @@ func collectPaths(prefix string, cmd *cmds.Command, out map[string]struct{}) {
 func TestCommands(t *testing.T) {
 	list := []string{
 		"/add",
+		"/alias",
+		"/alias/export",
+		"/alias/import",
+		"/alias/ls",
+		"/alias/rm",
+		"/alias/set",
 		"/bitswap",
 		"/bitswap/ledger",
 		"/bitswap/reprovide",
@@ -46,6 +55,7 @@ func TestCommands(t *testing.T) {
 		"/commands/completion/bash",
 		"/commands/completion/fish",
 		"/commands/completion/zsh",
+		"/commands/openapi",
 		"/config",
 		"/config/edit",
 		"/config/profile",
@@ -53,12 +63,25 @@ func TestCommands(t *testing.T) {
 		"/config/replace",
 		"/config/show",
 		"/dag",
+		"/dag/car",
+		"/dag/car/attach",
+		"/dag/car/detach",
+		"/dag/car/index",
+		"/dag/car/ls",
+		"/dag/diff",
 		"/dag/export",
 		"/dag/get",
 		"/dag/import",
 		"/dag/put",
+		"/dag/query",
 		"/dag/resolve",
+		"/dag/schema",
+		"/dag/schema/add",
+		"/dag/schema/get",
+		"/dag/schema/ls",
+		"/dag/schema/rm",
 		"/dag/stat",
+		"/dag/validate",
 		"/dht",
 		"/dht/query",
 		"/dht/findprovs",
@@ -74,11 +97,13 @@ func TestCommands(t *testing.T) {
 		"/routing/provide",
 		"/routing/reprovide",
 		"/diag",
+		"/diag/audit",
 		"/diag/cmds",
 		"/diag/cmds/clear",
 		"/diag/cmds/set-time",
 		"/diag/profile",
 		"/diag/sys",
+		"/events",
 		"/files",
 		"/files/chcid",
 		"/files/cp",
@@ -94,11 +119,21 @@ func TestCommands(t *testing.T) {
 		"/files/touch",
 		"/filestore",
 		"/filestore/dups",
+		"/filestore/gc",
 		"/filestore/ls",
+		"/filestore/reindex",
+		"/filestore/rm",
 		"/filestore/verify",
 		"/get",
 		"/id",
+		"/jobs",
+		"/jobs/cancel",
+		"/jobs/ls",
+		"/jobs/status",
+		"/jobs/wait",
 		"/key",
+		"/key/decrypt-store",
+		"/key/encrypt-store",
 		"/key/export",
 		"/key/gen",
 		"/key/import",
@@ -120,13 +155,21 @@ func TestCommands(t *testing.T) {
 		"/multibase/transcode",
 		"/multibase/list",
 		"/name",
+		"/name/history",
 		"/name/inspect",
 		"/name/publish",
+		"/name/publish-policy",
+		"/name/publish-policy/ls",
 		"/name/pubsub",
 		"/name/pubsub/cancel",
 		"/name/pubsub/state",
 		"/name/pubsub/subs",
+		"/name/record",
+		"/name/record/export",
+		"/name/record/import",
+		"/name/record/sign",
 		"/name/resolve",
+		"/name/rollback",
 		"/object",
 		"/object/data",
 		"/object/diff",
@@ -171,6 +214,7 @@ func TestCommands(t *testing.T) {
 		"/refs",
 		"/refs/local",
 		"/repo",
+		"/repo/du",
 		"/repo/gc",
 		"/repo/migrate",
 		"/repo/stat",
@@ -205,6 +249,8 @@ func TestCommands(t *testing.T) {
 		"/version",
 		"/version/check",
 		"/version/deps",
+		"/webhooks",
+		"/webhooks/ls",
 	}
 
 	cmdSet := make(map[string]struct{})
@@ -233,3 +279,54 @@ func TestCommands(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenAPI(t *testing.T) {
+	doc := OpenAPI(Root, "/api/v0")
+
+	// every command served over HTTP has an operation
+	var walk func(path string, cmd *cmds.Command)
+	walk = func(path string, cmd *cmds.Command) {
+		if cmd.NoRemote {
+			assert.NotContains(t, doc.Paths, path)
+			return
+		}
+		if cmd.Run != nil {
+			assert.Contains(t, doc.Paths, path)
+		}
+		for name, sub := range cmd.Subcommands {
+			walk(path+"/"+name, sub)
+		}
+	}
+	for name, sub := range Root.Subcommands {
+		walk("/api/v0/"+name, sub)
+	}
+	assert.NotContains(t, doc.Components.Parameters, ApiAuthOption)
+	assert.Contains(t, doc.Components.Parameters, cmds.TimeoutOpt)
+
+	// every reference resolves
+	b, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var raw struct {
+		Components map[string]map[string]json.RawMessage
+	}
+	require.NoError(t, json.Unmarshal(b, &raw))
+	var refs []string
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if tok == "$ref" {
+			var ref string
+			require.NoError(t, dec.Decode(&ref))
+			refs = append(refs, ref)
+		}
+	}
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		kind, name, ok := strings.Cut(strings.TrimPrefix(ref, "#/components/"), "/")
+		require.True(t, ok, ref)
+		assert.Contains(t, raw.Components[kind], name, ref)
+	}
+}
@@ -149,6 +149,15 @@ Set multiple values in the 'Addresses.AppendAnnounce' array:
 	Type: ConfigField{},
 }
 
+// ConfigKeyConcealed returns whether the value of the given config key may
+// hold secrets omitted by 'ipfs config show'.
+func ConfigKeyConcealed(key string) bool {
+	return matchesGlobPrefix(key, []string{config.IdentityTag, config.PrivKeyTag}) ||
+		matchesGlobPrefix(key, []string{config.APITag, config.AuthorizationTag}) ||
+		matchesGlobPrefix(key, config.PinningConcealSelector) ||
+		matchesGlobPrefix(key, config.WebhooksConcealSelector)
+}
+
 // matchesGlobPrefix returns true if and only if the key matches the glob.
 // The key is a sequence of string "parts", separated by commas.
 // The glob is a sequence of string "patterns".
@@ -181,7 +190,7 @@ var configShowCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Output config file contents.",
 		ShortDescription: `
-NOTE: For security reasons, this command will omit your private key and remote services. If you would like to make a full backup of your config (private key included), you must copy the config file from your repo.
+NOTE: For security reasons, this command will omit your private key, remote services and webhook secrets. If you would like to make a full backup of your config (private key included), you must copy the config file from your repo.
 `,
 	},
 	Type: make(map[string]interface{}),
@@ -223,6 +232,11 @@ NOTE: For security reasons, this command will omit your private key and remote s
 			return err
 		}
 
+		cfg, err = scrubOptionalValue(cfg, config.WebhooksConcealSelector)
+		if err != nil {
+			return err
+		}
+
 		return cmds.EmitOnce(res, &cfg)
 	},
 	Encoders: cmds.EncoderMap{
@@ -536,17 +550,14 @@ func replaceConfig(r repo.Repo, file io.Reader) error {
 		return errors.New("setting private key with API is not supported")
 	}
 
-	keyF, err := getConfig(r, config.PrivKeySelector)
+	// The private key is read from the repo rather than from the config file,
+	// which does not hold it when the keystore is encrypted.
+	oldCfg, err := r.Config()
 	if err != nil {
 		return errors.New("failed to get PrivKey")
 	}
 
-	pkstr, ok := keyF.Value.(string)
-	if !ok {
-		return errors.New("private key in config was not a string")
-	}
-
-	newCfg.Identity.PrivKey = pkstr
+	newCfg.Identity.PrivKey = oldCfg.Identity.PrivKey
 
 	// Handle Pinning.RemoteServices (API.Key of each service is a secret)
 
@@ -578,6 +589,15 @@ func replaceConfig(r repo.Repo, file io.Reader) error {
 		}
 	}
 
+	// Handle Webhooks (Secret of each webhook is a secret)
+
+	// keep the secrets omitted by 'config show', unless a new one is given
+	for name, hook := range newCfg.Webhooks {
+		if oldHook, ok := oldCfg.Webhooks[name]; ok && hook != nil && oldHook != nil && hook.Secret == "" {
+			hook.Secret = oldHook.Secret
+		}
+	}
+
 	return r.SetConfig(&newCfg)
 }
 
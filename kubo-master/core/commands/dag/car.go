@@ -0,0 +1,333 @@
+package dagcmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/kubo/blocks/carstore"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+	gocarv2 "github.com/ipld/go-car/v2"
+	"github.com/ipld/go-car/v2/index"
+	"github.com/multiformats/go-multicodec"
+	"github.com/multiformats/go-multihash"
+	"github.com/multiformats/go-varint"
+)
+
+const outputOptionName = "output"
+
+// CarList is the output type of 'dag car ls'.
+type CarList struct {
+	Cars []carstore.CarInfo
+}
+
+func dagCarAttach(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+
+	info, err := nd.CarStore.Attach(req.Context, req.Arguments[0])
+	if err != nil {
+		return err
+	}
+	return cmds.EmitOnce(res, &info)
+}
+
+func dagCarDetach(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+	return nd.CarStore.Detach(req.Context, req.Arguments[0])
+}
+
+func dagCarLs(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+	return cmds.EmitOnce(res, &CarList{Cars: nd.CarStore.List()})
+}
+
+// absPathArg makes the path argument of a command run by the daemon
+// independent of the working directory of the client.
+func absPathArg(req *cmds.Request, env cmds.Environment) error {
+	abs, err := filepath.Abs(req.Arguments[0])
+	if err != nil {
+		return err
+	}
+	req.Arguments[0] = abs
+	return nil
+}
+
+// CarIndexOutput is the output type of 'dag car index'.
+type CarIndexOutput struct {
+	Version    uint64
+	Roots      []cid.Cid
+	Blocks     uint64
+	IndexCodec string `json:",omitempty"`
+	Entries    uint64 `json:",omitempty"`
+	Output     string `json:",omitempty"`
+}
+
+func dagCarIndex(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	carPath, err := filepath.Abs(req.Arguments[0])
+	if err != nil {
+		return err
+	}
+
+	out := &CarIndexOutput{}
+	if output, _ := req.Options[outputOptionName].(string); output != "" {
+		if out.Output, err = filepath.Abs(output); err != nil {
+			return err
+		}
+		if out.Output == carPath {
+			return errors.New("the output must be a different file than the input")
+		}
+		if err = writeIndexedCar(carPath, out.Output); err != nil {
+			return err
+		}
+		carPath = out.Output
+	}
+
+	r, err := gocarv2.OpenReader(carPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	stats, err := r.Inspect(false)
+	if err != nil {
+		return fmt.Errorf("invalid CAR %s: %w", carPath, err)
+	}
+	out.Version = stats.Version
+	out.Roots = stats.Roots
+	out.Blocks = stats.BlockCount
+
+	if stats.Version == 2 && stats.Header.HasIndex() {
+		out.IndexCodec = stats.IndexCodec.String()
+		indexed := stats.BlockCount - stats.MhTypeCounts[multicodec.Identity]
+		if out.Entries, err = verifyCarIndex(r, indexed); err != nil {
+			return fmt.Errorf("invalid index in %s: %w", carPath, err)
+		}
+	}
+
+	return cmds.EmitOnce(res, out)
+}
+
+// writeIndexedCar writes the data payload of the CAR at src to dst, as a
+// CARv2 with a newly generated index.
+func writeIndexedCar(src, dst string) error {
+	r, err := gocarv2.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var dr io.ReadSeeker
+	if r.Version == 1 {
+		v1, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer v1.Close()
+		dr = v1
+	} else if dr, err = r.DataReader(); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err = gocarv2.WrapV1(dr, f); err != nil {
+		f.Close()
+		os.Remove(dst)
+		return err
+	}
+	return f.Close()
+}
+
+// verifyCarIndex checks the index of a CARv2 by reading the block at every
+// offset it holds, and returns the number of entries. indexed is the number
+// of blocks of the CAR that the index must hold.
+func verifyCarIndex(r *gocarv2.Reader, indexed uint64) (uint64, error) {
+	ir, err := r.IndexReader()
+	if err != nil {
+		return 0, err
+	}
+	idx, err := index.ReadFrom(ir)
+	if err != nil {
+		return 0, err
+	}
+	iidx, ok := idx.(index.IterableIndex)
+	if !ok {
+		return 0, fmt.Errorf("%s indexes cannot be verified", idx.Codec())
+	}
+	dr, err := r.DataReader()
+	if err != nil {
+		return 0, err
+	}
+
+	var entries uint64
+	err = iidx.ForEach(func(mh multihash.Multihash, offset uint64) error {
+		entries++
+		c, err := readCarSection(dr, offset)
+		if err != nil {
+			return fmt.Errorf("entry %s at offset %d: %w", mh.B58String(), offset, err)
+		}
+		if !bytes.Equal(c.Hash(), mh) {
+			return fmt.Errorf("entry %s at offset %d points to block %s", mh.B58String(), offset, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if entries != indexed {
+		return 0, fmt.Errorf("index has %d entries for %d blocks", entries, indexed)
+	}
+	return entries, nil
+}
+
+// readCarSection reads the block of the CAR section at the given offset of a
+// CARv1 payload and checks its hash.
+func readCarSection(ra io.ReaderAt, offset uint64) (cid.Cid, error) {
+	br := bufio.NewReader(io.NewSectionReader(ra, int64(offset), 1<<62))
+	size, err := varint.ReadUvarint(br)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if size > gocarv2.DefaultMaxAllowedSectionSize {
+		return cid.Undef, fmt.Errorf("section of %d bytes is too large", size)
+	}
+	n, c, err := cid.CidFromReader(br)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if uint64(n) > size {
+		return cid.Undef, errors.New("section is shorter than its CID")
+	}
+	data := make([]byte, size-uint64(n))
+	if _, err = io.ReadFull(br, data); err != nil {
+		return cid.Undef, err
+	}
+	sum, err := c.Prefix().Sum(data)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if !sum.Equals(c) {
+		return cid.Undef, fmt.Errorf("block %s does not match its content", c)
+	}
+	return c, nil
+}
+
+// carIndexCheck checks the index of a CARv2 read as a stream, such as in
+// 'dag import', against the offsets of its blocks.
+type carIndexCheck struct {
+	r      *countingReader
+	header gocarv2.Header
+	// offset of the block about to be read, in the data payload
+	offset uint64
+	// multihash and offset of every indexed block read
+	blocks map[string]struct{}
+}
+
+// newCarIndexCheck reads the CARv2 header of src, and returns a reader of the
+// whole CAR to pass to a gocarv2.BlockReader.
+func newCarIndexCheck(src io.Reader) (*carIndexCheck, io.Reader, error) {
+	head := make([]byte, gocarv2.PragmaSize+gocarv2.HeaderSize)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, err
+	}
+	head = head[:n]
+	if !bytes.HasPrefix(head, gocarv2.Pragma) {
+		return nil, nil, errors.New("not a CARv2, cannot verify its index")
+	}
+
+	ic := &carIndexCheck{blocks: make(map[string]struct{})}
+	if _, err = ic.header.ReadFrom(bytes.NewReader(head[gocarv2.PragmaSize:])); err != nil {
+		return nil, nil, err
+	}
+	if !ic.header.HasIndex() {
+		return nil, nil, errors.New("CARv2 has no index")
+	}
+	ic.r = &countingReader{r: io.MultiReader(bytes.NewReader(head), src)}
+	return ic, ic.r, nil
+}
+
+// next records the position of the next block.
+func (ic *carIndexCheck) next() {
+	ic.offset = ic.r.n - ic.header.DataOffset
+}
+
+// add records the block read at the last recorded position.
+func (ic *carIndexCheck) add(c cid.Cid) {
+	if c.Prefix().MhType == multihash.IDENTITY {
+		return
+	}
+	ic.blocks[indexEntryKey(c.Hash(), ic.offset)] = struct{}{}
+}
+
+// verify reads the index that follows the data payload and compares it to
+// the blocks read.
+func (ic *carIndexCheck) verify() error {
+	end := ic.header.DataOffset + ic.header.DataSize
+	if ic.r.n != end {
+		return fmt.Errorf("data payload ends at %d, expected %d", ic.r.n, end)
+	}
+	if ic.header.IndexOffset < end {
+		return fmt.Errorf("index at %d overlaps the data payload", ic.header.IndexOffset)
+	}
+	if _, err := io.CopyN(io.Discard, ic.r, int64(ic.header.IndexOffset-end)); err != nil {
+		return err
+	}
+	idx, err := index.ReadFrom(ic.r)
+	if err != nil {
+		return fmt.Errorf("cannot read index: %w", err)
+	}
+	iidx, ok := idx.(index.IterableIndex)
+	if !ok {
+		return fmt.Errorf("%s indexes cannot be verified", idx.Codec())
+	}
+
+	seen := make(map[string]struct{}, len(ic.blocks))
+	err = iidx.ForEach(func(mh multihash.Multihash, offset uint64) error {
+		key := indexEntryKey(mh, offset)
+		if _, ok := ic.blocks[key]; !ok {
+			return fmt.Errorf("index entry %s at offset %d does not match a block", mh.B58String(), offset)
+		}
+		seen[key] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(seen) != len(ic.blocks) {
+		return fmt.Errorf("index has entries for %d of %d blocks", len(seen), len(ic.blocks))
+	}
+	return nil
+}
+
+func indexEntryKey(mh multihash.Multihash, offset uint64) string {
+	return string(varint.ToUvarint(offset)) + string(mh)
+}
+
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
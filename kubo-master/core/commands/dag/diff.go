@@ -0,0 +1,286 @@
+package dagcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ipfs/boxo/path"
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	ipldlegacy "github.com/ipfs/go-ipld-legacy"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/core/commands/cmdutils"
+	iface "github.com/ipfs/kubo/core/coreiface"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/traversal"
+)
+
+const (
+	DiffAdd    = "add"
+	DiffRemove = "remove"
+	DiffChange = "change"
+)
+
+// DagDiffChange is a difference between two IPLD nodes. Before and After are
+// the dag-json encoded values, and are omitted for additions and removals
+// respectively.
+type DagDiffChange struct {
+	Type   string
+	Path   string
+	Before json.RawMessage `json:",omitempty"`
+	After  json.RawMessage `json:",omitempty"`
+}
+
+func dagDiff(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	api, err := cmdenv.GetApi(env, req)
+	if err != nil {
+		return err
+	}
+
+	var roots [2]ipld.Node
+	for i, arg := range req.Arguments {
+		roots[i], err = resolveNode(req.Context, api, arg)
+		if err != nil {
+			return err
+		}
+	}
+
+	d := &differ{
+		ctx: req.Context,
+		api: api,
+		emit: func(c *DagDiffChange) error {
+			return res.Emit(c)
+		},
+	}
+	return d.diff(datamodel.Path{}, roots[0], roots[1])
+}
+
+// resolveNode returns the IPLD node at the given CID or path.
+func resolveNode(ctx context.Context, api iface.CoreAPI, arg string) (ipld.Node, error) {
+	p, err := cmdutils.PathOrCidPath(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	rp, remainder, err := api.ResolvePath(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	nd, err := loadNode(ctx, api, rp.RootCid())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(remainder) > 0 {
+		return traversal.Get(nd, ipld.ParsePath(path.SegmentsToString(remainder...)))
+	}
+	return nd, nil
+}
+
+func loadNode(ctx context.Context, api iface.CoreAPI, c cid.Cid) (ipld.Node, error) {
+	obj, err := api.Dag().Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	universal, ok := obj.(ipldlegacy.UniversalNode)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a valid IPLD node", obj)
+	}
+	return universal, nil
+}
+
+// differ walks two IPLD nodes side by side, following the links that differ
+// and skipping the ones that are equal.
+type differ struct {
+	ctx  context.Context
+	api  iface.CoreAPI
+	emit func(*DagDiffChange) error
+}
+
+func (d *differ) diff(p datamodel.Path, a, b ipld.Node) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+
+	if a.Kind() == datamodel.Kind_Link && b.Kind() == datamodel.Kind_Link {
+		la, err := a.AsLink()
+		if err != nil {
+			return err
+		}
+		lb, err := b.AsLink()
+		if err != nil {
+			return err
+		}
+		if la.Binary() == lb.Binary() {
+			return nil
+		}
+		if p.Len() > 0 {
+			if err := d.change(DiffChange, p, a, b); err != nil {
+				return err
+			}
+		}
+
+		ca, okA := la.(cidlink.Link)
+		cb, okB := lb.(cidlink.Link)
+		if !okA || !okB {
+			return nil
+		}
+		if a, err = loadNode(d.ctx, d.api, ca.Cid); err != nil {
+			return err
+		}
+		if b, err = loadNode(d.ctx, d.api, cb.Cid); err != nil {
+			return err
+		}
+		return d.diff(p, a, b)
+	}
+
+	if datamodel.DeepEqual(a, b) {
+		return nil
+	}
+	if a.Kind() != b.Kind() {
+		return d.change(DiffChange, p, a, b)
+	}
+
+	switch a.Kind() {
+	case datamodel.Kind_Map:
+		return d.diffMap(p, a, b)
+	case datamodel.Kind_List:
+		return d.diffList(p, a, b)
+	default:
+		return d.change(DiffChange, p, a, b)
+	}
+}
+
+func (d *differ) diffMap(p datamodel.Path, a, b ipld.Node) error {
+	keysA, err := mapKeys(a)
+	if err != nil {
+		return err
+	}
+	keysB, err := mapKeys(b)
+	if err != nil {
+		return err
+	}
+	keys := slices.Clone(keysA)
+	for _, k := range keysB {
+		if _, found := slices.BinarySearch(keysA, k); !found {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		va, err := a.LookupByString(k)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		vb, err := b.LookupByString(k)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+
+		kp := p.AppendSegmentString(k)
+		switch {
+		case va == nil:
+			err = d.change(DiffAdd, kp, nil, vb)
+		case vb == nil:
+			err = d.change(DiffRemove, kp, va, nil)
+		default:
+			err = d.diff(kp, va, vb)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffList(p datamodel.Path, a, b ipld.Node) error {
+	la, lb := a.Length(), b.Length()
+	for i := int64(0); i < max(la, lb); i++ {
+		ip := p.AppendSegmentInt(i)
+
+		var va, vb ipld.Node
+		var err error
+		if i < la {
+			if va, err = a.LookupByIndex(i); err != nil {
+				return err
+			}
+		}
+		if i < lb {
+			if vb, err = b.LookupByIndex(i); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case va == nil:
+			err = d.change(DiffAdd, ip, nil, vb)
+		case vb == nil:
+			err = d.change(DiffRemove, ip, va, nil)
+		default:
+			err = d.diff(ip, va, vb)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *differ) change(typ string, p datamodel.Path, before, after ipld.Node) error {
+	c := &DagDiffChange{
+		Type: typ,
+		Path: p.String(),
+	}
+	var err error
+	if before != nil {
+		if c.Before, err = encodeDagJSON(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if c.After, err = encodeDagJSON(after); err != nil {
+			return err
+		}
+	}
+	return d.emit(c)
+}
+
+func mapKeys(n ipld.Node) ([]string, error) {
+	keys := make([]string, 0, n.Length())
+	it := n.MapIterator()
+	for !it.Done() {
+		k, _, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		ks, err := k.AsString()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ks)
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func isNotFound(err error) bool {
+	var notExists datamodel.ErrNotExists
+	return errors.As(err, &notExists)
+}
+
+func encodeDagJSON(n ipld.Node) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if err := dagjson.Encode(n, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -6,9 +6,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cheggaaa/pb"
+	"github.com/ipfs/boxo/blockservice"
+	offlinexchange "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/gateway"
+	"github.com/ipfs/boxo/path"
 	cid "github.com/ipfs/go-cid"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	ipld "github.com/ipfs/go-ipld-format"
@@ -16,7 +22,13 @@ import (
 	"github.com/ipfs/kubo/core/commands/cmdutils"
 	iface "github.com/ipfs/kubo/core/coreiface"
 	gocar "github.com/ipld/go-car/v2"
+	ipldprime "github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/ipld/go-ipld-prime/traversal"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
 	selectorparse "github.com/ipld/go-ipld-prime/traversal/selector/parse"
 )
 
@@ -32,6 +44,13 @@ func dagExport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 		return err
 	}
 
+	selectorStr, _ := req.Options[selectorOptionName].(string)
+	scope, _ := req.Options[dagScopeOptionName].(string)
+	entityBytes, _ := req.Options[entityBytesOptionName].(string)
+	if selectorStr != "" && (scope != "" || entityBytes != "") {
+		return fmt.Errorf("--%s cannot be combined with --%s or --%s", selectorOptionName, dagScopeOptionName, entityBytesOptionName)
+	}
+
 	// Resolve path and confirm the root block is available, fail fast if not
 	b, err := api.Block().Stat(req.Context, p)
 	if err != nil {
@@ -39,6 +58,24 @@ func dagExport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 	}
 	c := b.Path().RootCid()
 
+	carVersion, _ := req.Options[carVersionOptionName].(int)
+	if carVersion != 1 && carVersion != 2 {
+		return fmt.Errorf("invalid --%s %d: expected 1 or 2", carVersionOptionName, carVersion)
+	}
+
+	var export func(w io.Writer) error
+	if scope != "" || entityBytes != "" {
+		export, err = gatewayExport(req, env, api, p, scope, entityBytes)
+		if err == nil && carVersion == 2 {
+			export = wrapV2(export)
+		}
+	} else {
+		export, err = selectorExport(req, api, c, selectorStr, carVersion)
+	}
+	if err != nil {
+		return err
+	}
+
 	pipeR, pipeW := io.Pipe()
 
 	errCh := make(chan error, 2) // we only report the 1st error
@@ -50,27 +87,10 @@ func dagExport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 			close(errCh)
 		}()
 
-		lsys := cidlink.DefaultLinkSystem()
-		lsys.SetReadStorage(&dagStore{dag: api.Dag(), ctx: req.Context})
-
-		// Uncomment the following to support CARv2 output.
-		/*
-			car, err := gocar.NewSelectiveWriter(req.Context, &lsys, c, selectorparse.CommonSelector_ExploreAllRecursively, gocar.AllowDuplicatePuts(false))
-			if err != nil {
-				errCh <- err
-				return
-			}
-			if _, err = car.WriteTo(pipeW); err != nil {
-				errCh <- err
-				return
-			}
-		*/
-		_, err := gocar.TraverseV1(req.Context, &lsys, c, selectorparse.CommonSelector_ExploreAllRecursively, pipeW, gocar.AllowDuplicatePuts(false))
-		if err != nil {
+		if err := export(pipeW); err != nil {
 			errCh <- err
 			return
 		}
-
 	}()
 
 	if err := res.Emit(pipeR); err != nil {
@@ -96,6 +116,190 @@ func dagExport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 	return err
 }
 
+// selectorExport returns a function writing the CAR of the blocks matched by
+// the selector option under root.
+func selectorExport(req *cmds.Request, api iface.CoreAPI, root cid.Cid, selectorStr string, carVersion int) (func(w io.Writer) error, error) {
+	sel, depth, err := parseExportSelector(selectorStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w io.Writer) error {
+		lsys := cidlink.DefaultLinkSystem()
+		lsys.SetReadStorage(&dagStore{dag: api.Dag(), ctx: req.Context})
+		if depth >= 0 {
+			limitLinkDepth(&lsys, depth)
+		}
+
+		if carVersion == 2 {
+			// The DAG is traversed twice: once to compute the size of the
+			// data payload for the CARv2 header, and once to write it.
+			car, err := gocar.NewSelectiveWriter(req.Context, &lsys, root, sel, gocar.AllowDuplicatePuts(false))
+			if err != nil {
+				return err
+			}
+			_, err = car.WriteTo(w)
+			return err
+		}
+		_, err := gocar.TraverseV1(req.Context, &lsys, root, sel, w, gocar.AllowDuplicatePuts(false))
+		return err
+	}, nil
+}
+
+// wrapV2 turns a function writing a CARv1 into one writing the same data as
+// an indexed CARv2. The CARv1 is spooled to a temporary file, as the CARv2
+// header holds the size of the data payload.
+func wrapV2(exportV1 func(w io.Writer) error) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		tmp, err := os.CreateTemp("", "ipfs-dag-export-*.car")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		if err = exportV1(tmp); err != nil {
+			return err
+		}
+		if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return gocar.WrapV1(tmp, w)
+	}
+}
+
+// gatewayExport returns a function writing the same CAR as the trustless
+// gateway does for the given path, dag-scope and entity-bytes.
+func gatewayExport(req *cmds.Request, env cmds.Environment, api iface.CoreAPI, p path.Path, scope, entityBytes string) (func(w io.Writer) error, error) {
+	params := gateway.CarParams{
+		Scope:      gateway.DagScope(scope),
+		Order:      gateway.DagOrderDFS,
+		Duplicates: gateway.DuplicateBlocksExcluded,
+	}
+	if entityBytes != "" {
+		r, err := gateway.NewDagByteRange(entityBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", entityBytesOptionName, err)
+		}
+		params.Range = &r
+		if params.Scope == "" {
+			params.Scope = gateway.DagScopeEntity
+		}
+	}
+	switch params.Scope {
+	case gateway.DagScopeAll, gateway.DagScopeBlock:
+		if params.Range != nil {
+			return nil, fmt.Errorf("--%s requires --%s=%s", entityBytesOptionName, dagScopeOptionName, gateway.DagScopeEntity)
+		}
+	case gateway.DagScopeEntity:
+	default:
+		return nil, fmt.Errorf("invalid --%s %q: expected all, entity or block", dagScopeOptionName, scope)
+	}
+
+	imPath, err := path.NewImmutablePath(p)
+	if err != nil {
+		imPath, _, err = api.ResolvePath(req.Context, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := cmdenv.GetNode(env)
+	if err != nil {
+		return nil, err
+	}
+	bserv := n.Blocks
+	if offline, _ := req.Options["offline"].(bool); offline {
+		bserv = blockservice.New(n.Blockstore, offlinexchange.Exchange(n.Blockstore))
+	}
+	backend, err := gateway.NewBlocksBackend(bserv)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w io.Writer) error {
+		_, rc, err := backend.GetCAR(req.Context, imPath, params)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		_, err = io.Copy(w, rc)
+		return err
+	}, nil
+}
+
+// parseExportSelector parses the selector option: either a dag-json encoded
+// selector, or a comma-separated list of "path:<fields>" and "depth:<n>". It
+// returns the selector, and the maximum number of links to follow from the
+// root, or -1 for no limit.
+func parseExportSelector(s string) (ipldprime.Node, int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return selectorparse.CommonSelector_ExploreAllRecursively, -1, nil
+	}
+
+	if strings.HasPrefix(s, "{") {
+		sel, err := ipldprime.Decode([]byte(s), dagjson.Decode)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid selector: %w", err)
+		}
+		if _, err = selector.CompileSelector(sel); err != nil {
+			return nil, 0, fmt.Errorf("invalid selector: %w", err)
+		}
+		return sel, -1, nil
+	}
+
+	var fields []string
+	depth := -1
+	for _, part := range strings.Split(s, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), ":")
+		switch key {
+		case "path":
+			fields = strings.FieldsFunc(value, func(r rune) bool { return r == '/' })
+		case "depth":
+			d, err := strconv.Atoi(value)
+			if err != nil || d < 0 {
+				return nil, 0, fmt.Errorf("invalid selector depth %q: expected a positive integer", value)
+			}
+			depth = d
+		default:
+			return nil, 0, fmt.Errorf("invalid selector %q: expected dag-json, or path:<fields> and depth:<n>", part)
+		}
+	}
+
+	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+	spec := ssb.ExploreRecursive(selector.RecursionLimitNone(), ssb.ExploreAll(ssb.ExploreRecursiveEdge()))
+	for i := len(fields) - 1; i >= 0; i-- {
+		field, next := fields[i], spec
+		spec = ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert(field, next)
+		})
+	}
+	return spec.Node(), depth, nil
+}
+
+// limitLinkDepth makes lsys skip the blocks that are more than depth links
+// away from the root of a traversal.
+func limitLinkDepth(lsys *ipldprime.LinkSystem, depth int) {
+	open := lsys.StorageReadOpener
+	// depth of the blocks loaded so far, by path from the root
+	depths := make(map[string]int)
+	lsys.StorageReadOpener = func(lctx ipldprime.LinkContext, lnk ipldprime.Link) (io.Reader, error) {
+		d := 0
+		for i := lctx.LinkPath.Len() - 1; i >= 0; i-- {
+			if parent, ok := depths[lctx.LinkPath.Truncate(i).String()]; ok {
+				d = parent + 1
+				break
+			}
+		}
+		if d > depth {
+			return nil, traversal.SkipMe{}
+		}
+		depths[lctx.LinkPath.String()] = d
+		return open(lctx, lnk)
+	}
+}
+
 func finishCLIExport(res cmds.Response, re cmds.ResponseEmitter) error {
 	var showProgress bool
 	val, specified := res.Request().Options[progressOptionName]
@@ -20,6 +20,10 @@ import (
 )
 
 func dagImport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	if async, err := cmdutils.RunAsync(req, res, env); async {
+		return err
+	}
+
 	node, err := cmdenv.GetNode(env)
 	if err != nil {
 		return err
@@ -46,6 +50,12 @@ func dagImport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 	}
 
 	doPinRoots, _ := req.Options[pinRootsOptionName].(bool)
+	verifyIndex, _ := req.Options[verifyIndexOptionName].(bool)
+
+	typ, err := schemaFromRequest(req, node.Repo.Datastore())
+	if err != nil {
+		return err
+	}
 
 	// grab a pinlock ( which doubles as a GC lock ) so that regardless of the
 	// size of the streamed-in cars nothing will disappear on us before we had
@@ -104,7 +114,16 @@ func dagImport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 
 			var previous blocks.Block
 
-			car, err := gocarv2.NewBlockReader(file)
+			var r io.Reader = file
+			var indexCheck *carIndexCheck
+			if verifyIndex {
+				indexCheck, r, err = newCarIndexCheck(file)
+				if err != nil {
+					return importError(nil, nil, err)
+				}
+			}
+
+			car, err := gocarv2.NewBlockReader(r)
 			if err != nil {
 				return err
 			}
@@ -114,12 +133,18 @@ func dagImport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 			}
 
 			for {
+				if indexCheck != nil {
+					indexCheck.next()
+				}
 				block, err := car.Next()
 				if err != nil && err != io.EOF {
 					return importError(previous, block, err)
 				} else if block == nil {
 					break
 				}
+				if indexCheck != nil {
+					indexCheck.add(block.Cid())
+				}
 				if err := cmdutils.CheckBlockSize(req, uint64(len(block.RawData()))); err != nil {
 					return importError(previous, block, err)
 				}
@@ -137,6 +162,12 @@ func dagImport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 				blockBytesCount += uint64(len(block.RawData()))
 				previous = block
 			}
+
+			if indexCheck != nil {
+				if err := indexCheck.verify(); err != nil {
+					return importError(nil, nil, fmt.Errorf("invalid CARv2 index: %w", err))
+				}
+			}
 			return nil
 		}()
 		if err != nil {
@@ -148,6 +179,22 @@ func dagImport(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment
 		return err
 	}
 
+	// Validate the DAGs under all roots before pinning any of them: the
+	// blocks of a non-conforming import are left unpinned, for GC to remove.
+	if typ != nil {
+		v := newSchemaValidator(req.Context, api)
+		err = roots.ForEach(func(c cid.Cid) error {
+			nd, err := loadNode(req.Context, api, c)
+			if err != nil {
+				return fmt.Errorf("cannot validate root %q: %w", c, err)
+			}
+			return v.validate(c, nd, typ)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// It is not guaranteed that a root in a header is actually present in the same ( or any )
 	// .car file. This is the case in version 1, and ideally in further versions too.
 	// Accumulate any root CID seen in a header, and supplement its actual node if/when encountered
@@ -72,6 +72,11 @@ func dagPut(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) e
 		MhLength: -1,
 	}
 
+	typ, err := schemaFromRequest(req, nd.Repo.Datastore())
+	if err != nil {
+		return err
+	}
+
 	decoder, err := multicodec.LookupDecoder(uint64(icodec))
 	if err != nil {
 		return err
@@ -109,6 +114,12 @@ func dagPut(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) e
 		if err != nil {
 			return err
 		}
+		if typ != nil {
+			// Linked blocks may not be available yet, only check this one.
+			if err := newSchemaValidator(req.Context, nil).validate(blockCid, n, typ); err != nil {
+				return err
+			}
+		}
 		blk, err := blocks.NewBlockWithCid(bd.Bytes(), blockCid)
 		if err != nil {
 			return err
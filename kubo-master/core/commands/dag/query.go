@@ -0,0 +1,852 @@
+package dagcmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+	iface "github.com/ipfs/kubo/core/coreiface"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basicnode"
+)
+
+const rawOutputOptionName = "raw-output"
+
+func dagQuery(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	api, err := cmdenv.GetApi(env, req)
+	if err != nil {
+		return err
+	}
+
+	expr, err := parseQuery(req.Arguments[1])
+	if err != nil {
+		return err
+	}
+
+	root, err := resolveNode(req.Context, api, req.Arguments[0])
+	if err != nil {
+		return err
+	}
+
+	raw, _ := req.Options[rawOutputOptionName].(bool)
+	q := &queryEnv{ctx: req.Context, api: api}
+
+	r, w := io.Pipe()
+	go func() {
+		bw := bufio.NewWriter(w)
+		err := expr.eval(q, root, func(n ipld.Node) error {
+			if raw && n.Kind() == datamodel.Kind_String {
+				s, _ := n.AsString()
+				bw.WriteString(s)
+			} else if err := dagjson.Encode(n, bw); err != nil {
+				return err
+			}
+			return bw.WriteByte('\n')
+		})
+		if err == nil {
+			err = bw.Flush()
+		}
+		_ = w.CloseWithError(err)
+	}()
+
+	// Unblock the writer if the output is not read to the end, such as when
+	// the client goes away.
+	err = res.Emit(r)
+	_ = r.CloseWithError(err)
+	return err
+}
+
+// queryEnv loads the blocks that a query traverses.
+type queryEnv struct {
+	ctx context.Context
+	api iface.CoreAPI
+}
+
+// deref follows n while it is a link, so that queries see through blocks.
+func (q *queryEnv) deref(n ipld.Node) (ipld.Node, error) {
+	for n.Kind() == datamodel.Kind_Link {
+		lnk, err := n.AsLink()
+		if err != nil {
+			return nil, err
+		}
+		cl, ok := lnk.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("unsupported link type %T", lnk)
+		}
+		if n, err = loadNode(q.ctx, q.api, cl.Cid); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// queryExpr is a compiled query expression. eval calls emit for each output
+// of the expression on the given input.
+type queryExpr interface {
+	eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error
+}
+
+type (
+	identityExpr struct{}
+	iterateExpr  struct{}
+	literalExpr  struct{ value ipld.Node }
+	indexExpr    struct{ key queryExpr }
+	tryExpr      struct{ expr queryExpr }
+	collectExpr  struct{ expr queryExpr }
+	pipeExpr     struct{ left, right queryExpr }
+	commaExpr    struct{ left, right queryExpr }
+	andExpr      struct{ left, right queryExpr }
+	orExpr       struct{ left, right queryExpr }
+	compareExpr  struct {
+		op          string
+		left, right queryExpr
+	}
+	funcExpr struct {
+		name string
+		arg  queryExpr
+	}
+)
+
+func (identityExpr) eval(_ *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	return emit(in)
+}
+
+func (e literalExpr) eval(_ *queryEnv, _ ipld.Node, emit func(ipld.Node) error) error {
+	return emit(e.value)
+}
+
+func (iterateExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	in, err := q.deref(in)
+	if err != nil {
+		return err
+	}
+	switch in.Kind() {
+	case datamodel.Kind_List:
+		it := in.ListIterator()
+		for !it.Done() {
+			_, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+	case datamodel.Kind_Map:
+		it := in.MapIterator()
+		for !it.Done() {
+			_, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot iterate over %s", queryType(in))
+	}
+	return nil
+}
+
+func (e indexExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	return e.key.eval(q, in, func(key ipld.Node) error {
+		in, err := q.deref(in)
+		if err != nil {
+			return err
+		}
+		if in.IsNull() {
+			return emit(datamodel.Null)
+		}
+
+		var v ipld.Node
+		switch {
+		case in.Kind() == datamodel.Kind_Map && key.Kind() == datamodel.Kind_String:
+			k, _ := key.AsString()
+			v, err = in.LookupByString(k)
+		case in.Kind() == datamodel.Kind_List && key.Kind() == datamodel.Kind_Int:
+			i, _ := key.AsInt()
+			if i < 0 {
+				i += in.Length()
+			}
+			v, err = in.LookupByIndex(i)
+		default:
+			return fmt.Errorf("cannot index %s with %s", queryType(in), queryType(key))
+		}
+		if err != nil {
+			if !isNotFound(err) {
+				return err
+			}
+			v = datamodel.Null
+		}
+		return emit(v)
+	})
+}
+
+// tryErr wraps the errors of emit, which must not be suppressed by '?'.
+type tryErr struct{ err error }
+
+func (e tryErr) Error() string { return e.err.Error() }
+
+func (e tryExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	err := e.expr.eval(q, in, func(n ipld.Node) error {
+		if err := emit(n); err != nil {
+			return tryErr{err}
+		}
+		return nil
+	})
+	var te tryErr
+	if errors.As(err, &te) {
+		return te.err
+	}
+	if err != nil && q.ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+func (e collectExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	var values []ipld.Node
+	err := e.expr.eval(q, in, func(n ipld.Node) error {
+		values = append(values, n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	l, err := newList(values)
+	if err != nil {
+		return err
+	}
+	return emit(l)
+}
+
+func (e pipeExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	return e.left.eval(q, in, func(n ipld.Node) error {
+		return e.right.eval(q, n, emit)
+	})
+}
+
+func (e commaExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	if err := e.left.eval(q, in, emit); err != nil {
+		return err
+	}
+	return e.right.eval(q, in, emit)
+}
+
+func (e andExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	return e.left.eval(q, in, func(l ipld.Node) error {
+		if !truthy(l) {
+			return emit(basicnode.NewBool(false))
+		}
+		return e.right.eval(q, in, func(r ipld.Node) error {
+			return emit(basicnode.NewBool(truthy(r)))
+		})
+	})
+}
+
+func (e orExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	return e.left.eval(q, in, func(l ipld.Node) error {
+		if truthy(l) {
+			return emit(basicnode.NewBool(true))
+		}
+		return e.right.eval(q, in, func(r ipld.Node) error {
+			return emit(basicnode.NewBool(truthy(r)))
+		})
+	})
+}
+
+func (e compareExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	return e.left.eval(q, in, func(l ipld.Node) error {
+		return e.right.eval(q, in, func(r ipld.Node) error {
+			var result bool
+			switch e.op {
+			case "==":
+				result = queryEqual(l, r)
+			case "!=":
+				result = !queryEqual(l, r)
+			default:
+				c, err := queryCompare(l, r)
+				if err != nil {
+					return err
+				}
+				switch e.op {
+				case "<":
+					result = c < 0
+				case "<=":
+					result = c <= 0
+				case ">":
+					result = c > 0
+				case ">=":
+					result = c >= 0
+				}
+			}
+			return emit(basicnode.NewBool(result))
+		})
+	})
+}
+
+func (e funcExpr) eval(q *queryEnv, in ipld.Node, emit func(ipld.Node) error) error {
+	switch e.name {
+	case "empty":
+		return nil
+	case "not":
+		return emit(basicnode.NewBool(!truthy(in)))
+	case "select":
+		return e.arg.eval(q, in, func(n ipld.Node) error {
+			if truthy(n) {
+				return emit(in)
+			}
+			return nil
+		})
+	case "map":
+		return collectExpr{pipeExpr{iterateExpr{}, e.arg}}.eval(q, in, emit)
+	case "has":
+		return e.arg.eval(q, in, func(key ipld.Node) error {
+			v, err := q.deref(in)
+			if err != nil {
+				return err
+			}
+			var found bool
+			switch {
+			case v.Kind() == datamodel.Kind_Map && key.Kind() == datamodel.Kind_String:
+				k, _ := key.AsString()
+				_, err = v.LookupByString(k)
+				found = err == nil
+			case v.Kind() == datamodel.Kind_List && key.Kind() == datamodel.Kind_Int:
+				i, _ := key.AsInt()
+				found = i >= 0 && i < v.Length()
+			default:
+				return fmt.Errorf("cannot check whether %s has a %s key", queryType(v), queryType(key))
+			}
+			return emit(basicnode.NewBool(found))
+		})
+	case "type":
+		return emit(basicnode.NewString(queryType(in)))
+	}
+
+	// The remaining functions look through links.
+	in, err := q.deref(in)
+	if err != nil {
+		return err
+	}
+	switch e.name {
+	case "keys":
+		var keys []ipld.Node
+		switch in.Kind() {
+		case datamodel.Kind_Map:
+			ks, err := mapKeys(in)
+			if err != nil {
+				return err
+			}
+			for _, k := range ks {
+				keys = append(keys, basicnode.NewString(k))
+			}
+		case datamodel.Kind_List:
+			for i := int64(0); i < in.Length(); i++ {
+				keys = append(keys, basicnode.NewInt(i))
+			}
+		default:
+			return fmt.Errorf("%s has no keys", queryType(in))
+		}
+		l, err := newList(keys)
+		if err != nil {
+			return err
+		}
+		return emit(l)
+	case "length":
+		switch in.Kind() {
+		case datamodel.Kind_Null:
+			return emit(basicnode.NewInt(0))
+		case datamodel.Kind_Map, datamodel.Kind_List:
+			return emit(basicnode.NewInt(in.Length()))
+		case datamodel.Kind_String:
+			s, _ := in.AsString()
+			return emit(basicnode.NewInt(int64(utf8.RuneCountInString(s))))
+		case datamodel.Kind_Bytes:
+			b, _ := in.AsBytes()
+			return emit(basicnode.NewInt(int64(len(b))))
+		case datamodel.Kind_Int:
+			i, _ := in.AsInt()
+			return emit(basicnode.NewInt(max(i, -i)))
+		case datamodel.Kind_Float:
+			f, _ := in.AsFloat()
+			return emit(basicnode.NewFloat(math.Abs(f)))
+		default:
+			return fmt.Errorf("%s has no length", queryType(in))
+		}
+	}
+	return fmt.Errorf("unknown function %q", e.name)
+}
+
+// queryFuncs are the supported functions, and whether they take an argument.
+var queryFuncs = map[string]bool{
+	"empty":  false,
+	"not":    false,
+	"keys":   false,
+	"length": false,
+	"type":   false,
+	"select": true,
+	"map":    true,
+	"has":    true,
+}
+
+func newList(values []ipld.Node) (ipld.Node, error) {
+	nb := basicnode.Prototype.List.NewBuilder()
+	la, err := nb.BeginList(int64(len(values)))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if err := la.AssembleValue().AssignNode(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := la.Finish(); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+func truthy(n ipld.Node) bool {
+	if n.IsNull() {
+		return false
+	}
+	if b, err := n.AsBool(); err == nil && n.Kind() == datamodel.Kind_Bool {
+		return b
+	}
+	return true
+}
+
+func queryType(n ipld.Node) string {
+	switch n.Kind() {
+	case datamodel.Kind_Null:
+		return "null"
+	case datamodel.Kind_Bool:
+		return "boolean"
+	case datamodel.Kind_Int, datamodel.Kind_Float:
+		return "number"
+	case datamodel.Kind_String:
+		return "string"
+	case datamodel.Kind_Bytes:
+		return "bytes"
+	case datamodel.Kind_List:
+		return "array"
+	case datamodel.Kind_Map:
+		return "object"
+	case datamodel.Kind_Link:
+		return "link"
+	}
+	return n.Kind().String()
+}
+
+func asNumber(n ipld.Node) (float64, bool) {
+	switch n.Kind() {
+	case datamodel.Kind_Int:
+		i, err := n.AsInt()
+		return float64(i), err == nil
+	case datamodel.Kind_Float:
+		f, err := n.AsFloat()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func queryEqual(a, b ipld.Node) bool {
+	if fa, ok := asNumber(a); ok {
+		fb, ok := asNumber(b)
+		return ok && fa == fb
+	}
+	return datamodel.DeepEqual(a, b)
+}
+
+// queryCompare orders scalars of the same type, with null < booleans <
+// numbers < strings, like jq.
+func queryCompare(a, b ipld.Node) (int, error) {
+	rank := func(n ipld.Node) int {
+		switch n.Kind() {
+		case datamodel.Kind_Null:
+			return 0
+		case datamodel.Kind_Bool:
+			return 1
+		case datamodel.Kind_Int, datamodel.Kind_Float:
+			return 2
+		case datamodel.Kind_String:
+			return 3
+		}
+		return -1
+	}
+	ra, rb := rank(a), rank(b)
+	if ra < 0 || rb < 0 {
+		return 0, fmt.Errorf("cannot compare %s with %s", queryType(a), queryType(b))
+	}
+	if ra != rb {
+		return ra - rb, nil
+	}
+	switch ra {
+	case 1:
+		ba, _ := a.AsBool()
+		bb, _ := b.AsBool()
+		switch {
+		case ba == bb:
+			return 0, nil
+		case bb:
+			return -1, nil
+		}
+		return 1, nil
+	case 2:
+		fa, _ := asNumber(a)
+		fb, _ := asNumber(b)
+		switch {
+		case fa < fb:
+			return -1, nil
+		case fa > fb:
+			return 1, nil
+		}
+	case 3:
+		sa, _ := a.AsString()
+		sb, _ := b.AsString()
+		return strings.Compare(sa, sb), nil
+	}
+	return 0, nil
+}
+
+// Query parsing.
+//
+//	pipe    = comma { "|" comma }
+//	comma   = or { "," or }
+//	or      = and { "or" and }
+//	and     = compare { "and" compare }
+//	compare = postfix [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) postfix ]
+//	postfix = primary { "." name | "[" [ pipe ] "]" | "?" }
+//	primary = "." [ name | "[" [ pipe ] "]" ] | literal | "(" pipe ")"
+//	        | "[" [ pipe ] "]" | function [ "(" pipe ")" ]
+
+type queryToken struct {
+	kind string // "ident", "string", "number", "eof" or the punctuation itself
+	text string
+	pos  int
+}
+
+func lexQuery(s string) ([]queryToken, error) {
+	var toks []queryToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			var str string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &str); err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %w", i, err)
+			}
+			toks = append(toks, queryToken{"string", str, i})
+			i = j + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && strings.ContainsRune("0123456789.eE+-", rune(s[j])) {
+				if (s[j] == '+' || s[j] == '-') && s[j-1] != 'e' && s[j-1] != 'E' {
+					break
+				}
+				j++
+			}
+			toks = append(toks, queryToken{"number", s[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			toks = append(toks, queryToken{"ident", s[i:j], i})
+			i = j
+		default:
+			op := string(c)
+			if i+1 < len(s) && slices.Contains([]string{"==", "!=", "<=", ">="}, s[i:i+2]) {
+				op = s[i : i+2]
+			}
+			if !slices.Contains([]string{".", "|", ",", "[", "]", "(", ")", "?", "==", "!=", "<", "<=", ">", ">="}, op) {
+				return nil, fmt.Errorf("unexpected %q at %d", op, i)
+			}
+			toks = append(toks, queryToken{op, op, i})
+			i += len(op)
+		}
+	}
+	return append(toks, queryToken{"eof", "", len(s)}), nil
+}
+
+type queryParser struct {
+	toks []queryToken
+	pos  int
+}
+
+func parseQuery(s string) (queryExpr, error) {
+	toks, err := lexQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	p := &queryParser{toks: toks}
+	e, err := p.parsePipe()
+	if err == nil && p.peek().kind != "eof" {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return e, nil
+}
+
+func (p *queryParser) peek() queryToken { return p.toks[p.pos] }
+
+func (p *queryParser) next() queryToken {
+	t := p.toks[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) accept(kind string) bool {
+	if p.peek().kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) acceptIdent(name string) bool {
+	if t := p.peek(); t.kind == "ident" && t.text == name {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(kind string) error {
+	if !p.accept(kind) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *queryParser) unexpected() error {
+	t := p.peek()
+	if t.kind == "eof" {
+		return errors.New("unexpected end of query")
+	}
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *queryParser) parsePipe() (queryExpr, error) {
+	e, err := p.parseComma()
+	for err == nil && p.accept("|") {
+		var r queryExpr
+		if r, err = p.parseComma(); err == nil {
+			e = pipeExpr{e, r}
+		}
+	}
+	return e, err
+}
+
+func (p *queryParser) parseComma() (queryExpr, error) {
+	e, err := p.parseOr()
+	for err == nil && p.accept(",") {
+		var r queryExpr
+		if r, err = p.parseOr(); err == nil {
+			e = commaExpr{e, r}
+		}
+	}
+	return e, err
+}
+
+func (p *queryParser) parseOr() (queryExpr, error) {
+	e, err := p.parseAnd()
+	for err == nil && p.acceptIdent("or") {
+		var r queryExpr
+		if r, err = p.parseAnd(); err == nil {
+			e = orExpr{e, r}
+		}
+	}
+	return e, err
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	e, err := p.parseCompare()
+	for err == nil && p.acceptIdent("and") {
+		var r queryExpr
+		if r, err = p.parseCompare(); err == nil {
+			e = andExpr{e, r}
+		}
+	}
+	return e, err
+}
+
+func (p *queryParser) parseCompare() (queryExpr, error) {
+	e, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek().kind; op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		r, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return compareExpr{op, e, r}, nil
+	}
+	return e, nil
+}
+
+func (p *queryParser) parsePostfix() (queryExpr, error) {
+	e, err := p.parsePrimary()
+	for err == nil {
+		switch p.peek().kind {
+		case ".":
+			p.next()
+			var s queryExpr
+			if p.peek().kind == "[" {
+				s, err = p.parseBracket()
+			} else {
+				s, err = p.parseName()
+				s = indexExpr{s}
+			}
+			if err == nil {
+				e = pipeExpr{e, s}
+			}
+		case "[":
+			var s queryExpr
+			if s, err = p.parseBracket(); err == nil {
+				e = pipeExpr{e, s}
+			}
+		case "?":
+			p.next()
+			e = tryExpr{e}
+		default:
+			return e, nil
+		}
+	}
+	return nil, err
+}
+
+// parseName parses the field name after a dot.
+func (p *queryParser) parseName() (queryExpr, error) {
+	t := p.next()
+	if t.kind != "ident" && t.kind != "string" {
+		p.pos--
+		return nil, p.unexpected()
+	}
+	return literalExpr{basicnode.NewString(t.text)}, nil
+}
+
+// parseBracket parses "[]" or "[key]" after a value.
+func (p *queryParser) parseBracket() (queryExpr, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	if p.accept("]") {
+		return iterateExpr{}, nil
+	}
+	key, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	return indexExpr{key}, p.expect("]")
+}
+
+func (p *queryParser) parsePrimary() (queryExpr, error) {
+	t := p.peek()
+	switch t.kind {
+	case ".":
+		p.next()
+		switch p.peek().kind {
+		case "ident", "string":
+			key, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			return indexExpr{key}, nil
+		case "[":
+			return p.parseBracket()
+		}
+		return identityExpr{}, nil
+	case "string":
+		p.next()
+		return literalExpr{basicnode.NewString(t.text)}, nil
+	case "number":
+		p.next()
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return literalExpr{basicnode.NewInt(i)}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return literalExpr{basicnode.NewFloat(f)}, nil
+	case "(":
+		p.next()
+		e, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case "[":
+		p.next()
+		if p.accept("]") {
+			return collectExpr{funcExpr{name: "empty"}}, nil
+		}
+		e, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return collectExpr{e}, p.expect("]")
+	case "ident":
+		p.next()
+		switch t.text {
+		case "true":
+			return literalExpr{basicnode.NewBool(true)}, nil
+		case "false":
+			return literalExpr{basicnode.NewBool(false)}, nil
+		case "null":
+			return literalExpr{datamodel.Null}, nil
+		}
+		hasArg, ok := queryFuncs[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q at %d", t.text, t.pos)
+		}
+		if !hasArg {
+			return funcExpr{name: t.text}, nil
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		arg, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return funcExpr{t.text, arg}, p.expect(")")
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,151 @@
+package dagcmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runQuery evaluates query on the dag-json input, and returns the dag-json
+// encoding of its outputs.
+func runQuery(t *testing.T, query, input string) ([]string, error) {
+	t.Helper()
+	expr, err := parseQuery(query)
+	require.NoError(t, err, query)
+
+	nb := basicnode.Prototype.Any.NewBuilder()
+	require.NoError(t, dagjson.Decode(nb, strings.NewReader(input)))
+
+	var out []string
+	q := &queryEnv{ctx: context.Background()}
+	err = expr.eval(q, nb.Build(), func(n ipld.Node) error {
+		var buf bytes.Buffer
+		if err := dagjson.Encode(n, &buf); err != nil {
+			return err
+		}
+		out = append(out, buf.String())
+		return nil
+	})
+	return out, err
+}
+
+func TestQueryEval(t *testing.T) {
+	// keys are sorted, as in the dag-json encoding of the outputs
+	const input = `{"empty":null,"list":[1,2,3],"mixed":[null,"x",2.5,false],"name":"kubo","nested":{"a":{"b":true}}}`
+
+	for _, tc := range []struct {
+		query  string
+		output []string
+	}{
+		{`.`, []string{input}},
+		{`.name`, []string{`"kubo"`}},
+		{`.nested.a.b`, []string{`true`}},
+		{`.["name"]`, []string{`"kubo"`}},
+		{`."name"`, []string{`"kubo"`}},
+		{`.missing`, []string{`null`}},
+		{`.empty.a`, []string{`null`}},
+		{`.list[0]`, []string{`1`}},
+		{`.list[-1]`, []string{`3`}},
+		{`.list[5]`, []string{`null`}},
+		{`.list[]`, []string{`1`, `2`, `3`}},
+		{`.nested[]`, []string{`{"b":true}`}},
+		{`.list | .[1]`, []string{`2`}},
+		{`.name, .list[0]`, []string{`"kubo"`, `1`}},
+		{`[.list[] | select(. > 1)]`, []string{`[2,3]`}},
+		{`[]`, []string{`[]`}},
+		{`[.list[], .name]`, []string{`[1,2,3,"kubo"]`}},
+		{`.list | map(. >= 2)`, []string{`[false,true,true]`}},
+		{`.mixed | map(type)`, []string{`["null","string","number","boolean"]`}},
+		{`keys`, []string{`["empty","list","mixed","name","nested"]`}},
+		{`.list | keys`, []string{`[0,1,2]`}},
+		{`.name | length`, []string{`4`}},
+		{`.list | length`, []string{`3`}},
+		{`.empty | length`, []string{`0`}},
+		{`-2 | length`, []string{`2`}},
+		{`has("name"), has("other")`, []string{`true`, `false`}},
+		{`.list | has(2), has(3)`, []string{`true`, `false`}},
+		{`.name == "kubo" and .list[0] != 2`, []string{`true`}},
+		{`.empty or .nested.a.b`, []string{`true`}},
+		{`.empty and .nested.a.b`, []string{`false`}},
+		{`.empty | not`, []string{`true`}},
+		{`.list[0] == 1.0`, []string{`true`}},
+		{`null < false, false < 0, 0 < "a", "a" < "b"`, []string{`true`, `true`, `true`, `true`}},
+		{`.name[]?`, nil},
+		{`(.list[], .name[])?`, []string{`1`, `2`, `3`}},
+		{`empty`, nil},
+		{`1.5, "s", true, null`, []string{`1.5`, `"s"`, `true`, `null`}},
+		{`.list[] | select(. == 2) | . < 3`, []string{`true`}},
+	} {
+		out, err := runQuery(t, tc.query, input)
+		require.NoError(t, err, tc.query)
+		assert.Equal(t, tc.output, out, tc.query)
+	}
+}
+
+func TestQueryEvalErrors(t *testing.T) {
+	const input = `{"name":"kubo","list":[1,2,3]}`
+
+	for _, tc := range []struct {
+		query string
+		err   string
+	}{
+		{`.name[]`, "cannot iterate over string"},
+		{`.list["a"]`, "cannot index array with string"},
+		{`.name[0]`, "cannot index string with number"},
+		{`. < 1`, "cannot compare object with number"},
+		{`.list | has("a")`, "cannot check whether array has a string key"},
+		{`.name | keys`, "string has no keys"},
+		{`true | length`, "boolean has no length"},
+	} {
+		_, err := runQuery(t, tc.query, input)
+		require.Error(t, err, tc.query)
+		assert.Contains(t, err.Error(), tc.err, tc.query)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	for _, query := range []string{
+		`.`,
+		`.a.b[0]["c"]?`,
+		`.a | .b, .c`,
+		`[.[] | select(.a == "x" or .b != 1 and not)]`,
+		`map(has("a")) | length`,
+		`(.a, .b) | type`,
+		`.a <= -1.5e3`,
+		` . a `,
+	} {
+		_, err := parseQuery(query)
+		assert.NoError(t, err, query)
+	}
+
+	for _, tc := range []struct {
+		query string
+		err   string
+	}{
+		{``, "unexpected end of query"},
+		{`.a |`, "unexpected end of query"},
+		{`.[`, "unexpected end of query"},
+		{`.a]`, `unexpected "]" at 2`},
+		{`.a $`, `unexpected "$" at 3`},
+		{`"abc`, "unterminated string at 0"},
+		{`"\x"`, "invalid string at 0"},
+		{`1.2.3`, `invalid number "1.2.3" at 0`},
+		{`foo`, `unknown function "foo" at 0`},
+		{`select`, "unexpected end of query"},
+		{`map(.a`, "unexpected end of query"},
+		{`. == == .`, `unexpected "==" at 5`},
+		{`.a.1`, `unexpected "1" at 3`},
+	} {
+		_, err := parseQuery(tc.query)
+		if assert.Error(t, err, tc.query) {
+			assert.Contains(t, err.Error(), tc.err, tc.query)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package dagcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ipfs/boxo/files"
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/core/commands/cmdutils"
+	iface "github.com/ipfs/kubo/core/coreiface"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/bindnode"
+	"github.com/ipld/go-ipld-prime/schema"
+	schemadmt "github.com/ipld/go-ipld-prime/schema/dmt"
+	schemadsl "github.com/ipld/go-ipld-prime/schema/dsl"
+)
+
+const (
+	schemaOptionName     = "schema"
+	schemaTypeOptionName = "schema-type"
+	forceOptionName      = "force"
+
+	// Maximum size of the DSL source of a schema.
+	maxSchemaSize = 1 << 20
+)
+
+// schemasKey is the datastore prefix of the registered IPLD Schemas.
+var schemasKey = datastore.NewKey("/local/schemas")
+
+var schemaNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// SchemaInfo describes a registered IPLD Schema.
+type SchemaInfo struct {
+	Name  string
+	Types []string
+}
+
+// SchemaList is the output type of 'dag schema ls'.
+type SchemaList struct {
+	Schemas []SchemaInfo
+}
+
+// DagValidateOutput is the output type of 'dag validate'.
+type DagValidateOutput struct {
+	Cid    cid.Cid
+	Type   string
+	Blocks int
+}
+
+func dagSchemaAdd(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+
+	name := req.Arguments[0]
+	if !schemaNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid schema name %q: must only contain letters, digits, '.', '_' and '-'", name)
+	}
+
+	it := req.Files.Entries()
+	if !it.Next() {
+		if it.Err() != nil {
+			return it.Err()
+		}
+		return errors.New("missing schema file")
+	}
+	file := files.FileFromEntry(it)
+	if file == nil {
+		return errors.New("expected a regular file")
+	}
+	src, err := io.ReadAll(io.LimitReader(file, maxSchemaSize+1))
+	if err != nil {
+		return err
+	}
+	if len(src) > maxSchemaSize {
+		return fmt.Errorf("schema exceeds the maximum size of %d bytes", maxSchemaSize)
+	}
+
+	_, typeNames, err := compileSchema(name, src)
+	if err != nil {
+		return err
+	}
+
+	key := schemasKey.ChildString(name)
+	force, _ := req.Options[forceOptionName].(bool)
+	if !force {
+		has, err := nd.Repo.Datastore().Has(req.Context, key)
+		if err != nil {
+			return err
+		}
+		if has {
+			return fmt.Errorf("schema %q already exists, use --force to replace it", name)
+		}
+	}
+	if err = nd.Repo.Datastore().Put(req.Context, key, src); err != nil {
+		return err
+	}
+
+	return cmds.EmitOnce(res, &SchemaInfo{Name: name, Types: typeNames})
+}
+
+func dagSchemaLs(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+
+	results, err := nd.Repo.Datastore().Query(req.Context, query.Query{
+		Prefix: schemasKey.String(),
+		Orders: []query.Order{query.OrderByKey{}},
+	})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	list := SchemaList{Schemas: []SchemaInfo{}}
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		name := datastore.RawKey(r.Key).BaseNamespace()
+		_, typeNames, err := compileSchema(name, r.Value)
+		if err != nil {
+			return err
+		}
+		list.Schemas = append(list.Schemas, SchemaInfo{Name: name, Types: typeNames})
+	}
+
+	return cmds.EmitOnce(res, &list)
+}
+
+func dagSchemaGet(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+
+	src, err := getSchemaSource(req.Context, nd.Repo.Datastore(), req.Arguments[0])
+	if err != nil {
+		return err
+	}
+	return res.Emit(strings.NewReader(string(src)))
+}
+
+func dagSchemaRm(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+
+	name := req.Arguments[0]
+	if !schemaNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid schema name %q", name)
+	}
+	key := schemasKey.ChildString(name)
+	has, err := nd.Repo.Datastore().Has(req.Context, key)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return fmt.Errorf("schema %q not found", name)
+	}
+	return nd.Repo.Datastore().Delete(req.Context, key)
+}
+
+func dagValidate(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+	nd, err := cmdenv.GetNode(env)
+	if err != nil {
+		return err
+	}
+	api, err := cmdenv.GetApi(env, req)
+	if err != nil {
+		return err
+	}
+
+	typ, err := schemaFromRequest(req, nd.Repo.Datastore())
+	if err != nil {
+		return err
+	}
+	if typ == nil {
+		return fmt.Errorf("missing --%s", schemaOptionName)
+	}
+
+	p, err := cmdutils.PathOrCidPath(req.Arguments[0])
+	if err != nil {
+		return err
+	}
+	rp, remainder, err := api.ResolvePath(req.Context, p)
+	if err != nil {
+		return err
+	}
+	if len(remainder) > 0 {
+		return fmt.Errorf("%s does not resolve to a block", req.Arguments[0])
+	}
+	c := rp.RootCid()
+	root, err := loadNode(req.Context, api, c)
+	if err != nil {
+		return err
+	}
+
+	v := newSchemaValidator(req.Context, api)
+	if err = v.validate(c, root, typ); err != nil {
+		return err
+	}
+
+	return cmds.EmitOnce(res, &DagValidateOutput{
+		Cid:    c,
+		Type:   typ.Name(),
+		Blocks: v.blocks,
+	})
+}
+
+// schemaFromRequest returns the schema type selected by the --schema and
+// --schema-type options, or nil if no schema was requested.
+func schemaFromRequest(req *cmds.Request, ds datastore.Read) (schema.Type, error) {
+	name, _ := req.Options[schemaOptionName].(string)
+	typeName, _ := req.Options[schemaTypeOptionName].(string)
+	if name == "" {
+		if typeName != "" {
+			return nil, fmt.Errorf("--%s requires --%s", schemaTypeOptionName, schemaOptionName)
+		}
+		return nil, nil
+	}
+
+	src, err := getSchemaSource(req.Context, ds, name)
+	if err != nil {
+		return nil, err
+	}
+	ts, typeNames, err := compileSchema(name, src)
+	if err != nil {
+		return nil, err
+	}
+	if typeName == "" {
+		if len(typeNames) == 0 {
+			return nil, fmt.Errorf("schema %q declares no types", name)
+		}
+		typeName = typeNames[0]
+	}
+	typ := ts.TypeByName(typeName)
+	if typ == nil || !slices.Contains(typeNames, typeName) {
+		return nil, fmt.Errorf("schema %q has no type %q", name, typeName)
+	}
+	return typ, nil
+}
+
+func getSchemaSource(ctx context.Context, ds datastore.Read, name string) ([]byte, error) {
+	if !schemaNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid schema name %q", name)
+	}
+	src, err := ds.Get(ctx, schemasKey.ChildString(name))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, fmt.Errorf("schema %q not found, add it with 'ipfs dag schema add'", name)
+		}
+		return nil, err
+	}
+	return src, nil
+}
+
+// compileSchema parses the DSL form of an IPLD Schema and returns its type
+// system, with the names of the declared types in declaration order.
+func compileSchema(name string, src []byte) (*schema.TypeSystem, []string, error) {
+	sch, err := schemadsl.Parse(name, strings.NewReader(string(src)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid schema %q: %w", name, err)
+	}
+	ts := new(schema.TypeSystem)
+	ts.Init()
+	if err = schemadmt.Compile(ts, sch); err != nil {
+		return nil, nil, fmt.Errorf("invalid schema %q: %w", name, err)
+	}
+	return ts, sch.Types.Keys, nil
+}
+
+// schemaValidator checks that IPLD nodes conform to a schema type. Links
+// typed as &T in the schema are loaded and checked against T, unless api is
+// nil.
+type schemaValidator struct {
+	ctx    context.Context
+	api    iface.CoreAPI
+	seen   map[string]struct{}
+	blocks int
+}
+
+func newSchemaValidator(ctx context.Context, api iface.CoreAPI) *schemaValidator {
+	return &schemaValidator{
+		ctx:  ctx,
+		api:  api,
+		seen: make(map[string]struct{}),
+	}
+}
+
+// validate checks the node n, at the root of block c, against typ.
+func (v *schemaValidator) validate(c cid.Cid, n ipld.Node, typ schema.Type) error {
+	if err := v.ctx.Err(); err != nil {
+		return err
+	}
+	v.seen[c.KeyString()+"\x00"+typ.Name()] = struct{}{}
+	v.blocks++
+
+	nb := bindnode.Prototype(nil, typ).Representation().NewBuilder()
+	if err := copyNode(datamodel.Path{}, n, nb); err != nil {
+		return fmt.Errorf("%s does not conform to %s: %w", c, typ.Name(), err)
+	}
+	if v.api == nil {
+		return nil
+	}
+	return v.walk(nb.Build().(schema.TypedNode))
+}
+
+// walk follows the typed links below n.
+func (v *schemaValidator) walk(n schema.TypedNode) error {
+	switch n.Kind() {
+	case datamodel.Kind_Map:
+		it := n.MapIterator()
+		for !it.Done() {
+			_, val, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err = v.walkValue(val); err != nil {
+				return err
+			}
+		}
+	case datamodel.Kind_List:
+		it := n.ListIterator()
+		for !it.Done() {
+			_, val, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err = v.walkValue(val); err != nil {
+				return err
+			}
+		}
+	case datamodel.Kind_Link:
+		lt, ok := n.Type().(*schema.TypeLink)
+		if !ok || !lt.HasReferencedType() {
+			return nil
+		}
+		lnk, err := n.AsLink()
+		if err != nil {
+			return err
+		}
+		cl, ok := lnk.(cidlink.Link)
+		if !ok {
+			return nil
+		}
+		typ := lt.ReferencedType()
+		if _, ok := v.seen[cl.Cid.KeyString()+"\x00"+typ.Name()]; ok {
+			return nil
+		}
+		linked, err := loadNode(v.ctx, v.api, cl.Cid)
+		if err != nil {
+			return fmt.Errorf("cannot load %s: %w", cl.Cid, err)
+		}
+		return v.validate(cl.Cid, linked, typ)
+	}
+	return nil
+}
+
+func (v *schemaValidator) walkValue(n datamodel.Node) error {
+	if n.IsAbsent() || n.IsNull() {
+		return nil
+	}
+	tn, ok := n.(schema.TypedNode)
+	if !ok {
+		return nil
+	}
+	return v.walk(tn)
+}
+
+// copyNode is like datamodel.Copy, but reports the path of the value that
+// could not be assembled.
+func copyNode(p datamodel.Path, n datamodel.Node, na datamodel.NodeAssembler) error {
+	switch n.Kind() {
+	case datamodel.Kind_Map:
+		ma, err := na.BeginMap(n.Length())
+		if err != nil {
+			return pathError(p, err)
+		}
+		it := n.MapIterator()
+		for !it.Done() {
+			k, val, err := it.Next()
+			if err != nil {
+				return pathError(p, err)
+			}
+			ks, err := k.AsString()
+			if err != nil {
+				return pathError(p, err)
+			}
+			if err = ma.AssembleKey().AssignString(ks); err != nil {
+				return pathError(p, err)
+			}
+			if err = copyNode(p.AppendSegmentString(ks), val, ma.AssembleValue()); err != nil {
+				return err
+			}
+		}
+		if err = ma.Finish(); err != nil {
+			return pathError(p, err)
+		}
+		return nil
+	case datamodel.Kind_List:
+		la, err := na.BeginList(n.Length())
+		if err != nil {
+			return pathError(p, err)
+		}
+		it := n.ListIterator()
+		for !it.Done() {
+			i, val, err := it.Next()
+			if err != nil {
+				return pathError(p, err)
+			}
+			if err = copyNode(p.AppendSegmentInt(i), val, la.AssembleValue()); err != nil {
+				return err
+			}
+		}
+		if err = la.Finish(); err != nil {
+			return pathError(p, err)
+		}
+		return nil
+	default:
+		if err := datamodel.Copy(n, na); err != nil {
+			return pathError(p, err)
+		}
+		return nil
+	}
+}
+
+func pathError(p datamodel.Path, err error) error {
+	if p.Len() == 0 {
+		return err
+	}
+	return fmt.Errorf("at %s: %w", p, err)
+}
@@ -13,5 +13,6 @@ var DiagCmd = &cmds.Command{
 		"sys":     sysDiagCmd,
 		"cmds":    ActiveReqsCmd,
 		"profile": sysProfileCmd,
+		"audit":   diagAuditCmd,
 	},
 }
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/auditlog"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+)
+
+const (
+	diagAuditUserOptionName    = "user"
+	diagAuditCommandOptionName = "command"
+	diagAuditOutcomeOptionName = "outcome"
+	diagAuditSinceOptionName   = "since"
+)
+
+var diagAuditCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Query the audit log of the RPC API.",
+		ShortDescription: `
+Lists the RPC requests recorded in the audit log, oldest first. The audit log
+is written by the daemon when API.AuditLog.Enabled is set.
+`,
+		LongDescription: `
+Lists the RPC requests recorded in the audit log, oldest first. The audit log
+is written by the daemon when API.AuditLog.Enabled is set, and holds one JSON
+object per request: the user of API.Authorizations that made it, the command
+and its arguments with secrets redacted, the outcome and the duration.
+
+Entries can be filtered by user, command, outcome, and age:
+
+  > ipfs diag audit --user=ci --command=pin/rm --since=24h
+
+The --command option matches the command and its subcommands: 'pin' matches
+'pin/rm' and 'pin/remote/add'. The --outcome option is one of 'success',
+'denied' and 'error'.
+
+Use --enc=json to get the entries as JSON objects.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(diagAuditUserOptionName, "Only list the requests of this user."),
+		cmds.StringOption(diagAuditCommandOptionName, "Only list the requests of this command and its subcommands, such as 'pin/rm'."),
+		cmds.StringOption(diagAuditOutcomeOptionName, "Only list the requests with this outcome: 'success', 'denied' or 'error'."),
+		cmds.StringOption(diagAuditSinceOptionName, "Only list the requests made during this last duration, such as '24h'."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		cfg, err := nd.Repo.Config()
+		if err != nil {
+			return err
+		}
+
+		user, _ := req.Options[diagAuditUserOptionName].(string)
+		command, _ := req.Options[diagAuditCommandOptionName].(string)
+		command = strings.Trim(command, "/ ")
+		outcome, _ := req.Options[diagAuditOutcomeOptionName].(string)
+		switch outcome {
+		case "", auditlog.OutcomeSuccess, auditlog.OutcomeDenied, auditlog.OutcomeError:
+		default:
+			return fmt.Errorf("invalid outcome %q: must be %q, %q or %q", outcome,
+				auditlog.OutcomeSuccess, auditlog.OutcomeDenied, auditlog.OutcomeError)
+		}
+		var since time.Time
+		if s, _ := req.Options[diagAuditSinceOptionName].(string); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q: %w", s, err)
+			}
+			since = time.Now().Add(-d)
+		}
+
+		path := auditlog.Path(nd.Repo.Path(), cfg.API.AuditLog)
+		if !cfg.API.AuditLog.Enabled.WithDefault(config.DefaultAuditLogEnabled) {
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				return errors.New("the audit log is disabled, set API.AuditLog.Enabled to enable it")
+			}
+		}
+
+		return auditlog.Read(path, func(e *auditlog.Entry) error {
+			if user != "" && e.User != user {
+				return nil
+			}
+			if command != "" && e.Command != command && !strings.HasPrefix(e.Command, command+"/") {
+				return nil
+			}
+			if outcome != "" && e.Outcome != outcome {
+				return nil
+			}
+			if e.Time.Before(since) {
+				return nil
+			}
+			return res.Emit(e)
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, e *auditlog.Entry) error {
+			user := e.User
+			if user == "" {
+				user = "-"
+			}
+			if e.Subject != "" {
+				user += "(" + e.Subject + ")"
+			}
+			line := fmt.Sprintf("%s %s %s %s %d %s", e.Time.Format(time.RFC3339), user, e.Outcome, e.Command, e.Status, e.Duration)
+			if len(e.Args) > 0 {
+				line += fmt.Sprintf(" %q", e.Args)
+			}
+			if e.Error != "" {
+				line += fmt.Sprintf(" error=%q", e.Error)
+			}
+			_, err := fmt.Fprintln(w, line)
+			return err
+		}),
+	},
+	Type: auditlog.Entry{},
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/core/events"
+)
+
+const (
+	eventsTypeOptionName        = "type"
+	eventsLastEventIDOptionName = "last-event-id"
+)
+
+var EventsCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Stream the events of the daemon.",
+		ShortDescription: `
+Outputs the events of the daemon as they happen, until interrupted. With
+--enc=json, each event is a JSON object on its own line.
+`,
+		LongDescription: `
+Outputs the events of the daemon as they happen, until interrupted. With
+--enc=json, each event is a JSON object on its own line, holding the ID, the
+type and the time of the event, and data depending on its type:
+
+  pin.added           a path was pinned: Path, Cid, Recursive, Name
+  pin.failed          pinning a path failed: Path, Cid, Recursive, Name, Error
+  pin.removed         a path was unpinned: Path, Cid, Recursive
+  pin.remote.status   the status of a remote pin changed: Service, RequestID,
+                      Cid, Name, Status
+  mfs.root.changed    the root of MFS changed: Cid
+  peer.connected      a peer connected: Peer
+  peer.disconnected   a peer disconnected: Peer
+  gc.started          a garbage collection run started
+  gc.completed        a garbage collection run completed: Removed, Errors,
+                      Duration
+  provide.progress    CIDs were reprovided: Provided, Duration, Complete
+  ipns.republished    an IPNS record was republished: Name, Value
+
+The --type option only outputs the events of the given types. A trailing '*'
+matches any suffix:
+
+  > ipfs events --type='pin.*' --type=gc.completed
+
+The daemon holds its last 1024 events. A stream can resume after the last
+event it received with --last-event-id, to output the events emitted since
+then. When the daemon no longer holds that event, an event of type
+events.lost is output first, followed by all the events the daemon holds.
+
+Over HTTP, /api/v0/events is also served as Server-Sent Events to GET or
+POST requests with the 'Accept: text/event-stream' header. The 'type' query
+parameter filters the events, and the 'Last-Event-ID' header resumes the
+stream.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.DelimitedStringsOption(",", eventsTypeOptionName, "t", "Only output the events of the given types (comma-separated)."),
+		cmds.StringOption(eventsLastEventIDOptionName, "Resume after the event with the given ID."),
+	},
+	NoLocal: true,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsDaemon {
+			return errors.New("events are only emitted by a running daemon")
+		}
+
+		// the delimiter is only applied by the CLI, not to HTTP requests
+		var types []string
+		typeOpts, _ := req.Options[eventsTypeOptionName].([]string)
+		for _, t := range typeOpts {
+			types = append(types, strings.Split(t, ",")...)
+		}
+		lastEventID, _ := req.Options[eventsLastEventIDOptionName].(string)
+		sub, err := nd.Events.Subscribe(types, lastEventID)
+		if err != nil {
+			return err
+		}
+		defer sub.Close()
+
+		if f, ok := res.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		for {
+			select {
+			case ev, ok := <-sub.Events():
+				if !ok {
+					return sub.Err()
+				}
+				if err := res.Emit(&ev); err != nil {
+					return err
+				}
+			case <-req.Context.Done():
+				return nil
+			}
+		}
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, ev *events.Event) error {
+			id := ev.ID
+			if id == "" {
+				id = "-"
+			}
+			_, err := fmt.Fprintf(w, "%s %s %s", ev.Time.Format(time.RFC3339), id, ev.Type)
+			if err != nil {
+				return err
+			}
+			if ev.Data != nil {
+				data, err := json.Marshal(ev.Data)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(w, " %s", data)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprintln(w)
+			return err
+		}),
+	},
+	Type: events.Event{},
+}
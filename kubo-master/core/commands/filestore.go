@@ -2,15 +2,18 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	filestore "github.com/ipfs/boxo/filestore"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	core "github.com/ipfs/kubo/core"
 	cmdenv "github.com/ipfs/kubo/core/commands/cmdenv"
 	e "github.com/ipfs/kubo/core/commands/e"
+	corerepo "github.com/ipfs/kubo/core/corerepo"
 
 	"github.com/ipfs/go-cid"
 )
@@ -20,15 +23,19 @@ var FileStoreCmd = &cmds.Command{
 		Tagline: "Interact with filestore objects.",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"ls":     lsFileStore,
-		"verify": verifyFileStore,
-		"dups":   dupsFileStore,
+		"ls":      lsFileStore,
+		"verify":  verifyFileStore,
+		"dups":    dupsFileStore,
+		"rm":      rmFileStore,
+		"gc":      gcFileStore,
+		"reindex": reindexFileStore,
 	},
 }
 
 const (
 	fileOrderOptionName       = "file-order"
 	removeBadBlocksOptionName = "remove-bad-blocks"
+	removeChangedOptionName   = "changed"
 )
 
 var lsFileStore = &cmds.Command{
@@ -250,6 +257,247 @@ var dupsFileStore = &cmds.Command{
 	Type:     RefWrapper{},
 }
 
+var rmFileStore = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove objects from the filestore.",
+		LongDescription: `
+Remove references from the filestore. Each <obj> is either the CID of a
+filestore block, or the path of a backing file, in which case every
+block referencing that file is removed. The backing files themselves are
+never touched.
+
+WARNING: This may remove pinned data. You should run 'ipfs pin verify'
+after running this command and correct any issues.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("obj", true, true, "Cid of objects or paths of backing files to remove."),
+	},
+	PreRun: func(req *cmds.Request, env cmds.Environment) error {
+		// Paths are resolved by the daemon, make them independent of the
+		// working directory of the client.
+		for i, arg := range req.Arguments {
+			if _, err := cid.Decode(arg); err == nil {
+				continue
+			}
+			abs, err := filepath.Abs(arg)
+			if err != nil {
+				return err
+			}
+			req.Arguments[i] = abs
+		}
+		return nil
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, fs, err := getFilestore(env)
+		if err != nil {
+			return err
+		}
+
+		var failed bool
+		for _, arg := range req.Arguments {
+			var entries []*filestore.ListRes
+			if c, err := cid.Decode(arg); err == nil {
+				entries = []*filestore.ListRes{filestore.List(req.Context, fs, c)}
+			} else {
+				entries, err = corerepo.FilestoreFileEntries(req.Context, n, filepath.Clean(arg))
+				if err != nil {
+					return err
+				}
+				if len(entries) == 0 {
+					entries = []*filestore.ListRes{{
+						Status:   filestore.StatusKeyNotFound,
+						ErrorMsg: fmt.Sprintf("%s: no filestore entries for this file", arg),
+					}}
+				}
+			}
+
+			for _, r := range entries {
+				if r.Status == filestore.StatusOk {
+					if err := fs.FileManager().DeleteBlock(req.Context, r.Key); err != nil {
+						return err
+					}
+				} else {
+					failed = true
+				}
+				if err := res.Emit(r); err != nil {
+					return err
+				}
+			}
+		}
+		if failed {
+			return errors.New("some objects could not be removed")
+		}
+		return nil
+	},
+	PostRun: cmds.PostRunMap{
+		cmds.CLI: func(res cmds.Response, re cmds.ResponseEmitter) error {
+			enc, err := cmdenv.GetCidEncoder(res.Request())
+			if err != nil {
+				return err
+			}
+			return streamResult(func(v interface{}, out io.Writer) nonFatalError {
+				r := v.(*filestore.ListRes)
+				if r.Status != filestore.StatusOk {
+					if r.ErrorMsg != "" {
+						return nonFatalError(r.ErrorMsg)
+					}
+					return nonFatalError(fmt.Sprintf("%s: %s", enc.Encode(r.Key), r.Status))
+				}
+				fmt.Fprintf(out, "removed %s\n", r.FormatLong(enc.Encode))
+				return ""
+			})(res, re)
+		},
+	},
+	Type: filestore.ListRes{},
+}
+
+var gcFileStore = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove filestore objects whose backing file is gone.",
+		LongDescription: `
+Verify every object in the filestore and remove the ones whose backing
+file can no longer be found. With --changed, objects whose backing file
+has changed are removed too.
+
+The output is:
+
+<status> <hash> <size> <path> <offset>
+
+WARNING: This may remove pinned data. You should run 'ipfs pin verify'
+after running this command and correct any issues.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(removeChangedOptionName, "Also remove objects whose backing file has changed."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		_, fs, err := getFilestore(env)
+		if err != nil {
+			return err
+		}
+
+		removeChanged, _ := req.Options[removeChangedOptionName].(bool)
+		next, err := filestore.VerifyAll(req.Context, fs, false)
+		if err != nil {
+			return err
+		}
+
+		for r := next(req.Context); r != nil; r = next(req.Context) {
+			switch r.Status {
+			case filestore.StatusFileNotFound:
+			case filestore.StatusFileChanged:
+				if !removeChanged {
+					continue
+				}
+			default:
+				continue
+			}
+
+			if err := fs.FileManager().DeleteBlock(req.Context, r.Key); err != nil {
+				return err
+			}
+			if err := res.Emit(r); err != nil {
+				return err
+			}
+		}
+
+		return req.Context.Err()
+	},
+	PostRun: cmds.PostRunMap{
+		cmds.CLI: func(res cmds.Response, re cmds.ResponseEmitter) error {
+			enc, err := cmdenv.GetCidEncoder(res.Request())
+			if err != nil {
+				return err
+			}
+			return streamResult(func(v interface{}, out io.Writer) nonFatalError {
+				r := v.(*filestore.ListRes)
+				fmt.Fprintf(out, "%s %s\n", r.Status.Format(), r.FormatLong(enc.Encode))
+				return ""
+			})(res, re)
+		},
+	},
+	Type: filestore.ListRes{},
+}
+
+// FilestoreReindexOutput is the output type of "filestore reindex".
+type FilestoreReindexOutput struct {
+	Path string
+	From string
+	To   string
+}
+
+var reindexFileStore = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Re-add changed files to the filestore and update their pins.",
+		LongDescription: `
+Re-add each <path> to the filestore after its content changed, and update
+every recursive pin that contained the previous version of the file, as its
+root or inside a UnixFS directory. Stale references to the file are removed
+from the filestore. Files that changed together must be given together, as
+the updated pins cannot hold the previous version of a file.
+
+The daemon does this automatically for all files in the filestore when
+Experimental.FilestoreAutoReindex is enabled.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, true, "Paths of changed backing files."),
+	},
+	PreRun: func(req *cmds.Request, env cmds.Environment) error {
+		for i, arg := range req.Arguments {
+			abs, err := filepath.Abs(arg)
+			if err != nil {
+				return err
+			}
+			req.Arguments[i] = abs
+		}
+		return nil
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, _, err := getFilestore(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+		enc, err := cmdenv.GetCidEncoder(req)
+		if err != nil {
+			return err
+		}
+
+		paths := make([]string, len(req.Arguments))
+		for i, path := range req.Arguments {
+			paths[i] = filepath.Clean(path)
+		}
+		updates, err := corerepo.FilestoreReindex(req.Context, n, api, paths...)
+		if err != nil {
+			return err
+		}
+		for _, u := range updates {
+			for _, path := range u.Paths {
+				if err := res.Emit(&FilestoreReindexOutput{
+					Path: path,
+					From: enc.Encode(u.From),
+					To:   enc.Encode(u.To),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *FilestoreReindexOutput) error {
+			_, err := fmt.Fprintf(w, "%s: pin updated %s -> %s\n", out.Path, out.From, out.To)
+			return err
+		}),
+	},
+	Type: FilestoreReindexOutput{},
+}
+
 func getFilestore(env cmds.Environment) (*core.IpfsNode, *filestore.Filestore, error) {
 	n, err := cmdenv.GetNode(env)
 	if err != nil {
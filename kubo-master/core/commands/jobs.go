@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	oldcmds "github.com/ipfs/kubo/commands"
+)
+
+const jobIDArgName = "job-id"
+
+var JobsCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Manage background jobs of the daemon.",
+		ShortDescription: `
+Long-running commands, such as 'ipfs pin add', 'ipfs pin update' and
+'ipfs dag import', accept an --async option to run as a background job of the
+daemon. They output the ID of the job right away, instead of keeping the
+request open until the command completes.
+`,
+		LongDescription: `
+Long-running commands, such as 'ipfs pin add', 'ipfs pin update' and
+'ipfs dag import', accept an --async option to run as a background job of the
+daemon. They output the ID of the job right away, instead of keeping the
+request open until the command completes:
+
+  > job=$(ipfs pin add --async --progress bafy...)
+  > ipfs jobs status $job
+  > ipfs jobs wait $job
+
+The state of the jobs is persisted in the repo, with the last values output by
+their command: its progress while the job runs, and its result once the job
+succeeded. Jobs which were running when the daemon stopped are failed.
+Finished jobs are kept for a week. Running jobs are also listed by
+'ipfs diag cmds'.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls":     jobsLsCmd,
+		"status": jobsStatusCmd,
+		"cancel": jobsCancelCmd,
+		"wait":   jobsWaitCmd,
+	},
+}
+
+// JobList is the output of 'ipfs jobs ls'.
+type JobList struct {
+	Jobs []oldcmds.Job
+}
+
+var jobsLsCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "List background jobs.",
+		ShortDescription: `
+Lists the running and finished background jobs, oldest first.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		jobs, err := env.(*oldcmds.Context).GetJobs()
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &JobList{Jobs: jobs.List()})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *JobList) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tStatus\tStartTime\tRunTime\tCommand")
+			for _, job := range out.Jobs {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", job.ID, job.Status,
+					job.StartTime.Format(time.Stamp), jobRunTime(&job), jobCommandLine(&job))
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: JobList{},
+}
+
+var jobsStatusCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Show the status of a background job.",
+		ShortDescription: `
+Shows the status of a background job, and the last values output by its
+command: its progress while the job runs, and its result once the job
+succeeded.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg(jobIDArgName, true, false, "ID of the job."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		jobs, err := env.(*oldcmds.Context).GetJobs()
+		if err != nil {
+			return err
+		}
+		job, err := jobs.Get(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &job)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(encodeJob),
+	},
+	Type: oldcmds.Job{},
+}
+
+var jobsCancelCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Cancel a running background job.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg(jobIDArgName, true, false, "ID of the job."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		jobs, err := env.(*oldcmds.Context).GetJobs()
+		if err != nil {
+			return err
+		}
+		return jobs.Cancel(req.Arguments[0])
+	},
+}
+
+var jobsWaitCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Wait for a background job to finish.",
+		ShortDescription: `
+Waits for a background job to finish, and shows its status like
+'ipfs jobs status'. The command fails if the job did not succeed.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg(jobIDArgName, true, false, "ID of the job."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		jobs, err := env.(*oldcmds.Context).GetJobs()
+		if err != nil {
+			return err
+		}
+		job, err := jobs.Wait(req.Context, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		if err := res.Emit(&job); err != nil {
+			return err
+		}
+		if job.Status != oldcmds.JobSucceeded {
+			return fmt.Errorf("job %s %s", job.ID, job.Status)
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(encodeJob),
+	},
+	Type: oldcmds.Job{},
+}
+
+// AsyncCommand returns a copy of cmd for the requests made with its async
+// option: they output the background job running the command, instead of the
+// output of the command.
+func AsyncCommand(cmd *cmds.Command) *cmds.Command {
+	async := *cmd
+	async.Type = oldcmds.Job{}
+	async.PostRun = nil
+	async.Encoders = cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, job *oldcmds.Job) error {
+			_, err := fmt.Fprintln(w, job.ID)
+			return err
+		}),
+	}
+	return &async
+}
+
+func encodeJob(req *cmds.Request, w io.Writer, job *oldcmds.Job) error {
+	tw := tabwriter.NewWriter(w, 4, 4, 1, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", job.ID)
+	fmt.Fprintf(tw, "Command:\t%s\n", jobCommandLine(job))
+	fmt.Fprintf(tw, "Status:\t%s\n", job.Status)
+	fmt.Fprintf(tw, "StartTime:\t%s\n", job.StartTime.Format(time.RFC3339))
+	if job.Status != oldcmds.JobRunning {
+		fmt.Fprintf(tw, "EndTime:\t%s\n", job.EndTime.Format(time.RFC3339))
+	}
+	fmt.Fprintf(tw, "RunTime:\t%s\n", jobRunTime(job))
+	if job.Error != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", job.Error)
+	}
+	if len(job.Output) > 0 {
+		fmt.Fprintf(tw, "Output:\t%s\n", job.Output[len(job.Output)-1])
+	}
+	return tw.Flush()
+}
+
+func jobCommandLine(job *oldcmds.Job) string {
+	return strings.Join(append([]string{strings.ReplaceAll(job.Command, "/", " ")}, job.Args...), " ")
+}
+
+func jobRunTime(job *oldcmds.Job) time.Duration {
+	if job.Status == oldcmds.JobRunning {
+		return time.Since(job.StartTime).Truncate(time.Millisecond)
+	}
+	return job.EndTime.Sub(job.StartTime).Truncate(time.Millisecond)
+}
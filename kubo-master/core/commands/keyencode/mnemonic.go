@@ -0,0 +1,67 @@
+package keyencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// SeedSize is the size of the Ed25519 seeds encoded by mnemonics, which are
+// therefore made of 24 words of the BIP39 English wordlist.
+const SeedSize = 32
+
+// ErrMnemonicKeyType is returned when encoding a key which is not Ed25519 as a
+// mnemonic.
+var ErrMnemonicKeyType = errors.New("only Ed25519 keys can be encoded as a mnemonic")
+
+// MnemonicFromSeed returns the mnemonic encoding seed.
+func MnemonicFromSeed(seed []byte) (string, error) {
+	if len(seed) != SeedSize {
+		return "", fmt.Errorf("seed must be %d bytes long, got %d", SeedSize, len(seed))
+	}
+	return bip39.NewMnemonic(seed)
+}
+
+// SeedFromMnemonic returns the seed encoded by mnemonic, after checking its
+// checksum. Words may be separated by any whitespace, and are case
+// insensitive.
+func SeedFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) != SeedSize*3/4 {
+		return nil, fmt.Errorf("invalid mnemonic: expected %d words, got %d", SeedSize*3/4, len(words))
+	}
+	seed, err := bip39.EntropyFromMnemonic(strings.Join(words, " "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %w", err)
+	}
+	return seed, nil
+}
+
+// MnemonicFromPrivateKey returns the mnemonic encoding the seed of the Ed25519
+// key sk.
+func MnemonicFromPrivateKey(sk crypto.PrivKey) (string, error) {
+	if _, ok := sk.(*crypto.Ed25519PrivateKey); !ok {
+		return "", ErrMnemonicKeyType
+	}
+	raw, err := sk.Raw()
+	if err != nil {
+		return "", err
+	}
+	// the raw key is the seed followed by the public key
+	return MnemonicFromSeed(raw[:SeedSize])
+}
+
+// PrivateKeyFromMnemonic returns the Ed25519 key whose seed is encoded by
+// mnemonic.
+func PrivateKeyFromMnemonic(mnemonic string) (crypto.PrivKey, error) {
+	seed, err := SeedFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	sk, _, err := crypto.GenerateEd25519Key(bytes.NewReader(seed))
+	return sk, err
+}
@@ -0,0 +1,63 @@
+package keyencode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMnemonicVectors(t *testing.T) {
+	// from the BIP39 test vectors
+	for seed, mnemonic := range map[byte]string{
+		0x00: strings.Repeat("abandon ", 23) + "art",
+		0x7f: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title",
+		0xff: strings.Repeat("zoo ", 23) + "vote",
+	} {
+		s := bytes.Repeat([]byte{seed}, SeedSize)
+		m, err := MnemonicFromSeed(s)
+		require.NoError(t, err)
+		require.Equal(t, mnemonic, m)
+
+		decoded, err := SeedFromMnemonic(mnemonic)
+		require.NoError(t, err)
+		require.Equal(t, s, decoded)
+	}
+}
+
+func TestMnemonicPrivateKey(t *testing.T) {
+	sk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+
+	mnemonic, err := MnemonicFromPrivateKey(sk)
+	require.NoError(t, err)
+	require.Len(t, strings.Fields(mnemonic), 24)
+
+	// whitespace and case do not matter
+	decoded, err := PrivateKeyFromMnemonic(" " + strings.ToUpper(strings.ReplaceAll(mnemonic, " ", "\n")) + "\n")
+	require.NoError(t, err)
+	require.True(t, sk.Equals(decoded))
+
+	rsa, _, err := crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	require.NoError(t, err)
+	_, err = MnemonicFromPrivateKey(rsa)
+	require.ErrorIs(t, err, ErrMnemonicKeyType)
+}
+
+func TestInvalidMnemonic(t *testing.T) {
+	words := strings.Fields(strings.Repeat("abandon ", 23) + "art")
+
+	_, err := SeedFromMnemonic(strings.Join(words[:12], " "))
+	require.ErrorContains(t, err, "expected 24 words")
+
+	// wrong checksum
+	_, err = SeedFromMnemonic(strings.Repeat("abandon ", 24))
+	require.Error(t, err)
+
+	words[3] = "notaword"
+	_, err = SeedFromMnemonic(strings.Join(words, " "))
+	require.Error(t, err)
+}
@@ -18,9 +18,12 @@ import (
 	oldcmds "github.com/ipfs/kubo/commands"
 	config "github.com/ipfs/kubo/config"
 	cmdenv "github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/core/commands/cmdutils"
 	"github.com/ipfs/kubo/core/commands/e"
 	ke "github.com/ipfs/kubo/core/commands/keyencode"
 	options "github.com/ipfs/kubo/core/coreiface/options"
+	enckeystore "github.com/ipfs/kubo/keystore/encrypted"
+	"github.com/ipfs/kubo/repo"
 	fsrepo "github.com/ipfs/kubo/repo/fsrepo"
 	migrations "github.com/ipfs/kubo/repo/fsrepo/migrations"
 	"github.com/libp2p/go-libp2p/core/crypto"
@@ -46,15 +49,17 @@ publish'.
 		`,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"gen":    keyGenCmd,
-		"export": keyExportCmd,
-		"import": keyImportCmd,
-		"list":   keyListCmd,
-		"rename": keyRenameCmd,
-		"rm":     keyRmCmd,
-		"rotate": keyRotateCmd,
-		"sign":   keySignCmd,
-		"verify": keyVerifyCmd,
+		"gen":           keyGenCmd,
+		"export":        keyExportCmd,
+		"import":        keyImportCmd,
+		"list":          keyListCmd,
+		"rename":        keyRenameCmd,
+		"rm":            keyRmCmd,
+		"rotate":        keyRotateCmd,
+		"encrypt-store": keyEncryptStoreCmd,
+		"decrypt-store": keyDecryptStoreCmd,
+		"sign":          keySignCmd,
+		"verify":        keyVerifyCmd,
 	},
 }
 
@@ -76,23 +81,38 @@ type KeyRenameOutput struct {
 }
 
 const (
-	keyStoreAlgorithmDefault = options.Ed25519Key
-	keyStoreTypeOptionName   = "type"
-	keyStoreSizeOptionName   = "size"
-	oldKeyOptionName         = "oldkey"
+	keyStoreAlgorithmDefault  = options.Ed25519Key
+	keyStoreTypeOptionName    = "type"
+	keyStoreSizeOptionName    = "size"
+	keyFromMnemonicOptionName = "from-mnemonic"
+	oldKeyOptionName          = "oldkey"
 )
 
 var keyGenCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Create a new keypair",
+		ShortDescription: `
+Generates a new keypair and stores it under the provided name.
+
+With '--from-mnemonic', the Ed25519 key is derived from a 24 words BIP39
+mnemonic instead of being random, such as one written by
+'ipfs key export --format=mnemonic'. The mnemonic is read from the file given
+as second argument, or from stdin with '-'. Generating a key from the same
+mnemonic always gives the same key, which allows to recover an IPNS name from
+a paper backup:
+
+  $ ipfs key gen mysite --from-mnemonic mysite.mnemonic
+`,
 	},
 	Options: []cmds.Option{
 		cmds.StringOption(keyStoreTypeOptionName, "t", "type of the key to create: rsa, ed25519").WithDefault(keyStoreAlgorithmDefault),
 		cmds.IntOption(keyStoreSizeOptionName, "s", "size of the key to generate"),
+		cmds.BoolOption(keyFromMnemonicOptionName, "Derive the Ed25519 key from the 24 words BIP39 mnemonic given as argument."),
 		ke.OptionIPNSBase,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("name", true, false, "name of key to create"),
+		cmds.FileArg("mnemonic", false, false, "file holding the mnemonic, with --from-mnemonic"),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		api, err := cmdenv.GetApi(env, req)
@@ -116,6 +136,30 @@ var keyGenCmd = &cmds.Command{
 		if sizefound {
 			opts = append(opts, options.Key.Size(size))
 		}
+		if fromMnemonic, _ := req.Options[keyFromMnemonicOptionName].(bool); fromMnemonic {
+			if typ != options.Ed25519Key {
+				return fmt.Errorf("--%s can only be used with --%s=%s", keyFromMnemonicOptionName, keyStoreTypeOptionName, options.Ed25519Key)
+			}
+			if req.Files == nil {
+				return fmt.Errorf("--%s requires the file holding the mnemonic as argument", keyFromMnemonicOptionName)
+			}
+			file, err := cmdenv.GetFileArg(req.Files.Entries())
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			mnemonic, err := io.ReadAll(file)
+			if err != nil {
+				return err
+			}
+			seed, err := ke.SeedFromMnemonic(string(mnemonic))
+			if err != nil {
+				return err
+			}
+			opts = append(opts, options.Key.Seed(seed))
+		} else if req.Files != nil {
+			return fmt.Errorf("the mnemonic argument requires --%s", keyFromMnemonicOptionName)
+		}
 		keyEnc, err := ke.KeyEncoderFromString(req.Options[ke.OptionIPNSBase.Name()].(string))
 		if err != nil {
 			return err
@@ -145,7 +189,10 @@ const (
 	keyFormatOptionName            = "format"
 	keyFormatPemCleartextOption    = "pem-pkcs8-cleartext"
 	keyFormatLibp2pCleartextOption = "libp2p-protobuf-cleartext"
+	keyFormatLibp2pEncryptedOption = "libp2p-protobuf-encrypted"
+	keyFormatMnemonicOption        = "mnemonic"
 	keyAllowAnyTypeOptionName      = "allow-any-key-type"
+	keyPassphraseFileOptionName    = "passphrase-file"
 )
 
 var keyExportCmd = &cmds.Command{
@@ -163,6 +210,21 @@ elsewhere. For example, using openssl to get a PEM with public key:
 
   $ ipfs key export testkey --format=pem-pkcs8-cleartext -o privkey.pem
   $ openssl pkey -in privkey.pem -pubout > pubkey.pem
+
+To protect the exported key with a passphrase, pass
+'--format=libp2p-protobuf-encrypted'. The passphrase is read from the file
+given with '--passphrase-file', or from the terminal. Such a key can only be
+imported back with 'ipfs key import --format=libp2p-protobuf-encrypted'.
+
+Ed25519 keys can be exported as a 24 words BIP39 mnemonic with
+'--format=mnemonic', to be written down on paper. The key can be recovered
+with 'ipfs key import --format=mnemonic' or 'ipfs key gen --from-mnemonic'.
+Anyone reading the mnemonic can use the key: store it as carefully as the key
+itself.
+
+When the keystore is encrypted (see 'ipfs key encrypt-store'), its passphrase
+is read from the IPFS_KEYSTORE_PASSPHRASE or IPFS_KEYSTORE_PASSPHRASE_FILE
+environment variables, or from the terminal.
 `,
 	},
 	Arguments: []cmds.Argument{
@@ -170,7 +232,8 @@ elsewhere. For example, using openssl to get a PEM with public key:
 	},
 	Options: []cmds.Option{
 		cmds.StringOption(outputOptionName, "o", "The path where the output should be stored."),
-		cmds.StringOption(keyFormatOptionName, "f", "The format of the exported private key, libp2p-protobuf-cleartext or pem-pkcs8-cleartext.").WithDefault(keyFormatLibp2pCleartextOption),
+		cmds.StringOption(keyFormatOptionName, "f", "The format of the exported private key, libp2p-protobuf-cleartext, libp2p-protobuf-encrypted, pem-pkcs8-cleartext or mnemonic.").WithDefault(keyFormatLibp2pCleartextOption),
+		cmds.StringOption(keyPassphraseFileOptionName, "Path to a file containing the passphrase protecting the exported key, with format=libp2p-protobuf-encrypted."),
 	},
 	NoRemote: true,
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
@@ -197,7 +260,7 @@ elsewhere. For example, using openssl to get a PEM with public key:
 		// Export is read-only: safe to read it without acquiring repo lock
 		// (this makes export work when ipfs daemon is already running)
 		ksp := filepath.Join(cfgRoot, "keystore")
-		ks, err := keystore.NewFSKeystore(ksp)
+		ks, err := openKeystore(ksp)
 		if err != nil {
 			return err
 		}
@@ -233,6 +296,26 @@ elsewhere. For example, using openssl to get a PEM with public key:
 			if err != nil {
 				return err
 			}
+		case keyFormatLibp2pEncryptedOption:
+			passFile, _ := req.Options[keyPassphraseFileOptionName].(string)
+			pass, err := cmdutils.ReadPassphrase(passFile, "Enter passphrase for the exported key: ", true)
+			if err != nil {
+				return err
+			}
+			b, err := crypto.MarshalPrivateKey(sk)
+			if err != nil {
+				return err
+			}
+			formattedKey, err = enckeystore.Seal(pass, b)
+			if err != nil {
+				return err
+			}
+		case keyFormatMnemonicOption:
+			mnemonic, err := ke.MnemonicFromPrivateKey(sk)
+			if err != nil {
+				return err
+			}
+			formattedKey = []byte(mnemonic + "\n")
 		default:
 			return fmt.Errorf("unrecognized export format: %s", exportFormat)
 		}
@@ -262,6 +345,10 @@ elsewhere. For example, using openssl to get a PEM with public key:
 					fileExtension = "pem"
 				case keyFormatLibp2pCleartextOption:
 					fileExtension = "key"
+				case keyFormatLibp2pEncryptedOption:
+					fileExtension = "key.enc"
+				case keyFormatMnemonicOption:
+					fileExtension = "mnemonic"
 				}
 				trimmed := strings.TrimRight(fmt.Sprintf("%s.%s", req.Arguments[0], fileExtension), "/")
 				_, outPath = filepath.Split(trimmed)
@@ -290,7 +377,7 @@ elsewhere. For example, using openssl to get a PEM with public key:
 					return fmt.Errorf("encoding PEM block: %w", err)
 				}
 
-			case keyFormatLibp2pCleartextOption:
+			case keyFormatLibp2pCleartextOption, keyFormatLibp2pEncryptedOption, keyFormatMnemonicOption:
 				_, err = io.Copy(file, outReader)
 				if err != nil {
 					return err
@@ -316,12 +403,22 @@ The PEM format allows for key generation outside of the IPFS node:
 
   $ openssl genpkey -algorithm ED25519 > ed25519.pem
   $ ipfs key import test-openssl -f pem-pkcs8-cleartext ed25519.pem
+
+Keys exported with '--format=libp2p-protobuf-encrypted' are imported with the
+same format, and their passphrase is read from the file given with
+'--passphrase-file', or from the terminal.
+
+Ed25519 keys exported with '--format=mnemonic' are imported from a file
+holding their 24 words with the same format:
+
+  $ ipfs key import mysite --format=mnemonic mysite.mnemonic
 `,
 	},
 	Options: []cmds.Option{
 		ke.OptionIPNSBase,
-		cmds.StringOption(keyFormatOptionName, "f", "The format of the private key to import, libp2p-protobuf-cleartext or pem-pkcs8-cleartext.").WithDefault(keyFormatLibp2pCleartextOption),
+		cmds.StringOption(keyFormatOptionName, "f", "The format of the private key to import, libp2p-protobuf-cleartext, libp2p-protobuf-encrypted, pem-pkcs8-cleartext or mnemonic.").WithDefault(keyFormatLibp2pCleartextOption),
 		cmds.BoolOption(keyAllowAnyTypeOptionName, "Allow importing any key type.").WithDefault(false),
+		cmds.StringOption(keyPassphraseFileOptionName, "Path to a file containing the passphrase protecting the key, with format=libp2p-protobuf-encrypted."),
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("name", true, false, "name to associate with key in keychain"),
@@ -388,6 +485,25 @@ The PEM format allows for key generation outside of the IPFS node:
 				}
 				return fmt.Errorf("unable to unmarshall format=%s: %w", keyFormatLibp2pCleartextOption, err)
 			}
+		case keyFormatLibp2pEncryptedOption:
+			passFile, _ := req.Options[keyPassphraseFileOptionName].(string)
+			pass, err := cmdutils.ReadPassphrase(passFile, "Enter passphrase for the imported key: ", false)
+			if err != nil {
+				return err
+			}
+			b, err := enckeystore.Unseal(pass, data)
+			if err != nil {
+				return fmt.Errorf("decrypting key: %w", err)
+			}
+			sk, err = crypto.UnmarshalPrivateKey(b)
+			if err != nil {
+				return fmt.Errorf("unable to unmarshall format=%s: %w", keyFormatLibp2pEncryptedOption, err)
+			}
+		case keyFormatMnemonicOption:
+			sk, err = ke.PrivateKeyFromMnemonic(string(data))
+			if err != nil {
+				return err
+			}
 
 		default:
 			return fmt.Errorf("unrecognized import format: %s", importFormat)
@@ -417,6 +533,9 @@ The PEM format allows for key generation outside of the IPFS node:
 			return err
 		}
 		defer r.Close()
+		if err := cmdutils.UnlockKeystore(r, ""); err != nil {
+			return err
+		}
 
 		_, err = r.Keystore().Get(name)
 		if err == nil {
@@ -632,6 +751,9 @@ func doRotate(out io.Writer, repoRoot string, oldKey string, algorithm string, n
 		return fmt.Errorf("opening repo (%v)", err)
 	}
 	defer repo.Close()
+	if err := cmdutils.UnlockKeystore(repo, ""); err != nil {
+		return err
+	}
 
 	// Read config file from repo
 	cfg, err := repo.Config()
@@ -675,6 +797,118 @@ func doRotate(out io.Writer, repoRoot string, oldKey string, algorithm string, n
 	return nil
 }
 
+// openKeystore opens the keystore in ksp, unlocking it if it is encrypted.
+func openKeystore(ksp string) (keystore.Keystore, error) {
+	encrypted, err := enckeystore.IsEncrypted(ksp)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return keystore.NewFSKeystore(ksp)
+	}
+	ks, err := enckeystore.Open(ksp)
+	if err != nil {
+		return nil, err
+	}
+	pass, err := cmdutils.KeystorePassphrase("", false)
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.Unlock(pass); err != nil {
+		return nil, fmt.Errorf("unlocking keystore: %w", err)
+	}
+	return ks, nil
+}
+
+var keyEncryptStoreCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Encrypt the keystore with a passphrase.",
+		ShortDescription: `
+Encrypts the keys of the keystore, and moves the identity of the node from
+Identity.PrivKey in the config file to the encrypted keystore.
+
+The passphrase is read from the file given with '--passphrase-file', from the
+IPFS_KEYSTORE_PASSPHRASE or IPFS_KEYSTORE_PASSPHRASE_FILE environment
+variables, or from the terminal. The same sources are used to unlock the
+keystore afterwards, and 'ipfs daemon' also accepts
+'--keystore-passphrase-file'.
+
+The keys are encrypted with XChaCha20-Poly1305, under a key derived from the
+passphrase with scrypt. Use 'ipfs key decrypt-store' to store them in
+plaintext again.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(keyPassphraseFileOptionName, "Path to a file containing the passphrase."),
+	},
+	NoRemote: true,
+	PreRun:   DaemonNotRunning,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		cctx := env.(*oldcmds.Context)
+		r, err := fsrepo.Open(cctx.ConfigRoot)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		if _, ok := r.Keystore().(*enckeystore.Keystore); ok {
+			return errors.New("keystore is already encrypted")
+		}
+		passFile, _ := req.Options[keyPassphraseFileOptionName].(string)
+		pass, err := cmdutils.KeystorePassphrase(passFile, true)
+		if err != nil {
+			return err
+		}
+		ke, ok := r.(repo.KeystoreEncrypter)
+		if !ok {
+			return repo.ErrKeystoreEncryptionNotSupported
+		}
+		return ke.EncryptKeystore(pass)
+	},
+}
+
+var keyDecryptStoreCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Store the keys of an encrypted keystore in plaintext again.",
+		ShortDescription: `
+Decrypts the keys of a keystore encrypted with 'ipfs key encrypt-store', and
+moves the identity of the node back to Identity.PrivKey in the config file.
+
+The passphrase is read from the file given with '--passphrase-file', from the
+IPFS_KEYSTORE_PASSPHRASE or IPFS_KEYSTORE_PASSPHRASE_FILE environment
+variables, or from the terminal.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(keyPassphraseFileOptionName, "Path to a file containing the passphrase."),
+	},
+	NoRemote: true,
+	PreRun:   DaemonNotRunning,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		cctx := env.(*oldcmds.Context)
+		r, err := fsrepo.Open(cctx.ConfigRoot)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		if _, ok := r.Keystore().(*enckeystore.Keystore); !ok {
+			return enckeystore.ErrNotEncrypted
+		}
+		passFile, _ := req.Options[keyPassphraseFileOptionName].(string)
+		if err := cmdutils.UnlockKeystore(r, passFile); err != nil {
+			return err
+		}
+		ke, ok := r.(repo.KeystoreEncrypter)
+		if !ok {
+			return repo.ErrKeystoreEncryptionNotSupported
+		}
+		return ke.DecryptKeystore()
+	},
+}
+
 func keyOutputListEncoders() cmds.EncoderFunc {
 	return cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, list *KeyOutputList) error {
 		withID, _ := req.Options["l"].(bool)
@@ -0,0 +1,248 @@
+package name
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/namesys"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-datastore"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdenv "github.com/ipfs/kubo/core/commands/cmdenv"
+	iface "github.com/ipfs/kubo/core/coreiface"
+	options "github.com/ipfs/kubo/core/coreiface/options"
+	"github.com/ipfs/kubo/namesys/history"
+)
+
+const toOptionName = "to"
+
+// IpnsHistory is the output type of 'name history'.
+type IpnsHistory struct {
+	Name    string
+	Entries []history.Entry
+}
+
+// IpnsRollback is the output type of 'name rollback'.
+type IpnsRollback struct {
+	Name     string
+	Value    string
+	Sequence uint64
+}
+
+var IpnsHistoryCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the IPNS records published by this node for a name.",
+		ShortDescription: `
+Lists the values published with 'ipfs name publish' for the given key, oldest
+first, with their sequence number and the time they were published.
+`,
+		LongDescription: `
+Lists the values published with 'ipfs name publish' for the given key, oldest
+first, with their sequence number and the time they were published. Records
+published by other nodes, and republished records, are not listed.
+
+The key is the name of a key, as listed by 'ipfs key list', or the IPNS name
+itself. Use 'ipfs name rollback' to publish an earlier value again.
+
+Example:
+
+  > ipfs name history self
+  0  2024-01-02T15:04:05Z  /ipfs/<first-cid>
+  1  2024-01-03T10:20:30Z  /ipfs/<second-cid>
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg(keyOptionName, true, false, "Name of the key, or IPNS name, to list the history of."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		name, err := keyIpnsName(req.Context, api, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		entries, err := history.List(req.Context, nd.Repo.Datastore(), name)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &IpnsHistory{Name: name.String(), Entries: entries})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, h *IpnsHistory) error {
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			for _, e := range h.Entries {
+				fmt.Fprintf(tw, "%d\t%s\t%s\n", e.Sequence, e.Published.Format(time.RFC3339), cmdenv.EscNonPrint(e.Value))
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: IpnsHistory{},
+}
+
+var IpnsRollbackCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Publish an earlier value of an IPNS name again.",
+		ShortDescription: `
+Publishes the value of the record with the given sequence number, as listed by
+'ipfs name history', in a new record with a newer sequence number.
+`,
+		LongDescription: `
+Publishes the value of the record with the given sequence number, as listed by
+'ipfs name history', in a new record with a newer sequence number, so that it
+replaces the current value everywhere.
+
+The record is published for --lifetime, with the TTL of the original record
+unless --ttl is given.
+
+Example:
+
+  > ipfs name rollback self --to 0
+  Rolled back <ipns-name> to /ipfs/<first-cid> (sequence 2)
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg(keyOptionName, true, false, "Name of the key, or IPNS name, to roll back."),
+	},
+	Options: []cmds.Option{
+		cmds.Uint64Option(toOptionName, "Sequence number of the record to publish again."),
+		cmds.StringOption(lifeTimeOptionName, "t", `Time duration the signed record will be valid for. Accepts durations such as "300s", "1.5h" or "7d2h45m"`).WithDefault(ipns.DefaultRecordLifetime.String()),
+		cmds.StringOption(ttlOptionName, "Time duration hint indicating how long to cache this record before checking for updates. Defaults to the TTL of the original record."),
+		cmds.BoolOption(v1compatOptionName, "Produce a backward-compatible IPNS Record by including fields for both V1 and V2 signatures.").WithDefault(true),
+		cmds.BoolOption(allowOfflineOptionName, "When --offline, save the IPNS record to the local datastore without broadcasting to the network (instead of failing)."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		seq, ok := req.Options[toOptionName].(uint64)
+		if !ok {
+			return fmt.Errorf("the sequence number to roll back to must be given with --%s", toOptionName)
+		}
+		validTimeOpt, _ := req.Options[lifeTimeOptionName].(string)
+		validTime, err := time.ParseDuration(validTimeOpt)
+		if err != nil {
+			return fmt.Errorf("error parsing lifetime option: %s", err)
+		}
+
+		kname := req.Arguments[0]
+		name, err := keyIpnsName(req.Context, api, kname)
+		if err != nil {
+			return err
+		}
+		ds := nd.Repo.Datastore()
+		entry, err := history.Find(req.Context, ds, name, seq)
+		if err != nil {
+			return err
+		}
+		p, err := path.NewPath(entry.Value)
+		if err != nil {
+			return err
+		}
+
+		// A record with the current value would not get a newer sequence
+		// number.
+		_, current, err := publishedRecord(req.Context, ds, name)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			if v, err := current.Value(); err == nil && v.String() == entry.Value {
+				return fmt.Errorf("%s already points to %s", name, entry.Value)
+			}
+		}
+
+		allowOffline, _ := req.Options[allowOfflineOptionName].(bool)
+		compatibleWithV1, _ := req.Options[v1compatOptionName].(bool)
+		ttl := entry.TTL
+		if ttlOpt, found := req.Options[ttlOptionName].(string); found {
+			if ttl, err = time.ParseDuration(ttlOpt); err != nil {
+				return err
+			}
+		}
+
+		_, err = api.Name().Publish(req.Context, p,
+			options.Name.AllowOffline(allowOffline),
+			options.Name.Key(kname),
+			options.Name.ValidTime(validTime),
+			options.Name.TTL(ttl),
+			options.Name.CompatibleWithV1(compatibleWithV1),
+		)
+		if err != nil {
+			if err == iface.ErrOffline {
+				err = errAllowOffline
+			}
+			return err
+		}
+
+		out := &IpnsRollback{Name: name.String(), Value: entry.Value}
+		entries, err := history.List(req.Context, ds, name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			out.Sequence = entries[len(entries)-1].Sequence
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *IpnsRollback) error {
+			_, err := fmt.Fprintf(w, "Rolled back %s to %s (sequence %d)\n", cmdenv.EscNonPrint(out.Name), cmdenv.EscNonPrint(out.Value), out.Sequence)
+			return err
+		}),
+	},
+	Type: IpnsRollback{},
+}
+
+// keyIpnsName returns the IPNS name of the key with the given name or ID.
+// IPNS names of keys that are not in the keystore are accepted too.
+func keyIpnsName(ctx context.Context, api iface.CoreAPI, k string) (ipns.Name, error) {
+	keys, err := api.Key().List(ctx)
+	if err != nil {
+		return ipns.Name{}, err
+	}
+	for _, key := range keys {
+		if key.Name() == k || key.ID().String() == k {
+			return ipns.NameFromPeer(key.ID()), nil
+		}
+	}
+	name, err := ipns.NameFromString(k)
+	if err != nil {
+		return ipns.Name{}, fmt.Errorf("no key named %q", k)
+	}
+	return name, nil
+}
+
+// publishedRecord returns the record last published by this node for name,
+// or nil if there is none.
+func publishedRecord(ctx context.Context, ds datastore.Datastore, name ipns.Name) ([]byte, *ipns.Record, error) {
+	data, err := ds.Get(ctx, namesys.IpnsDsKey(name))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	rec, err := ipns.UnmarshalRecord(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, rec, nil
+}
@@ -70,10 +70,14 @@ Resolve the value of a dnslink:
 	},
 
 	Subcommands: map[string]*cmds.Command{
-		"publish": PublishCmd,
-		"resolve": IpnsCmd,
-		"pubsub":  IpnsPubsubCmd,
-		"inspect": IpnsInspectCmd,
+		"publish":        PublishCmd,
+		"resolve":        IpnsCmd,
+		"pubsub":         IpnsPubsubCmd,
+		"inspect":        IpnsInspectCmd,
+		"history":        IpnsHistoryCmd,
+		"rollback":       IpnsRollbackCmd,
+		"record":         IpnsRecordCmd,
+		"publish-policy": IpnsPublishPolicyCmd,
 	},
 }
 
@@ -93,6 +97,34 @@ type IpnsInspectEntry struct {
 	TTL          *time.Duration
 }
 
+// inspectEntry returns the values of rec that could be decoded.
+func inspectEntry(rec *ipns.Record) IpnsInspectEntry {
+	var entry IpnsInspectEntry
+
+	// Best effort to get the fields. Show everything we can.
+	if v, err := rec.Value(); err == nil {
+		entry.Value = v.String()
+	}
+
+	if v, err := rec.ValidityType(); err == nil {
+		entry.ValidityType = &v
+	}
+
+	if v, err := rec.Validity(); err == nil {
+		entry.Validity = &v
+	}
+
+	if v, err := rec.Sequence(); err == nil {
+		entry.Sequence = &v
+	}
+
+	if v, err := rec.TTL(); err == nil {
+		entry.TTL = &v
+	}
+
+	return entry
+}
+
 type IpnsInspectResult struct {
 	Entry         IpnsInspectEntry
 	PbSize        int
@@ -151,28 +183,7 @@ Passing --verify will verify signature against provided public key.
 		}
 
 		result := &IpnsInspectResult{
-			Entry: IpnsInspectEntry{},
-		}
-
-		// Best effort to get the fields. Show everything we can.
-		if v, err := rec.Value(); err == nil {
-			result.Entry.Value = v.String()
-		}
-
-		if v, err := rec.ValidityType(); err == nil {
-			result.Entry.ValidityType = &v
-		}
-
-		if v, err := rec.Validity(); err == nil {
-			result.Entry.Validity = &v
-		}
-
-		if v, err := rec.Sequence(); err == nil {
-			result.Entry.Sequence = &v
-		}
-
-		if v, err := rec.TTL(); err == nil {
-			result.Entry.TTL = &v
+			Entry: inspectEntry(rec),
 		}
 
 		// Here we need the raw protobuf just to decide the version.
@@ -0,0 +1,111 @@
+package name
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdenv "github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/namesys/publishpolicy"
+)
+
+// IpnsPublishPolicy describes the publish policy of a key.
+type IpnsPublishPolicy struct {
+	Key string
+	publishpolicy.State
+}
+
+// IpnsPublishPolicyList is the output type of 'name publish-policy ls'.
+type IpnsPublishPolicyList struct {
+	Policies []IpnsPublishPolicy
+}
+
+var IpnsPublishPolicyCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect the policies keeping IPNS names pointing to MFS paths.",
+		ShortDescription: `
+Publish policies are configured in Ipns.PublishPolicies. While the daemon is
+running, each policy republishes its key when its MFS path changes, once the
+path has been left unchanged for Debounce, and at most every MinInterval.
+`,
+		LongDescription: `
+Publish policies are configured in Ipns.PublishPolicies. While the daemon is
+running, each policy republishes its key when its MFS path changes, once the
+path has been left unchanged for Debounce, and at most every MinInterval.
+
+Example:
+
+  > ipfs key gen site
+  > ipfs config --json Ipns.PublishPolicies '{"site": {"MFS": "/site"}}'
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls": ipnsPublishPolicyLsCmd,
+	},
+}
+
+var ipnsPublishPolicyLsCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "List the publish policies and their state.",
+		ShortDescription: `
+Lists the configured publish policies, with the value they last published, the
+change of their MFS path waiting to be published, and the last error.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		cfg, err := nd.Repo.Config()
+		if err != nil {
+			return err
+		}
+
+		out := &IpnsPublishPolicyList{Policies: []IpnsPublishPolicy{}}
+		for key, policy := range cfg.Ipns.PublishPolicies {
+			st, err := publishpolicy.Load(req.Context, nd.Repo.Datastore(), key)
+			if err != nil {
+				return err
+			}
+			if st.MFS != policy.MFS {
+				// The state of a previous MFS path is not relevant.
+				st = publishpolicy.State{MFS: policy.MFS, Value: st.Value, Published: st.Published}
+			}
+			out.Policies = append(out.Policies, IpnsPublishPolicy{Key: key, State: st})
+		}
+		slices.SortFunc(out.Policies, func(a, b IpnsPublishPolicy) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *IpnsPublishPolicyList) error {
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			for _, p := range out.Policies {
+				var status []string
+				if p.Value != "" {
+					status = append(status, fmt.Sprintf("published %s at %s", p.Value, p.Published.Format(time.RFC3339)))
+				}
+				if p.Pending != "" {
+					status = append(status, fmt.Sprintf("pending %s since %s", p.Pending, p.PendingSince.Format(time.RFC3339)))
+				}
+				if p.Error != "" {
+					status = append(status, "error: "+p.Error)
+				}
+				if len(status) == 0 {
+					status = append(status, "not published yet")
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Key, cmdenv.EscNonPrint(p.MFS), cmdenv.EscNonPrint(strings.Join(status, ", ")))
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: IpnsPublishPolicyList{},
+}
@@ -0,0 +1,357 @@
+package name
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/namesys"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/kubo/core"
+	cmdenv "github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/core/commands/cmdutils"
+	iface "github.com/ipfs/kubo/core/coreiface"
+	options "github.com/ipfs/kubo/core/coreiface/options"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	nameOptionName     = "name"
+	putOptionName      = "put"
+	sequenceOptionName = "sequence"
+)
+
+// IpnsRecordImport is the output type of 'name record import'.
+type IpnsRecordImport struct {
+	Name  string
+	Entry IpnsInspectEntry
+	Put   bool
+}
+
+var IpnsRecordCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Move signed IPNS records between nodes.",
+		ShortDescription: `
+'ipfs name record' exports and imports signed IPNS records, and signs records
+without publishing them, so that they can be published by a node that does not
+have the private key.
+`,
+		LongDescription: `
+'ipfs name record' exports and imports signed IPNS records, and signs records
+without publishing them, so that they can be published by a node that does not
+have the private key.
+
+Records are read and written in their Protobuf serialization, as returned by
+'ipfs routing get', and can be decoded with 'ipfs name inspect'.
+
+Example, publishing from a node that does not have the key:
+
+  (on the machine with the key)
+  > ipfs name record sign --key=mykey /ipfs/<cid> > record.bin
+
+  (on the publishing node)
+  > ipfs name record import --put --name=<ipns-name> record.bin
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"export": ipnsRecordExportCmd,
+		"import": ipnsRecordImportCmd,
+		"sign":   ipnsRecordSignCmd,
+	},
+}
+
+var ipnsRecordExportCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Write the signed IPNS record of a name to stdout.",
+		ShortDescription: `
+Writes the IPNS record last published or imported by this node for the given
+key or IPNS name. If there is none, the record is looked up in the routing
+system.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg(keyOptionName, true, false, "Name of the key, or IPNS name, to export the record of."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		name, err := keyIpnsName(req.Context, api, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		data, _, err := publishedRecord(req.Context, nd.Repo.Datastore(), name)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			if data, err = api.Routing().Get(req.Context, ipns.NamespacePrefix+name.String()); err != nil {
+				return fmt.Errorf("no record found for %s: %w", name, err)
+			}
+		}
+		return res.Emit(bytes.NewReader(data))
+	},
+}
+
+var ipnsRecordImportCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Import a signed IPNS record.",
+		ShortDescription: `
+Validates the given IPNS record and stores it as the record of its name, in
+place of the record last published or imported by this node. Records older
+than the stored record are rejected. Pass --put to also put the record to the
+routing system.
+`,
+		LongDescription: `
+Validates the given IPNS record and stores it as the record of its name, in
+place of the record last published or imported by this node. Records older
+than the stored record are rejected. Pass --put to also put the record to the
+routing system.
+
+The name is taken from the public key embedded in the record, such as in
+records signed with RSA keys. Otherwise, it must be given with --name.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("record", true, false, "The IPNS record to import.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(nameOptionName, "IPNS name of the record, if it does not embed its public key."),
+		cmds.BoolOption(putOptionName, "Put the record to the routing system."),
+		cmds.BoolOption(allowOfflineOptionName, "When --put and offline, save the record to the local datastore without broadcasting it to the network (instead of failing)."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		file, err := cmdenv.GetFileArg(req.Files.Entries())
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		rec, err := ipns.UnmarshalRecord(data)
+		if err != nil {
+			return err
+		}
+
+		name, err := recordName(rec, req.Options[nameOptionName])
+		if err != nil {
+			return err
+		}
+		if err = ipns.ValidateWithName(rec, name); err != nil {
+			return fmt.Errorf("invalid record for %s: %w", name, err)
+		}
+
+		seq, err := rec.Sequence()
+		if err != nil {
+			return err
+		}
+		ds := nd.Repo.Datastore()
+		_, stored, err := publishedRecord(req.Context, ds, name)
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			storedSeq, err := stored.Sequence()
+			if err != nil {
+				return err
+			}
+			if seq < storedSeq {
+				return fmt.Errorf("record has sequence number %d, older than the stored record of %s with sequence number %d", seq, name, storedSeq)
+			}
+		}
+
+		put, _ := req.Options[putOptionName].(bool)
+		if put {
+			allowOffline, _ := req.Options[allowOfflineOptionName].(bool)
+			err = api.Routing().Put(req.Context, ipns.NamespacePrefix+name.String(), data, options.Routing.AllowOffline(allowOffline))
+			if err != nil {
+				if err == iface.ErrOffline {
+					err = errAllowOffline
+				}
+				return err
+			}
+		}
+		if err = storeRecord(req.Context, nd, name, data); err != nil {
+			return err
+		}
+
+		return cmds.EmitOnce(res, &IpnsRecordImport{
+			Name:  name.String(),
+			Entry: inspectEntry(rec),
+			Put:   put,
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *IpnsRecordImport) error {
+			verb := "Imported"
+			if out.Put {
+				verb = "Imported and put"
+			}
+			_, err := fmt.Fprintf(w, "%s record for %s: %s\n", verb, cmdenv.EscNonPrint(out.Name), cmdenv.EscNonPrint(out.Entry.Value))
+			return err
+		}),
+	},
+	Type: IpnsRecordImport{},
+}
+
+var ipnsRecordSignCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Sign an IPNS record without publishing it.",
+		ShortDescription: `
+Writes to stdout an IPNS record for the given path, signed with a key of the
+keystore. Nothing is published nor stored: the record can be published by
+another node with 'ipfs name record import --put'.
+`,
+		LongDescription: `
+Writes to stdout an IPNS record for the given path, signed with a key of the
+keystore. Nothing is published nor stored: the record can be published by
+another node with 'ipfs name record import --put'.
+
+The sequence number defaults to the one following the record last published or
+imported by this node for the key, or to 0 if there is none. Pass --sequence
+when the latest record is held by another node.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg(ipfsPathOptionName, true, false, "ipfs path of the object the record points to.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(keyOptionName, "k", "Name of the key to be used or a valid PeerID, as listed by 'ipfs key list -l'.").WithDefault("self"),
+		cmds.Uint64Option(sequenceOptionName, "Sequence number of the record."),
+		cmds.StringOption(lifeTimeOptionName, "t", `Time duration the signed record will be valid for. Accepts durations such as "300s", "1.5h" or "7d2h45m"`).WithDefault(ipns.DefaultRecordLifetime.String()),
+		cmds.StringOption(ttlOptionName, "Time duration hint, akin to --lifetime, indicating how long to cache this record before checking for updates.").WithDefault(ipns.DefaultRecordTTL.String()),
+		cmds.BoolOption(v1compatOptionName, "Produce a backward-compatible IPNS Record by including fields for both V1 and V2 signatures.").WithDefault(true),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		validTimeOpt, _ := req.Options[lifeTimeOptionName].(string)
+		validTime, err := time.ParseDuration(validTimeOpt)
+		if err != nil {
+			return fmt.Errorf("error parsing lifetime option: %s", err)
+		}
+		ttlOpt, _ := req.Options[ttlOptionName].(string)
+		ttl, err := time.ParseDuration(ttlOpt)
+		if err != nil {
+			return fmt.Errorf("error parsing ttl option: %s", err)
+		}
+		compatibleWithV1, _ := req.Options[v1compatOptionName].(bool)
+
+		p, err := cmdutils.PathOrCidPath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		kname, _ := req.Options[keyOptionName].(string)
+		sk, err := privateKey(req.Context, nd, api, kname)
+		if err != nil {
+			return err
+		}
+
+		seq, ok := req.Options[sequenceOptionName].(uint64)
+		if !ok {
+			name, err := keyIpnsName(req.Context, api, kname)
+			if err != nil {
+				return err
+			}
+			_, stored, err := publishedRecord(req.Context, nd.Repo.Datastore(), name)
+			if err != nil {
+				return err
+			}
+			if stored != nil {
+				if seq, err = stored.Sequence(); err != nil {
+					return err
+				}
+				seq++
+			}
+		}
+
+		rec, err := ipns.NewRecord(sk, p, seq, time.Now().Add(validTime), ttl, ipns.WithV1Compatibility(compatibleWithV1))
+		if err != nil {
+			return err
+		}
+		data, err := ipns.MarshalRecord(rec)
+		if err != nil {
+			return err
+		}
+		return res.Emit(bytes.NewReader(data))
+	},
+}
+
+// recordName returns the IPNS name given with --name, or else the name of the
+// public key embedded in rec.
+func recordName(rec *ipns.Record, opt any) (ipns.Name, error) {
+	if s, ok := opt.(string); ok {
+		return ipns.NameFromString(s)
+	}
+	pk, err := rec.PubKey()
+	if err != nil {
+		return ipns.Name{}, fmt.Errorf("the record does not embed its public key, pass its IPNS name with --%s", nameOptionName)
+	}
+	pid, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		return ipns.Name{}, err
+	}
+	return ipns.NameFromPeer(pid), nil
+}
+
+// storeRecord stores data as the record of name, where the records published
+// by this node are stored.
+func storeRecord(ctx context.Context, nd *core.IpfsNode, name ipns.Name, data []byte) error {
+	ds := nd.Repo.Datastore()
+	key := namesys.IpnsDsKey(name)
+	if err := ds.Put(ctx, key, data); err != nil {
+		return err
+	}
+	return ds.Sync(ctx, key)
+}
+
+// privateKey returns the private key with the given name or ID.
+func privateKey(ctx context.Context, nd *core.IpfsNode, api iface.CoreAPI, k string) (crypto.PrivKey, error) {
+	keys, err := api.Key().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.Name() != k && key.ID().String() != k {
+			continue
+		}
+		if key.Name() == "self" {
+			return nd.PrivateKey, nil
+		}
+		return nd.Repo.Keystore().Get(key.Name())
+	}
+	return nil, fmt.Errorf("no key named %q", k)
+}
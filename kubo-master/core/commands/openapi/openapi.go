@@ -0,0 +1,514 @@
+// Package openapi generates an OpenAPI 3 document describing the RPC API
+// from the tree of its commands.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+)
+
+// Version is the version of the OpenAPI specification the documents follow.
+const Version = "3.0.3"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path. Commands are only served to POST
+// requests.
+type PathItem struct {
+	Post *Operation `json:"post,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Status is the status of the command when it is not active, such as
+	// "experimental".
+	Status string `json:"x-kubo-status,omitempty"`
+	// Encodings are the values of the enc option supported by the command.
+	Encodings []string `json:"x-kubo-encodings,omitempty"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Schema is a schema object of OpenAPI 3.0, a subset of JSON Schema.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	Parameters      map[string]*Parameter      `json:"parameters,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+// errorSchema is the name of the schema of the errors returned by commands.
+const errorSchema = "Error"
+
+var mimeTypes = map[cmds.EncodingType]string{
+	cmds.JSON:        "application/json",
+	cmds.XML:         "application/xml",
+	cmds.Protobuf:    "application/protobuf",
+	cmds.Text:        "text/plain",
+	cmds.TextNewline: "text/plain",
+}
+
+var statuses = map[cmds.Status]string{
+	cmds.Experimental: "experimental",
+	cmds.Deprecated:   "deprecated",
+	cmds.Removed:      "removed",
+}
+
+// Generate returns the document describing the commands of root, served
+// under apiPath by a node of the given version. The options of root apply to
+// all the commands, except the ones named in localOptions, which only apply
+// to the command line.
+func Generate(root *cmds.Command, apiPath, version string, localOptions ...string) *Document {
+	g := &generator{
+		doc: &Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:       "Kubo RPC API",
+				Description: "The RPC API of a Kubo node, to control it remotely. Commands are only served to POST requests, with their arguments and options in the query string.",
+				Version:     version,
+			},
+			Paths: make(map[string]*PathItem),
+			Components: Components{
+				Schemas: map[string]*Schema{
+					errorSchema: {
+						Type: "object",
+						Properties: map[string]*Schema{
+							"Message": {Type: "string"},
+							"Code":    {Type: "integer"},
+							"Type":    {Type: "string"},
+						},
+						Required: []string{"Message", "Code", "Type"},
+					},
+				},
+				Parameters: make(map[string]*Parameter),
+				SecuritySchemes: map[string]*SecurityScheme{
+					"basicAuth":  {Type: "http", Scheme: "basic", Description: "An AuthSecret of API.Authorizations, as basic:user:password."},
+					"bearerAuth": {Type: "http", Scheme: "bearer", Description: "An AuthSecret of API.Authorizations, as bearer:token, or a JWT verified with API.Authorizations.JWT."},
+				},
+			},
+			// the API only requires authorization when API.Authorizations is set
+			Security: []map[string][]string{{}, {"basicAuth": {}}, {"bearerAuth": {}}},
+		},
+		typeNames: make(map[reflect.Type]string),
+	}
+
+	var globals []*Parameter
+	for _, opt := range root.Options {
+		if slices.Contains(localOptions, opt.Name()) {
+			continue
+		}
+		g.doc.Components.Parameters[opt.Name()] = optionParameter(opt)
+		globals = append(globals, &Parameter{Ref: "#/components/parameters/" + opt.Name()})
+	}
+
+	names := make([]string, 0, len(root.Subcommands))
+	for name := range root.Subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sub := root.Subcommands[name]
+		if sub.NoRemote {
+			continue
+		}
+		g.doc.Tags = append(g.doc.Tags, Tag{Name: name, Description: sub.Helptext.Tagline})
+		g.walk(apiPath, []string{name}, sub, globals)
+	}
+	return g.doc
+}
+
+type generator struct {
+	doc       *Document
+	typeNames map[reflect.Type]string
+}
+
+// walk adds the operations of a command and its subcommands. params holds
+// the options of the parents of the command, which apply to it as well.
+func (g *generator) walk(apiPath string, cmdPath []string, cmd *cmds.Command, params []*Parameter) {
+	if cmd.NoRemote {
+		return
+	}
+	params = slices.Clip(params)
+	for _, opt := range cmd.Options {
+		params = append(params, optionParameter(opt))
+	}
+	if cmd.Run != nil {
+		g.doc.Paths[apiPath+"/"+strings.Join(cmdPath, "/")] = &PathItem{Post: g.operation(cmdPath, cmd, params)}
+	}
+
+	names := make([]string, 0, len(cmd.Subcommands))
+	for name := range cmd.Subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.walk(apiPath, append(slices.Clip(cmdPath), name), cmd.Subcommands[name], params)
+	}
+}
+
+func (g *generator) operation(cmdPath []string, cmd *cmds.Command, params []*Parameter) *Operation {
+	op := &Operation{
+		OperationID: operationID(cmdPath),
+		Summary:     cmd.Helptext.Tagline,
+		Description: strings.TrimSpace(cmd.Helptext.ShortDescription),
+		Tags:        []string{cmdPath[0]},
+		Deprecated:  cmd.Status == cmds.Deprecated || cmd.Status == cmds.Removed,
+		Status:      statuses[cmd.Status],
+		Responses: map[string]*Response{
+			"default": {
+				Description: "The command failed.",
+				Content: map[string]*MediaType{
+					"application/json": {Schema: &Schema{Ref: "#/components/schemas/" + errorSchema}},
+				},
+			},
+		},
+	}
+	if op.Description == "" {
+		op.Description = strings.TrimSpace(cmd.Helptext.LongDescription)
+	}
+
+	var (
+		args     []string
+		required bool
+	)
+	for _, arg := range cmd.Arguments {
+		switch arg.Type {
+		case cmds.ArgString:
+			desc := "`" + arg.Name + "`"
+			if arg.Variadic {
+				desc += "..."
+			}
+			if arg.Description != "" {
+				desc += ": " + arg.Description
+			}
+			args = append(args, desc)
+			required = required || arg.Required
+		case cmds.ArgFile:
+			op.RequestBody = fileRequestBody(arg)
+		}
+	}
+	if len(args) > 0 {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        "arg",
+			In:          "query",
+			Description: "The arguments of the command, in order: " + strings.Join(args, "; "),
+			Required:    required,
+			Schema:      &Schema{Type: "array", Items: &Schema{Type: "string"}},
+		})
+	}
+	op.Parameters = append(op.Parameters, params...)
+
+	encodings := []string{cmds.JSON}
+	for enc := range cmd.Encoders {
+		if enc != cmds.JSON {
+			encodings = append(encodings, string(enc))
+		}
+	}
+	sort.Strings(encodings[1:])
+	op.Encodings = encodings
+
+	ok := &Response{
+		Description: "The output of the command. Commands outputting several values send one value after the other.",
+		Content:     make(map[string]*MediaType),
+	}
+	if cmd.Type == nil {
+		ok.Content["text/plain"] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+	} else {
+		ok.Content["application/json"] = &MediaType{Schema: g.schema(reflect.TypeOf(cmd.Type))}
+		for _, enc := range encodings[1:] {
+			mime, known := mimeTypes[cmds.EncodingType(enc)]
+			if _, exists := ok.Content[mime]; known && !exists {
+				ok.Content[mime] = &MediaType{Schema: &Schema{Type: "string"}}
+			}
+		}
+	}
+	op.Responses["200"] = ok
+	return op
+}
+
+func fileRequestBody(arg cmds.Argument) *RequestBody {
+	file := &Schema{Type: "string", Format: "binary"}
+	if arg.Variadic {
+		file = &Schema{Type: "array", Items: file}
+	}
+	return &RequestBody{
+		Description: "`" + arg.Name + "`: " + arg.Description,
+		Required:    arg.Required,
+		Content: map[string]*MediaType{
+			"multipart/form-data": {Schema: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{"file": file},
+			}},
+		},
+	}
+}
+
+func optionParameter(opt cmds.Option) *Parameter {
+	var s *Schema
+	switch opt.Type() {
+	case cmds.Bool:
+		s = &Schema{Type: "boolean"}
+	case cmds.Int, cmds.Uint:
+		s = &Schema{Type: "integer"}
+	case cmds.Int64, cmds.Uint64:
+		s = &Schema{Type: "integer", Format: "int64"}
+	case cmds.Float:
+		s = &Schema{Type: "number", Format: "double"}
+	case cmds.Strings:
+		s = &Schema{Type: "array", Items: &Schema{Type: "string"}}
+	default:
+		s = &Schema{Type: "string"}
+	}
+	s.Default = opt.Default()
+	return &Parameter{
+		Name:        opt.Name(),
+		In:          "query",
+		Description: opt.Description(),
+		Schema:      s,
+	}
+}
+
+// operationID returns the ID of the operation of a command, such as
+// "pinRemoteAdd" for "pin/remote/add".
+func operationID(cmdPath []string) string {
+	var b strings.Builder
+	for i, name := range cmdPath {
+		for j, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' }) {
+			if i > 0 || j > 0 {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			}
+			b.WriteString(word)
+		}
+	}
+	return b.String()
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+	timeType      = reflect.TypeFor[time.Time]()
+	cidType       = reflect.TypeFor[cid.Cid]()
+)
+
+// schema returns the schema of the JSON encoding of values of type t. Named
+// struct types are added to the schemas of the components, and referenced.
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case cidType:
+		return &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"/": {Type: "string"}},
+			Required:   []string{"/"},
+		}
+	}
+	if marshals(t, jsonMarshaler) {
+		if marshals(t, textMarshaler) {
+			// types marshaling to text usually marshal to JSON strings
+			return &Schema{Type: "string"}
+		}
+		return &Schema{}
+	}
+	if marshals(t, textMarshaler) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		// nil slices and maps are encoded as null
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.typeNames[t]
+		if !ok {
+			name = g.schemaName(t)
+			g.typeNames[t] = name
+			// registered first, for recursive types to reference it
+			g.doc.Components.Schemas[name] = &Schema{}
+			*g.doc.Components.Schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		// interfaces, which can hold any value
+		return &Schema{}
+	}
+}
+
+func marshals(t reflect.Type, marshaler reflect.Type) bool {
+	return t.Implements(marshaler) || (t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(marshaler))
+}
+
+// structSchema returns the schema of a struct.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+// addFields adds the fields of a struct to its schema, following the rules of
+// encoding/json: the fields of embedded structs are encoded along the other
+// fields, which take precedence.
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	var embedded []reflect.Type
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, exists := s.Properties[name]; exists {
+			continue
+		}
+
+		flags := strings.Split(opts, ",")
+		if slices.Contains(flags, "string") {
+			s.Properties[name] = &Schema{Type: "string"}
+		} else {
+			s.Properties[name] = g.schema(f.Type)
+		}
+		if !slices.Contains(flags, "omitempty") && !slices.Contains(flags, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	for _, et := range embedded {
+		g.addFields(s, et)
+	}
+}
+
+var invalidSchemaChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// schemaName returns the name of the schema of a named type, qualified with
+// the name of its package.
+func (g *generator) schemaName(t reflect.Type) string {
+	base := invalidSchemaChars.ReplaceAllString(path.Base(t.PkgPath())+"."+t.Name(), "_")
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := g.doc.Components.Schemas[name]; !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
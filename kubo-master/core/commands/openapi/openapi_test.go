@@ -0,0 +1,126 @@
+package openapi
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	Name string
+}
+
+type testNode struct {
+	testBase
+	Cid      cid.Cid
+	Time     time.Time `json:",omitempty"`
+	Size     uint64    `json:",string"`
+	Links    []testNode
+	Meta     map[string]any `json:"meta,omitempty"`
+	Parent   *testNode
+	Ignored  string `json:"-"`
+	internal string
+}
+
+func TestGenerate(t *testing.T) {
+	run := func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return nil }
+	root := &cmds.Command{
+		Options: []cmds.Option{
+			cmds.StringOption("api", "The API to use."),
+			cmds.OptionEncodingType,
+		},
+		Subcommands: map[string]*cmds.Command{
+			"node": {
+				Helptext: cmds.HelpText{Tagline: "Manage nodes."},
+				Options:  []cmds.Option{cmds.BoolOption("verbose", "v", "Be verbose.")},
+				Subcommands: map[string]*cmds.Command{
+					"get": {
+						Helptext: cmds.HelpText{
+							Tagline:          "Get a node.",
+							ShortDescription: "\nGets a node.\n",
+						},
+						Arguments: []cmds.Argument{
+							cmds.StringArg("path", true, false, "The path of the node."),
+							cmds.StringArg("other", false, true, ""),
+						},
+						Options: []cmds.Option{
+							cmds.IntOption("max-depth", "The depth.").WithDefault(-1),
+							cmds.StringsOption("filter", "The filters."),
+						},
+						Run:  run,
+						Type: testNode{},
+						Encoders: cmds.EncoderMap{
+							cmds.Text: cmds.MakeTypedEncoder(func(*cmds.Request, io.Writer, *testNode) error { return nil }),
+						},
+					},
+					"put-data": {
+						Status:    cmds.Experimental,
+						Arguments: []cmds.Argument{cmds.FileArg("data", true, false, "The data.")},
+						Run:       run,
+					},
+					"old": {Status: cmds.Deprecated, Run: run},
+				},
+			},
+			"local": {NoRemote: true, Run: run},
+		},
+	}
+
+	doc := Generate(root, "/api/v0", "0.1.0", "api")
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, "0.1.0", doc.Info.Version)
+	assert.Equal(t, []Tag{{Name: "node", Description: "Manage nodes."}}, doc.Tags)
+	assert.Len(t, doc.Paths, 3)
+	assert.NotContains(t, doc.Paths, "/api/v0/local")
+	assert.NotContains(t, doc.Paths, "/api/v0/node")
+	assert.NotContains(t, doc.Components.Parameters, "api")
+	require.Contains(t, doc.Components.Parameters, cmds.EncLong)
+
+	get := doc.Paths["/api/v0/node/get"].Post
+	require.NotNil(t, get)
+	assert.Equal(t, "nodeGet", get.OperationID)
+	assert.Equal(t, "Get a node.", get.Summary)
+	assert.Equal(t, "Gets a node.", get.Description)
+	assert.Equal(t, []string{"node"}, get.Tags)
+	assert.Equal(t, []string{"json", "text"}, get.Encodings)
+	require.Len(t, get.Parameters, 5)
+	assert.Equal(t, "arg", get.Parameters[0].Name)
+	assert.True(t, get.Parameters[0].Required)
+	assert.Contains(t, get.Parameters[0].Description, "`path`: The path of the node.; `other`...")
+	assert.Equal(t, "#/components/parameters/"+cmds.EncLong, get.Parameters[1].Ref)
+	assert.Equal(t, &Parameter{Name: "verbose", In: "query", Description: "Be verbose.", Schema: &Schema{Type: "boolean"}}, get.Parameters[2])
+	assert.Equal(t, &Schema{Type: "integer", Default: -1}, get.Parameters[3].Schema)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, get.Parameters[4].Schema)
+
+	ok := get.Responses["200"]
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/openapi.testNode"}, ok.Content["application/json"].Schema)
+	assert.Contains(t, ok.Content, "text/plain")
+	assert.Equal(t, "#/components/schemas/Error", get.Responses["default"].Content["application/json"].Schema.Ref)
+
+	node := doc.Components.Schemas["openapi.testNode"]
+	require.NotNil(t, node)
+	assert.Equal(t, []string{"Cid", "Links", "Name", "Parent", "Size"}, node.Required)
+	assert.Len(t, node.Properties, 7)
+	assert.Equal(t, &Schema{Type: "string"}, node.Properties["Name"])
+	assert.Equal(t, &Schema{Type: "string"}, node.Properties["Size"])
+	assert.Equal(t, "date-time", node.Properties["Time"].Format)
+	assert.Equal(t, []string{"/"}, node.Properties["Cid"].Required)
+	assert.Equal(t, &Schema{Type: "array", Nullable: true, Items: &Schema{Ref: "#/components/schemas/openapi.testNode"}}, node.Properties["Links"])
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/openapi.testNode"}, node.Properties["Parent"])
+	assert.Equal(t, &Schema{Type: "object", Nullable: true, AdditionalProperties: &Schema{}}, node.Properties["meta"])
+
+	put := doc.Paths["/api/v0/node/put-data"].Post
+	require.NotNil(t, put)
+	assert.Equal(t, "nodePutData", put.OperationID)
+	assert.Equal(t, "experimental", put.Status)
+	require.NotNil(t, put.RequestBody)
+	assert.True(t, put.RequestBody.Required)
+	assert.Equal(t, "binary", put.RequestBody.Content["multipart/form-data"].Schema.Properties["file"].Format)
+	assert.Equal(t, "binary", put.Responses["200"].Content["text/plain"].Schema.Format)
+
+	assert.True(t, doc.Paths["/api/v0/node/old"].Post.Deprecated)
+}
@@ -75,7 +75,9 @@ and use 'pin ls --names' to see it. Pinning a second time with a different
 name will update the name of the pin.
 
 If daemon is running, any missing blocks will be retrieved from the network.
-It may take some time. Pass '--progress' to track the progress.
+It may take some time. Pass '--progress' to track the progress, or '--async'
+to pin in a background job of the daemon: the job ID is output right away,
+and the job is managed with 'ipfs jobs'.
 `,
 	},
 
@@ -86,9 +88,14 @@ It may take some time. Pass '--progress' to track the progress.
 		cmds.BoolOption(pinRecursiveOptionName, "r", "Recursively pin the object linked to by the specified object(s).").WithDefault(true),
 		cmds.StringOption(pinNameOptionName, "n", "An optional name for created pin(s)."),
 		cmds.BoolOption(pinProgressOptionName, "Show progress"),
+		cmdutils.AsyncOption,
 	},
 	Type: AddPinOutput{},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		if async, err := cmdutils.RunAsync(req, res, env); async {
+			return err
+		}
+
 		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
@@ -597,6 +604,9 @@ derivative of an existing one, particularly for large objects. This allows a mor
 efficient DAG-traversal which fully skips already-pinned branches from the old
 object. As a requirement, the old object needs to be an existing recursive
 pin.
+
+Pass '--async' to update the pin in a background job of the daemon, managed
+with 'ipfs jobs'.
 `,
 	},
 
@@ -606,9 +616,14 @@ pin.
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption(pinUnpinOptionName, "Remove the old pin.").WithDefault(true),
+		cmdutils.AsyncOption,
 	},
 	Type: PinOutput{},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		if async, err := cmdutils.RunAsync(req, res, env); async {
+			return err
+		}
+
 		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
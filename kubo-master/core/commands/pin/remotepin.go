@@ -22,6 +22,7 @@ import (
 	config "github.com/ipfs/kubo/config"
 	"github.com/ipfs/kubo/core/commands/cmdenv"
 	"github.com/ipfs/kubo/core/commands/cmdutils"
+	"github.com/ipfs/kubo/core/events"
 	fsrepo "github.com/ipfs/kubo/repo/fsrepo"
 	"github.com/libp2p/go-libp2p/core/host"
 	peer "github.com/libp2p/go-libp2p/core/peer"
@@ -203,6 +204,8 @@ NOTE: a comma-separated notation is supported in CLI for convenience:
 		if err != nil {
 			return err
 		}
+		notify := remotePinNotifier(node.Events, req.Options[pinServiceNameOptionName].(string))
+		notify(ps)
 
 		// Act on PinStatus.delegates
 		// If Pinning Service returned any delegates, proactively try to
@@ -232,6 +235,7 @@ NOTE: a comma-separated notation is supported in CLI for convenience:
 				if ps.GetRequestId() != requestID {
 					return fmt.Errorf("failed to check pin status for requestid=%q, remote service sent unexpected requestid=%q", requestID, ps.GetRequestId())
 				}
+				notify(ps)
 				s := ps.GetStatus()
 				if s == pinclient.StatusPinned {
 					break
@@ -251,6 +255,10 @@ NOTE: a comma-separated notation is supported in CLI for convenience:
 					return fmt.Errorf("waiting for pin interrupted, requestid=%q remains on remote service", requestID)
 				}
 			}
+		} else if node.IsDaemon && node.Events.Subscribed(events.RemotePinStatus) {
+			// the daemon keeps watching the status of the pin, to emit an
+			// event when it changes
+			go watchRemotePin(node.Context(), c, ps.GetRequestId(), notify)
 		}
 
 		return res.Emit(toRemotePinOutput(ps))
@@ -263,6 +271,49 @@ NOTE: a comma-separated notation is supported in CLI for convenience:
 	},
 }
 
+// remotePinNotifier returns a function emitting an event when the status of a
+// remote pin changes.
+func remotePinNotifier(bus *events.Bus, service string) func(pinclient.PinStatusGetter) {
+	var last pinclient.Status
+	return func(ps pinclient.PinStatusGetter) {
+		if ps.GetStatus() == last {
+			return
+		}
+		last = ps.GetStatus()
+		bus.Emit(events.RemotePinStatus, events.RemotePinEvent{
+			Service:   service,
+			RequestID: ps.GetRequestId(),
+			Cid:       ps.GetPin().GetCid().String(),
+			Name:      ps.GetPin().GetName(),
+			Status:    last.String(),
+		})
+	}
+}
+
+// watchRemotePin polls the status of a remote pin until it is pinned or
+// failed.
+func watchRemotePin(ctx context.Context, c *pinclient.Client, requestID string, notify func(pinclient.PinStatusGetter)) {
+	const pollInterval = 5 * time.Second
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		ps, err := c.GetStatusByID(ctx, requestID)
+		if err != nil {
+			log.Errorf("failed to check pin status for requestid=%q due to error: %v", requestID, err)
+			continue
+		}
+		notify(ps)
+		if s := ps.GetStatus(); s == pinclient.StatusPinned || s == pinclient.StatusFailed {
+			return
+		}
+	}
+}
+
 var listRemotePinCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "List objects pinned to remote pinning service.",
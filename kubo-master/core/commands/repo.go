@@ -38,6 +38,7 @@ var RepoCmd = &cmds.Command{
 
 	Subcommands: map[string]*cmds.Command{
 		"stat":    repoStatCmd,
+		"du":      repoDuCmd,
 		"gc":      repoGcCmd,
 		"version": repoVersionCmd,
 		"verify":  repoVerifyCmd,
@@ -226,6 +227,98 @@ Version         string The repo version.
 	},
 }
 
+var repoDuCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show how much space each pin and MFS entry holds in the repo.",
+		ShortDescription: `
+'ipfs repo du' walks every pin and every top-level MFS entry and reports,
+for each of them, the bytes it holds alone and the bytes it shares with
+other roots.
+`,
+		LongDescription: `
+'ipfs repo du' walks every recursive and direct pin, as well as every
+top-level entry of the MFS root, and reports for each of them:
+
+Unique          int Bytes reachable only from this root.
+Shared          int Bytes also reachable from at least one other root.
+Blocks          int Number of blocks reachable from this root.
+Missing         int Number of blocks that are not in the local repo.
+
+Direct pins only hold their root block. Unique bytes are what 'ipfs repo gc'
+would free once the root is unpinned or removed from MFS. Only local blocks
+are walked; nothing is fetched from the network.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(repoHumanOptionName, "H", "Print sizes in human readable format (e.g., 1K 234M 2G)"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		enc, err := cmdenv.GetCidEncoder(req)
+		if err != nil {
+			return err
+		}
+
+		usage, err := corerepo.DiskUsage(req.Context, n)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range usage {
+			if err := res.Emit(&RepoDuEntry{
+				Cid:     enc.Encode(u.Cid),
+				Type:    u.Type,
+				Name:    u.Name,
+				Blocks:  u.NumBlocks,
+				Size:    u.TotalSize,
+				Unique:  u.UniqueSize,
+				Shared:  u.SharedSize,
+				Missing: u.MissingBlocks,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: RepoDuEntry{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RepoDuEntry) error {
+			human, _ := req.Options[repoHumanOptionName].(bool)
+
+			printSize := func(size uint64) string {
+				if human {
+					return humanize.Bytes(size)
+				}
+				return fmt.Sprintf("%d", size)
+			}
+
+			name := out.Cid
+			if out.Type == corerepo.DuRootMFS {
+				name = out.Name
+			}
+
+			_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", printSize(out.Unique), printSize(out.Shared), out.Type, name)
+			return err
+		}),
+	},
+}
+
+// RepoDuEntry is the result returned for each root by "repo du".
+type RepoDuEntry struct {
+	Cid     string
+	Type    string
+	Name    string `json:",omitempty"`
+	Blocks  uint64
+	Size    uint64
+	Unique  uint64
+	Shared  uint64
+	Missing uint64 `json:",omitempty"`
+}
+
 type VerifyProgress struct {
 	Msg      string
 	Progress int
@@ -415,7 +508,7 @@ var repoMigrateCmd = &cmds.Command{
 		fetchDistPath := migrations.GetDistPathEnv(migrations.CurrentIpfsDist)
 
 		// Create fetchers according to migrationCfg.DownloadSources
-		fetcher, err := migrations.GetMigrationFetcher(migrationCfg.DownloadSources, fetchDistPath, newIpfsFetcher)
+		fetcher, err := migrations.GetMigrationFetcher(migrationCfg.DownloadSources, fetchDistPath, newIpfsFetcher, migrationCfg.TrustedKeys...)
 		if err != nil {
 			return err
 		}
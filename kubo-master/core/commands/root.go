@@ -58,6 +58,7 @@ ADVANCED COMMANDS
   shutdown      Shut down the daemon process
   resolve       Resolve any type of content path
   name          Publish and resolve IPNS names
+  alias         Manage local names for content paths
   key           Create and list IPNS name keypairs
   pin           Pin objects to local storage
   repo          Manipulate the IPFS repository
@@ -80,6 +81,9 @@ TOOL COMMANDS
   config        Manage configuration
   version       Show IPFS version information
   diag          Generate diagnostic reports
+  events        Stream the events of the daemon
+  jobs          Manage background jobs of the daemon
+  webhooks      Inspect the webhooks notified by the daemon
   update        Download and apply go-ipfs updates
   commands      List all available commands
   log           Manage and show logs of running daemon
@@ -126,6 +130,7 @@ var CommandsDaemonCmd = CommandsCmd(Root)
 
 var rootSubcommands = map[string]*cmds.Command{
 	"add":       AddCmd,
+	"alias":     AliasCmd,
 	"bitswap":   BitswapCmd,
 	"block":     BlockCmd,
 	"cat":       CatCmd,
@@ -142,7 +147,9 @@ var rootSubcommands = map[string]*cmds.Command{
 	"dht":       DhtCmd,
 	"routing":   RoutingCmd,
 	"diag":      DiagCmd,
+	"events":    EventsCmd,
 	"id":        IDCmd,
+	"jobs":      JobsCmd,
 	"key":       KeyCmd,
 	"log":       LogCmd,
 	"ls":        LsCmd,
@@ -157,6 +164,7 @@ var rootSubcommands = map[string]*cmds.Command{
 	"swarm":     SwarmCmd,
 	"update":    ExternalBinary("Please see https://github.com/ipfs/ipfs-update/blob/master/README.md#install for installation instructions."),
 	"version":   VersionCmd,
+	"webhooks":  WebhooksCmd,
 	"shutdown":  daemonShutdownCmd,
 	"cid":       CidCmd,
 	"multibase": MbaseCmd,
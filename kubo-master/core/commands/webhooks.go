@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
+	"github.com/ipfs/kubo/core/webhooks"
+)
+
+const webhooksVerboseOptionName = "verbose"
+
+var WebhooksCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect the webhooks notified of the events of the daemon.",
+		ShortDescription: `
+The daemon sends its events to the webhooks of the 'Webhooks' config section,
+as signed JSON POST requests. See the documentation of 'Webhooks' in
+docs/config.md for the events and the format of the requests.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls": webhooksLsCmd,
+	},
+}
+
+// WebhookList is the output of 'ipfs webhooks ls'.
+type WebhookList struct {
+	Webhooks []webhooks.Status
+}
+
+var webhooksLsCmd = &cmds.Command{
+	Status: cmds.Experimental,
+	Helptext: cmds.HelpText{
+		Tagline: "List the webhooks and the state of their deliveries.",
+		ShortDescription: `
+Lists the webhooks with the number of events delivered to them, failed after
+all the retries, and pending delivery. The --verbose option also lists the
+last deliveries of each webhook, newest first.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(webhooksVerboseOptionName, "v", "Also list the last deliveries."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsDaemon {
+			return errors.New("webhooks are only notified by a running daemon")
+		}
+		return cmds.EmitOnce(res, &WebhookList{Webhooks: nd.Webhooks.Status()})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *WebhookList) error {
+			verbose, _ := req.Options[webhooksVerboseOptionName].(bool)
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "Name\tURL\tEvents\tDelivered\tFailed\tPending")
+			for _, h := range out.Webhooks {
+				events := "*"
+				if len(h.Events) > 0 {
+					events = strings.Join(h.Events, ",")
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\n", h.Name, h.URL, events, h.Delivered, h.Failed, h.Pending)
+				if !verbose {
+					continue
+				}
+				for _, dv := range h.Recent {
+					fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\tattempts=%d", dv.ID, dv.Type, dv.Time.Format(time.Stamp), dv.State, dv.Attempts)
+					if dv.StatusCode != 0 {
+						fmt.Fprintf(tw, " status=%d", dv.StatusCode)
+					}
+					if dv.Error != "" {
+						fmt.Fprintf(tw, " error=%q", dv.Error)
+					}
+					fmt.Fprintln(tw)
+				}
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: WebhookList{},
+}
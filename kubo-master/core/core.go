@@ -51,9 +51,12 @@ import (
 	"github.com/ipfs/boxo/namesys"
 	ipnsrp "github.com/ipfs/boxo/namesys/republisher"
 	"github.com/ipfs/boxo/peering"
+	"github.com/ipfs/kubo/blocks/carstore"
 	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/events"
 	"github.com/ipfs/kubo/core/node"
 	"github.com/ipfs/kubo/core/node/libp2p"
+	"github.com/ipfs/kubo/core/webhooks"
 	"github.com/ipfs/kubo/fuse/mount"
 	"github.com/ipfs/kubo/p2p"
 	"github.com/ipfs/kubo/repo"
@@ -80,6 +83,7 @@ type IpfsNode struct {
 	Blockstore                  bstore.GCBlockstore       // the block store (lower level)
 	Filestore                   *filestore.Filestore      `optional:"true"` // the filestore blockstore
 	BaseBlocks                  node.BaseBlocks           // the raw blockstore, no filestore wrapping
+	CarStore                    *carstore.Store           // the CAR files attached under the blockstore
 	GCLocker                    bstore.GCLocker           // the locker used to protect the blockstore during gc
 	Blocks                      bserv.BlockService        // the block service, get/add blocks.
 	DAG                         ipld.DAGService           // the merkle dag service, get/add objects.
@@ -109,6 +113,8 @@ type IpfsNode struct {
 	Provider                  provider.System            // the value provider system
 	IpnsRepub                 *ipnsrp.Republisher        `optional:"true"`
 	ResourceManager           network.ResourceManager    `optional:"true"`
+	Events                    *events.Bus                `optional:"true"` // the events of the node
+	Webhooks                  *webhooks.Dispatcher       `optional:"true"` // notifies webhooks of the events of the node
 
 	PubSub   *pubsub.PubSub             `optional:"true"`
 	PSRouter *psrouter.PubsubValueStore `optional:"true"`
@@ -26,6 +26,7 @@ import (
 	provider "github.com/ipfs/boxo/provider"
 	offlineroute "github.com/ipfs/boxo/routing/offline"
 	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipfs/kubo/config"
 	coreiface "github.com/ipfs/kubo/core/coreiface"
 	"github.com/ipfs/kubo/core/coreiface/options"
@@ -40,10 +41,13 @@ import (
 
 	"github.com/ipfs/boxo/namesys"
 	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/events"
 	"github.com/ipfs/kubo/core/node"
 	"github.com/ipfs/kubo/repo"
 )
 
+var log = logging.Logger("coreapi")
+
 type CoreAPI struct {
 	nctx context.Context
 
@@ -74,6 +78,8 @@ type CoreAPI struct {
 
 	pubSub *pubsub.PubSub
 
+	events *events.Bus
+
 	checkPublishAllowed func() error
 	checkOnline         func(allowOffline bool) error
 
@@ -189,6 +195,8 @@ func (api *CoreAPI) WithOptions(opts ...options.ApiOption) (coreiface.CoreAPI, e
 
 		pubSub: n.PubSub,
 
+		events: n.Events,
+
 		nd:         n,
 		parentOpts: settings,
 	}
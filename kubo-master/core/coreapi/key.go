@@ -1,7 +1,9 @@
 package coreapi
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -76,6 +78,10 @@ func (api *KeyAPI) Generate(ctx context.Context, name string, opts ...caopts.Key
 	var sk crypto.PrivKey
 	var pk crypto.PubKey
 
+	if options.Seed != nil && options.Algorithm != caopts.Ed25519Key {
+		return nil, fmt.Errorf("a seed can only be used with key type %s", caopts.Ed25519Key)
+	}
+
 	switch options.Algorithm {
 	case "rsa":
 		if options.Size == -1 {
@@ -90,7 +96,14 @@ func (api *KeyAPI) Generate(ctx context.Context, name string, opts ...caopts.Key
 		sk = priv
 		pk = pub
 	case "ed25519":
-		priv, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+		src := rand.Reader
+		if options.Seed != nil {
+			if len(options.Seed) != ed25519.SeedSize {
+				return nil, fmt.Errorf("seed must be %d bytes long, got %d", ed25519.SeedSize, len(options.Seed))
+			}
+			src = bytes.NewReader(options.Seed)
+		}
+		priv, pub, err := crypto.GenerateEd25519Key(src)
 		if err != nil {
 			return nil, err
 		}
@@ -10,6 +10,7 @@ import (
 	"github.com/ipfs/boxo/ipns"
 	keystore "github.com/ipfs/boxo/keystore"
 	"github.com/ipfs/boxo/namesys"
+	"github.com/ipfs/kubo/namesys/history"
 	"github.com/ipfs/kubo/tracing"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -66,6 +67,7 @@ func (api *NameAPI) Publish(ctx context.Context, p path.Path, opts ...caopts.Nam
 		publishOptions = append(publishOptions, namesys.PublishWithTTL(*options.TTL))
 	}
 
+	published := time.Now()
 	err = api.namesys.Publish(ctx, k, p, publishOptions...)
 	if err != nil {
 		return ipns.Name{}, err
@@ -75,8 +77,27 @@ func (api *NameAPI) Publish(ctx context.Context, p path.Path, opts ...caopts.Nam
 	if err != nil {
 		return ipns.Name{}, err
 	}
+	name := ipns.NameFromPeer(pid)
 
-	return ipns.NameFromPeer(pid), nil
+	if err = api.addHistory(ctx, name, published); err != nil {
+		log.Errorf("failed to record the publication of %s in its history: %s", name, err)
+	}
+
+	return name, nil
+}
+
+// addHistory records the record just published for name in its history.
+func (api *NameAPI) addHistory(ctx context.Context, name ipns.Name, published time.Time) error {
+	ds := api.repo.Datastore()
+	data, err := ds.Get(ctx, namesys.IpnsDsKey(name))
+	if err != nil {
+		return err
+	}
+	rec, err := ipns.UnmarshalRecord(data)
+	if err != nil {
+		return err
+	}
+	return history.Add(ctx, ds, name, rec, published)
 }
 
 func (api *NameAPI) Search(ctx context.Context, name string, opts ...caopts.NameResolveOption) (<-chan coreiface.IpnsResult, error) {
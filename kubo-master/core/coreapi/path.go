@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/ipfs/boxo/namesys"
+	"github.com/ipfs/kubo/namesys/alias"
 	"github.com/ipfs/kubo/tracing"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -36,11 +37,17 @@ func (api *CoreAPI) ResolveNode(ctx context.Context, p path.Path) (ipld.Node, er
 }
 
 // ResolvePath resolves the path `p` using Unixfs resolver, returns the
-// resolved path.
+// resolved path. Paths starting with an alias are resolved through the alias
+// registry first.
 func (api *CoreAPI) ResolvePath(ctx context.Context, p path.Path) (path.ImmutablePath, []string, error) {
 	ctx, span := tracing.Span(ctx, "CoreAPI", "ResolvePath", trace.WithAttributes(attribute.String("path", p.String())))
 	defer span.End()
 
+	p, err := alias.Resolve(ctx, api.repo.Datastore(), p)
+	if err != nil {
+		return path.ImmutablePath{}, nil, err
+	}
+
 	res, err := namesys.Resolve(ctx, api.namesys, p)
 	if errors.Is(err, namesys.ErrNoNamesys) {
 		return path.ImmutablePath{}, nil, coreiface.ErrOffline
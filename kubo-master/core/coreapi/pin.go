@@ -13,6 +13,7 @@ import (
 	"github.com/ipfs/go-cid"
 	coreiface "github.com/ipfs/kubo/core/coreiface"
 	caopts "github.com/ipfs/kubo/core/coreiface/options"
+	"github.com/ipfs/kubo/core/events"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
@@ -21,19 +22,30 @@ import (
 
 type PinAPI CoreAPI
 
-func (api *PinAPI) Add(ctx context.Context, p path.Path, opts ...caopts.PinAddOption) error {
+func (api *PinAPI) Add(ctx context.Context, p path.Path, opts ...caopts.PinAddOption) (err error) {
 	ctx, span := tracing.Span(ctx, "CoreAPI.PinAPI", "Add", trace.WithAttributes(attribute.String("path", p.String())))
 	defer span.End()
 
-	dagNode, err := api.core().ResolveNode(ctx, p)
+	settings, err := caopts.PinAddOptions(opts...)
 	if err != nil {
-		return fmt.Errorf("pin: %s", err)
+		return err
 	}
 
-	settings, err := caopts.PinAddOptions(opts...)
+	ev := events.PinEvent{Path: p.String(), Recursive: settings.Recursive, Name: settings.Name}
+	defer func() {
+		if err != nil {
+			ev.Error = err.Error()
+			api.events.Emit(events.PinFailed, ev)
+		} else {
+			api.events.Emit(events.PinAdded, ev)
+		}
+	}()
+
+	dagNode, err := api.core().ResolveNode(ctx, p)
 	if err != nil {
-		return err
+		return fmt.Errorf("pin: %s", err)
 	}
+	ev.Cid = dagNode.Cid().String()
 
 	span.SetAttributes(attribute.Bool("recursive", settings.Recursive))
 
@@ -122,7 +134,15 @@ func (api *PinAPI) Rm(ctx context.Context, p path.Path, opts ...caopts.PinRmOpti
 		return err
 	}
 
-	return api.pinning.Flush(ctx)
+	if err = api.pinning.Flush(ctx); err != nil {
+		return err
+	}
+	api.events.Emit(events.PinRemoved, events.PinEvent{
+		Path:      p.String(),
+		Cid:       rp.RootCid().String(),
+		Recursive: settings.Recursive,
+	})
+	return nil
 }
 
 func (api *PinAPI) Update(ctx context.Context, from path.Path, to path.Path, opts ...caopts.PinUpdateOption) error {
@@ -0,0 +1,54 @@
+package corehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	core "github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/namesys/alias"
+)
+
+// AliasOption serves /alias/<name>/<path> by redirecting to the target of the
+// alias, as registered with 'ipfs alias set'.
+func AliasOption() ServeOption {
+	return func(n *core.IpfsNode, _ net.Listener, mux *http.ServeMux) (*http.ServeMux, error) {
+		mux.Handle("/"+alias.Namespace+"/", &aliasHandler{node: n})
+		return mux, nil
+	}
+}
+
+type aliasHandler struct {
+	node *core.IpfsNode
+}
+
+func (h *aliasHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/"+alias.Namespace+"/")
+	p, err := alias.NewPath(alias.Prefix + rest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target, err := alias.Resolve(r.Context(), h.node.Repo.Datastore(), p)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, alias.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	location := &url.URL{Path: target.String(), RawQuery: r.URL.RawQuery}
+	if strings.HasSuffix(rest, "/") && !strings.HasSuffix(location.Path, "/") {
+		location.Path += "/"
+	}
+	http.Redirect(w, r, location.String(), http.StatusFound)
+}
@@ -0,0 +1,150 @@
+package corehttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdsHttp "github.com/ipfs/go-ipfs-cmds/http"
+	"github.com/ipfs/kubo/core/auditlog"
+	corecommands "github.com/ipfs/kubo/core/commands"
+)
+
+type auditEntryKey struct{}
+
+// auditSecretOptions are substrings of the names of the options whose values
+// are redacted in the audit log.
+// "auth" covers the api-auth option that the ipfs command forwards to the
+// daemon.
+var auditSecretOptions = []string{"auth", "secret", "token", "password", "passphrase", "mnemonic"}
+
+// withAuditLog writes an entry to the audit log for each request handled by
+// next. The entry of the request is in its context, for withAuthSecrets to
+// record the user.
+func withAuditLog(auditLog *auditlog.Writer, root *cmds.Command, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &auditlog.Entry{
+			Time:    start,
+			Remote:  r.RemoteAddr,
+			Method:  r.Method,
+			Command: strings.Trim(strings.TrimPrefix(r.URL.Path, APIPath), "/"),
+		}
+		if req, ok := parseRPCRequest(root, r); ok {
+			entry.Command = strings.TrimPrefix(req.path, APIPath+"/")
+			entry.Args, entry.Options = redactAuditArgs(entry.Command, req.args, req.options)
+		}
+
+		aw := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(aw, r.WithContext(context.WithValue(r.Context(), auditEntryKey{}, entry)))
+
+		entry.Duration = time.Since(start)
+		entry.Status = aw.status
+		switch {
+		case aw.status == http.StatusForbidden || aw.status == http.StatusUnauthorized:
+			entry.Outcome = auditlog.OutcomeDenied
+			entry.Error = aw.errorMessage()
+		case aw.status >= http.StatusBadRequest:
+			entry.Outcome = auditlog.OutcomeError
+			entry.Error = aw.errorMessage()
+		case w.Header().Get(cmdsHttp.StreamErrHeader) != "":
+			entry.Outcome = auditlog.OutcomeError
+			entry.Error = w.Header().Get(cmdsHttp.StreamErrHeader)
+		default:
+			entry.Outcome = auditlog.OutcomeSuccess
+		}
+		if err := auditLog.Log(entry); err != nil {
+			log.Errorf("writing audit log: %s", err)
+		}
+	})
+}
+
+// setAuditUser records the authenticated user in the audit log entry of r.
+func setAuditUser(r *http.Request, auth rpcAuthScopeWithUser) {
+	if entry, ok := r.Context().Value(auditEntryKey{}).(*auditlog.Entry); ok {
+		entry.User = auth.User
+		entry.Subject = auth.Subject
+	}
+}
+
+// redactAuditArgs returns copies of args and options where the values that
+// may hold secrets are redacted: config values concealed by 'ipfs config
+// show', remote pinning service keys, and options named like secrets.
+func redactAuditArgs(command string, args []string, options map[string][]string) ([]string, map[string][]string) {
+	args = slices.Clone(args)
+	switch command {
+	case "config":
+		if len(args) > 1 && corecommands.ConfigKeyConcealed(args[0]) {
+			args[1] = auditlog.Redacted
+		}
+	case "pin/remote/service/add":
+		if len(args) > 2 {
+			args[2] = auditlog.Redacted
+		}
+	}
+
+	var redacted map[string][]string
+	for name, values := range options {
+		if redacted == nil {
+			redacted = make(map[string][]string, len(options))
+		}
+		lower := strings.ToLower(name)
+		if slices.ContainsFunc(auditSecretOptions, func(s string) bool { return strings.Contains(lower, s) }) {
+			redacted[name] = []string{auditlog.Redacted}
+		} else {
+			redacted[name] = slices.Clone(values)
+		}
+	}
+	return args, redacted
+}
+
+// auditResponseWriter records the status of a response, and the beginning of
+// its body when it is an error.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+const auditMaxErrorBody = 1024
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if w.status >= http.StatusBadRequest && w.body.Len() < auditMaxErrorBody {
+		w.body.Write(b[:min(len(b), auditMaxErrorBody-w.body.Len())])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// errorMessage returns the message of the error in the body of the response.
+func (w *auditResponseWriter) errorMessage() string {
+	var cmdsErr cmds.Error
+	if json.Unmarshal(w.body.Bytes(), &cmdsErr) == nil && cmdsErr.Message != "" {
+		return cmdsErr.Message
+	}
+	return strings.TrimSpace(w.body.String())
+}
@@ -0,0 +1,147 @@
+package corehttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdsHttp "github.com/ipfs/go-ipfs-cmds/http"
+	config "github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/auditlog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	run := func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return nil }
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"config": {
+				Arguments: []cmds.Argument{
+					cmds.StringArg("key", true, false, ""),
+					cmds.StringArg("value", false, false, ""),
+				},
+				Options: []cmds.Option{cmds.BoolOption("json", "")},
+				Run:     run,
+			},
+			"pin": {
+				Subcommands: map[string]*cmds.Command{
+					"rm": {
+						Arguments: []cmds.Argument{cmds.StringArg("ipfs-path", true, true, "")},
+						Run:       run,
+					},
+					"remote": {
+						Subcommands: map[string]*cmds.Command{
+							"service": {
+								Subcommands: map[string]*cmds.Command{
+									"add": {
+										Arguments: []cmds.Argument{
+											cmds.StringArg("service", true, false, ""),
+											cmds.StringArg("endpoint", true, false, ""),
+											cmds.StringArg("key", true, false, ""),
+										},
+										Run: run,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"key": {
+				Subcommands: map[string]*cmds.Command{
+					"import": {
+						Options: []cmds.Option{cmds.StringOption("passphrase", "")},
+						Run:     run,
+					},
+					"gen": {
+						Arguments: []cmds.Argument{cmds.StringArg("name", true, false, "")},
+						Options:   []cmds.Option{cmds.StringOption("from-mnemonic", "")},
+						Run:       run,
+					},
+				},
+			},
+		},
+	}
+
+	authorizations, err := convertAuthorizationsMap(&config.API{Authorizations: map[string]*config.RPCAuthScope{
+		"admin": {AuthSecret: "admin-token", AllowedPaths: []string{"/api/v0"}},
+		"ci":    {AuthSecret: "ci-token", AllowedPaths: []string{"/api/v0/pin/rm"}},
+	}})
+	require.NoError(t, err)
+
+	repo := t.TempDir()
+	auditLog, err := auditlog.Open(repo, config.RPCAuditLog{})
+	require.NoError(t, err)
+
+	handler := withAuditLog(auditLog, root, withAuthSecrets(authorizations, nil, root, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/pin/rm":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(cmds.Errorf(cmds.ErrNormal, "not pinned"))
+		case "/api/v0/key/import":
+			w.Header().Set("Trailer", cmdsHttp.StreamErrHeader)
+			w.WriteHeader(http.StatusOK)
+			w.Header().Set(cmdsHttp.StreamErrHeader, "invalid key")
+		}
+	})))
+
+	for _, req := range []struct{ token, url string }{
+		{"admin-token", "/api/v0/config?arg=Addresses.API&arg=/ip4/127.0.0.1/tcp/5001"},
+		{"admin-token", "/api/v0/config?arg=Identity.PrivKey&arg=CAESQ"},
+		{"admin-token", "/api/v0/config?arg=Pinning.RemoteServices.svc.API.Key&arg=s3cr3t"},
+		{"admin-token", "/api/v0/pin/remote/service/add?arg=svc&arg=https://pin.example.com&arg=s3cr3t"},
+		{"admin-token", "/api/v0/key/import?passphrase=s3cr3t&api-auth=bearer:admin-token"},
+		{"admin-token", "/api/v0/key/gen?arg=mysite&from-mnemonic=abandon+abandon+art"},
+		{"ci-token", "/api/v0/pin/rm?arg=bafkqaaa"},
+		{"ci-token", "/api/v0/config?arg=Addresses.API"},
+		{"", "/api/v0/pin/rm?arg=bafkqaaa"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, req.url, nil)
+		if req.token != "" {
+			r.Header.Set("Authorization", "Bearer "+req.token)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	var entries []*auditlog.Entry
+	require.NoError(t, auditlog.Read(auditlog.Path(repo, config.RPCAuditLog{}), func(e *auditlog.Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	require.Len(t, entries, 9)
+
+	for i, expected := range []struct {
+		user, command string
+		args          []string
+		outcome       string
+		errMsg        string
+	}{
+		{"admin", "config", []string{"Addresses.API", "/ip4/127.0.0.1/tcp/5001"}, auditlog.OutcomeSuccess, ""},
+		{"admin", "config", []string{"Identity.PrivKey", auditlog.Redacted}, auditlog.OutcomeSuccess, ""},
+		{"admin", "config", []string{"Pinning.RemoteServices.svc.API.Key", auditlog.Redacted}, auditlog.OutcomeSuccess, ""},
+		{"admin", "pin/remote/service/add", []string{"svc", "https://pin.example.com", auditlog.Redacted}, auditlog.OutcomeSuccess, ""},
+		{"admin", "key/import", nil, auditlog.OutcomeError, "invalid key"},
+		{"admin", "key/gen", []string{"mysite"}, auditlog.OutcomeSuccess, ""},
+		{"ci", "pin/rm", []string{"bafkqaaa"}, auditlog.OutcomeError, "not pinned"},
+		{"ci", "config", []string{"Addresses.API"}, auditlog.OutcomeDenied, ""},
+		{"", "pin/rm", []string{"bafkqaaa"}, auditlog.OutcomeDenied, ""},
+	} {
+		e := entries[i]
+		require.Equal(t, expected.user, e.User, i)
+		require.Equal(t, expected.command, e.Command, i)
+		require.Equal(t, expected.args, e.Args, i)
+		require.Equal(t, expected.outcome, e.Outcome, i)
+		if expected.errMsg != "" {
+			require.Equal(t, expected.errMsg, e.Error, i)
+		}
+	}
+	require.Equal(t, map[string][]string{
+		"passphrase": {auditlog.Redacted},
+		"api-auth":   {auditlog.Redacted},
+	}, entries[4].Options)
+	require.Equal(t, map[string][]string{"from-mnemonic": {auditlog.Redacted}}, entries[5].Options)
+	require.Contains(t, entries[7].Error, "Kubo RPC Access Denied")
+}
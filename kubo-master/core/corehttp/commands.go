@@ -15,6 +15,7 @@ import (
 	oldcmds "github.com/ipfs/kubo/commands"
 	config "github.com/ipfs/kubo/config"
 	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/auditlog"
 	corecommands "github.com/ipfs/kubo/core/commands"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
@@ -140,10 +141,27 @@ func commandsOption(cctx oldcmds.Context, command *cmds.Command) ServeOption {
 		patchCORSVars(cfg, l.Addr())
 
 		cmdHandler := cmdsHttp.NewHandler(&cctx, command, cfg)
+		cmdHandler = withEventStream(cfg, n.Events, cmdHandler)
+		cmdHandler = withOpenAPI(cfg, command, cmdHandler)
 
 		if len(rcfg.API.Authorizations) > 0 {
-			authorizations := convertAuthorizationsMap(rcfg.API.Authorizations)
-			cmdHandler = withAuthSecrets(authorizations, cmdHandler)
+			authorizations, err := convertAuthorizationsMap(&rcfg.API)
+			if err != nil {
+				return nil, err
+			}
+			jwtVerifiers, err := newRPCJWTVerifiers(&rcfg.API)
+			if err != nil {
+				return nil, err
+			}
+			cmdHandler = withAuthSecrets(authorizations, jwtVerifiers, command, cmdHandler)
+		}
+
+		if rcfg.API.AuditLog.Enabled.WithDefault(config.DefaultAuditLogEnabled) {
+			auditLog, err := auditlog.Open(n.Repo.Path(), rcfg.API.AuditLog)
+			if err != nil {
+				return nil, fmt.Errorf("opening audit log: %w", err)
+			}
+			cmdHandler = withAuditLog(auditLog, command, cmdHandler)
 		}
 
 		cmdHandler = otelhttp.NewHandler(cmdHandler, "corehttp.cmdsHandler")
@@ -155,41 +173,71 @@ func commandsOption(cctx oldcmds.Context, command *cmds.Command) ServeOption {
 type rpcAuthScopeWithUser struct {
 	config.RPCAuthScope
 	User string
+	// Subject is the subject of the JSON Web Token of the request, if any.
+	Subject string
+	Roles   []*config.RPCRole
 }
 
-func convertAuthorizationsMap(authScopes map[string]*config.RPCAuthScope) map[string]rpcAuthScopeWithUser {
+func convertAuthorizationsMap(api *config.API) (map[string]rpcAuthScopeWithUser, error) {
 	// authorizations is a map where we can just check for the header value to match.
 	authorizations := map[string]rpcAuthScopeWithUser{}
-	for user, authScope := range authScopes {
+	for user, authScope := range api.Authorizations {
 		expectedHeader := config.ConvertAuthSecret(authScope.AuthSecret)
 		if expectedHeader != "" {
+			roles, err := api.AuthorizationRoles(user)
+			if err != nil {
+				return nil, err
+			}
 			authorizations[expectedHeader] = rpcAuthScopeWithUser{
-				RPCAuthScope: *authScopes[user],
+				RPCAuthScope: *authScope,
 				User:         user,
+				Roles:        roles,
 			}
 		}
 	}
 
-	return authorizations
+	return authorizations, nil
 }
 
-func withAuthSecrets(authorizations map[string]rpcAuthScopeWithUser, next http.Handler) http.Handler {
+func withAuthSecrets(authorizations map[string]rpcAuthScopeWithUser, jwtVerifiers []*rpcJWTVerifier, root *cmds.Command, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authorizationHeader := r.Header.Get("Authorization")
 		auth, ok := authorizations[authorizationHeader]
+		if !ok {
+			auth, ok = verifyRPCJWT(jwtVerifiers, authorizationHeader)
+		}
 
 		if ok {
+			setAuditUser(r, auth)
+
 			// version check is implicitly allowed
 			if r.URL.Path == "/api/v0/version" {
 				next.ServeHTTP(w, r)
 				return
 			}
-			// everything else has to be safelisted via AllowedPaths
+			// everything else has to be safelisted via AllowedPaths or
+			// the rules of the roles of the user
+			allowedPath := false
 			for _, prefix := range auth.AllowedPaths {
 				if strings.HasPrefix(r.URL.Path, prefix) {
+					allowedPath = true
+					break
+				}
+			}
+			if len(auth.Roles) == 0 {
+				if allowedPath {
 					next.ServeHTTP(w, r)
 					return
 				}
+			} else if req, ok := parseRPCRequest(root, r); ok {
+				if allowsRPCRequest(auth.Roles, req, allowedPath) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			} else if allowedPath {
+				// not a command: the handler answers 404
+				next.ServeHTTP(w, r)
+				return
 			}
 		}
 
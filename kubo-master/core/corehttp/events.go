@@ -0,0 +1,132 @@
+package corehttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cmdsHttp "github.com/ipfs/go-ipfs-cmds/http"
+	"github.com/ipfs/kubo/core/events"
+)
+
+// eventStreamKeepAlive is the interval of the comments sent on idle event
+// streams, to keep proxies from closing them.
+const eventStreamKeepAlive = 15 * time.Second
+
+// withEventStream serves the requests to the events command accepting
+// text/event-stream as Server-Sent Events, which browsers can subscribe to
+// with an EventSource. Other requests are passed to next.
+func withEventStream(cfg *cmdsHttp.ServerConfig, bus *events.Bus, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != cfg.APIPath+"/events" || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "405 - Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		origin, ok := requestOrigin(cfg, r)
+		if !ok {
+			http.Error(w, "403 - Forbidden", http.StatusForbidden)
+			return
+		}
+
+		q := r.URL.Query()
+		var types []string
+		for _, t := range q["type"] {
+			types = append(types, strings.Split(t, ",")...)
+		}
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = q.Get("last-event-id")
+		}
+		sub, err := bus.Subscribe(types, lastEventID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer sub.Close()
+
+		h := w.Header()
+		for k, v := range cfg.Headers {
+			h[k] = v
+		}
+		if origin != "" {
+			h.Set(cmdsHttp.ACAOrigin, origin)
+			h.Add("Vary", "Origin")
+		}
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flush := func() {
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		flush()
+
+		keepAlive := time.NewTicker(eventStreamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case ev, ok := <-sub.Events():
+				if !ok {
+					if err := sub.Err(); err != nil {
+						fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+						flush()
+					}
+					return
+				}
+				data, err := json.Marshal(&ev)
+				if err != nil {
+					log.Errorf("encoding %s event: %s", ev.Type, err)
+					continue
+				}
+				if ev.ID != "" {
+					fmt.Fprintf(w, "id: %s\n", ev.ID)
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+					return
+				}
+				flush()
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+				flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+}
+
+// requestOrigin returns the origin of a request, and whether it is allowed by
+// the CORS settings of the API. Requests without an Origin or
+// Referer header are not from browsers, and are allowed.
+func requestOrigin(cfg *cmdsHttp.ServerConfig, r *http.Request) (string, bool) {
+	origin := r.Header.Get("Origin")
+	check := origin
+	if check == "" {
+		referer := r.Referer()
+		if referer == "" {
+			return "", true
+		}
+		u, err := url.Parse(referer)
+		if err != nil {
+			return "", false
+		}
+		check = u.Scheme + "://" + u.Host
+	}
+	for _, o := range cfg.AllowedOrigins() {
+		if o == "*" || o == check {
+			return origin, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,86 @@
+package corehttp
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cmdsHttp "github.com/ipfs/go-ipfs-cmds/http"
+	"github.com/ipfs/kubo/core/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStream(t *testing.T) {
+	bus := events.NewBus(8)
+	var ids []string
+	bus.Handle(nil, func(ev events.Event) { ids = append(ids, ev.ID) })
+	bus.Emit(events.PinAdded, events.PinEvent{Cid: "bafkqaaa"})
+	bus.Emit(events.GCStarted, nil)
+	bus.Emit(events.PinRemoved, events.PinEvent{Cid: "bafkqaaa"})
+
+	cfg := cmdsHttp.NewServerConfig()
+	cfg.APIPath = APIPath
+	cfg.SetAllowedOrigins("http://localhost:3000")
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	srv := httptest.NewServer(withEventStream(cfg, bus, next))
+	defer srv.Close()
+
+	do := func(t *testing.T, method, path string, header map[string]string) *http.Response {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, method, srv.URL+path, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			cancel()
+			resp.Body.Close()
+		})
+		return resp
+	}
+	sse := map[string]string{"Accept": "text/event-stream"}
+
+	t.Run("other requests are passed on", func(t *testing.T) {
+		assert.Equal(t, http.StatusTeapot, do(t, http.MethodPost, APIPath+"/events", nil).StatusCode)
+		assert.Equal(t, http.StatusTeapot, do(t, http.MethodGet, APIPath+"/id", sse).StatusCode)
+	})
+
+	t.Run("streams the filtered events after the last event ID", func(t *testing.T) {
+		resp := do(t, http.MethodGet, APIPath+"/events?type=pin.*", map[string]string{
+			"Accept":        "text/event-stream",
+			"Last-Event-ID": ids[0],
+			"Origin":        "http://localhost:3000",
+		})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "http://localhost:3000", resp.Header.Get(cmdsHttp.ACAOrigin))
+
+		scanner := bufio.NewScanner(resp.Body)
+		require.True(t, scanner.Scan())
+		assert.Equal(t, "id: "+ids[2], scanner.Text())
+		require.True(t, scanner.Scan())
+		assert.Equal(t, "event: "+events.PinRemoved, scanner.Text())
+	})
+
+	t.Run("rejects disallowed origins and invalid IDs", func(t *testing.T) {
+		resp := do(t, http.MethodGet, APIPath+"/events", map[string]string{
+			"Accept":  "text/event-stream",
+			"Referer": "https://example.com/page",
+		})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = do(t, http.MethodGet, APIPath+"/events?last-event-id=invalid", sse)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = do(t, http.MethodPut, APIPath+"/events", sse)
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
@@ -0,0 +1,54 @@
+package corehttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdsHttp "github.com/ipfs/go-ipfs-cmds/http"
+	corecommands "github.com/ipfs/kubo/core/commands"
+)
+
+// openAPIPath is where the OpenAPI document is served, under APIPath.
+const openAPIPath = "/openapi.json"
+
+// withOpenAPI serves the OpenAPI document of the commands at
+// APIPath/openapi.json, to GET and POST requests. Other requests are passed
+// to next.
+func withOpenAPI(cfg *cmdsHttp.ServerConfig, root *cmds.Command, next http.Handler) http.Handler {
+	doc := sync.OnceValues(func() ([]byte, error) {
+		return json.Marshal(corecommands.OpenAPI(root, cfg.APIPath))
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != cfg.APIPath+openAPIPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "405 - Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		origin, ok := requestOrigin(cfg, r)
+		if !ok {
+			http.Error(w, "403 - Forbidden", http.StatusForbidden)
+			return
+		}
+		body, err := doc()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		h := w.Header()
+		for k, v := range cfg.Headers {
+			h[k] = v
+		}
+		if origin != "" {
+			h.Set(cmdsHttp.ACAOrigin, origin)
+			h.Add("Vary", "Origin")
+		}
+		h.Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
@@ -0,0 +1,174 @@
+package corehttp
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/kubo/config"
+)
+
+// rpcRequest is what the rules of API.Roles match in an RPC request.
+type rpcRequest struct {
+	// path is the RPC path of the command, without the arguments that may
+	// follow it in the URL.
+	path    string
+	method  string
+	args    []string
+	options map[string][]string
+	// bodyArgs is set when more arguments may be read from the request
+	// body, where they cannot be checked.
+	bodyArgs bool
+}
+
+// rpcEndpoints are the paths served in front of the commands handler, which
+// are not commands. Their requests have no arguments nor options.
+var rpcEndpoints = []string{APIPath + openAPIPath}
+
+// parseRPCRequest extracts the command, arguments and options of r the same
+// way as the commands HTTP handler does. It returns false when r does not
+// target a command of root, nor one of rpcEndpoints.
+func parseRPCRequest(root *cmds.Command, r *http.Request) (*rpcRequest, bool) {
+	if slices.Contains(rpcEndpoints, r.URL.Path) {
+		return &rpcRequest{path: r.URL.Path, method: r.Method, options: map[string][]string{}}, true
+	}
+
+	p := strings.Trim(strings.TrimPrefix(r.URL.Path, APIPath), "/")
+	if p == "" {
+		return nil, false
+	}
+	pth := strings.Split(p, "/")
+
+	cmdPath, err := root.Resolve(pth[:len(pth)-1])
+	if err != nil {
+		return nil, false
+	}
+	req := &rpcRequest{
+		method:  r.Method,
+		options: map[string][]string{},
+	}
+	cmd := cmdPath[len(cmdPath)-1]
+	if sub := cmd.Subcommands[pth[len(pth)-1]]; sub != nil {
+		cmd = sub
+	} else {
+		// the last element of the path is an argument, as in /cat/<cid>
+		req.args = append(req.args, pth[len(pth)-1])
+		pth = pth[:len(pth)-1]
+	}
+	req.path = APIPath + "/" + strings.Join(pth, "/")
+
+	optDefs, err := root.GetOptions(pth)
+	if err != nil {
+		return nil, false
+	}
+	for k, v := range r.URL.Query() {
+		if k == "arg" {
+			req.args = append(req.args, v...)
+			continue
+		}
+		if optDef, ok := optDefs[k]; ok {
+			k = optDef.Name()
+		}
+		req.options[k] = append(req.options[k], v...)
+	}
+
+	mediatype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediatype == "multipart/form-data" {
+		for _, arg := range cmd.Arguments {
+			if arg.Type == cmds.ArgString && arg.SupportsStdin {
+				req.bodyArgs = true
+			}
+		}
+	}
+	return req, true
+}
+
+// allowsRPCRequest returns whether the roles of a user allow req. With
+// allowedPath, req is allowed unless a Deny rule matches.
+func allowsRPCRequest(roles []*config.RPCRole, req *rpcRequest, allowedPath bool) bool {
+	for _, role := range roles {
+		for _, rule := range role.Deny {
+			if matchesRPCRule(rule, req, false) {
+				return false
+			}
+		}
+	}
+	if allowedPath {
+		return true
+	}
+	for _, role := range roles {
+		for _, rule := range role.Allow {
+			if matchesRPCRule(rule, req, true) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesRPCRule returns whether rule matches req. The arguments and options
+// must all match for an Allow rule, and any of them for a Deny rule.
+func matchesRPCRule(rule config.RPCRule, req *rpcRequest, allow bool) bool {
+	if req.path != rule.Path && !strings.HasPrefix(req.path, strings.TrimSuffix(rule.Path, "/")+"/") {
+		return false
+	}
+	if len(rule.Methods) > 0 && !slices.ContainsFunc(rule.Methods, func(m string) bool {
+		return strings.EqualFold(m, req.method)
+	}) {
+		return false
+	}
+
+	if len(rule.Args) > 0 {
+		if allow {
+			if len(req.args) == 0 || req.bodyArgs || !matchesAll(rule.Args, req.args) {
+				return false
+			}
+		} else if !req.bodyArgs && !matchesAny(rule.Args, req.args) {
+			return false
+		}
+	}
+
+	if len(rule.Options) == 0 {
+		return true
+	}
+	for name, patterns := range rule.Options {
+		values := req.options[name]
+		if allow && (len(values) == 0 || !matchesAll(patterns, values)) {
+			return false
+		}
+		if !allow && matchesAny(patterns, values) {
+			return true
+		}
+	}
+	return allow
+}
+
+func matchesAll(patterns, values []string) bool {
+	for _, v := range values {
+		if !matchesAny(patterns, []string{v}) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(patterns, values []string) bool {
+	for _, v := range values {
+		if strings.HasPrefix(v, "/") {
+			v = path.Clean(v)
+		}
+		for _, p := range patterns {
+			if prefix, ok := strings.CutSuffix(p, "*"); ok {
+				if strings.HasPrefix(v, prefix) {
+					return true
+				}
+			} else if v == p {
+				return true
+			}
+		}
+	}
+	return false
+}
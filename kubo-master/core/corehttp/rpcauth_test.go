@@ -0,0 +1,170 @@
+package corehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	config "github.com/ipfs/kubo/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCRoles(t *testing.T) {
+	run := func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return nil }
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"cat": {
+				Arguments: []cmds.Argument{cmds.StringArg("ipfs-path", true, true, "")},
+				Run:       run,
+			},
+			"pin": {
+				Subcommands: map[string]*cmds.Command{
+					"add": {
+						Arguments: []cmds.Argument{cmds.StringArg("ipfs-path", true, true, "").EnableStdin()},
+						Run:       run,
+					},
+					"rm": {
+						Arguments: []cmds.Argument{cmds.StringArg("ipfs-path", true, true, "")},
+						Run:       run,
+					},
+				},
+			},
+			"files": {
+				Subcommands: map[string]*cmds.Command{
+					"ls": {
+						Arguments: []cmds.Argument{cmds.StringArg("path", false, false, "")},
+						Run:       run,
+					},
+					"cp": {
+						Arguments: []cmds.Argument{
+							cmds.StringArg("source", true, false, ""),
+							cmds.StringArg("dest", true, false, ""),
+						},
+						Run: run,
+					},
+				},
+			},
+			"name": {
+				Subcommands: map[string]*cmds.Command{
+					"publish": {
+						Options:   []cmds.Option{cmds.StringOption("key", "k", "")},
+						Arguments: []cmds.Argument{cmds.StringArg("ipfs-path", true, false, "")},
+						Run:       run,
+					},
+				},
+			},
+		},
+	}
+
+	authorizations, err := convertAuthorizationsMap(&config.API{Authorizations: map[string]*config.RPCAuthScope{
+		"ci": {
+			AuthSecret: "ci-token",
+			Roles:      []string{"pinner", "ci-files"},
+		},
+		"admin": {
+			AuthSecret:   "admin-token",
+			AllowedPaths: []string{"/api/v0"},
+			Roles:        []string{"no-secret"},
+		},
+	}, Roles: map[string]*config.RPCRole{
+		"pinner": {
+			Allow: []config.RPCRule{{Path: "/api/v0/pin/add", Methods: []string{"post"}}},
+		},
+		"ci-files": {
+			Allow: []config.RPCRule{
+				{Path: "/api/v0/files", Args: []string{"/ci", "/ci/*"}},
+				{Path: "/api/v0/name/publish", Options: map[string][]string{"key": {"ci-*"}}},
+				{Path: "/api/v0/openapi.json", Methods: []string{"get"}},
+			},
+		},
+		"no-secret": {
+			Deny: []config.RPCRule{
+				{Path: "/api/v0/files", Args: []string{"/secret", "/secret/*"}},
+				{Path: "/api/v0/pin/add"},
+			},
+		},
+	}})
+	require.NoError(t, err)
+
+	handler := withAuthSecrets(authorizations, nil, root, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		token   string
+		method  string
+		url     string
+		allowed bool
+	}{
+		{"ci-token", http.MethodPost, "/api/v0/version", true},
+		{"ci-token", http.MethodPost, "/api/v0/pin/add?arg=bafkqaaa", true},
+		{"ci-token", http.MethodGet, "/api/v0/pin/add?arg=bafkqaaa", false},
+		{"ci-token", http.MethodPost, "/api/v0/pin/rm?arg=bafkqaaa", false},
+		{"ci-token", http.MethodPost, "/api/v0/pin/addx", false},
+		{"ci-token", http.MethodPost, "/api/v0/files/ls?arg=/ci", true},
+		{"ci-token", http.MethodPost, "/api/v0/files/ls?arg=/ci/dir/", true},
+		{"ci-token", http.MethodPost, "/api/v0/files/ls", false},
+		{"ci-token", http.MethodPost, "/api/v0/files/ls?arg=/cix", false},
+		{"ci-token", http.MethodPost, "/api/v0/files/ls?arg=/ci/../secret", false},
+		{"ci-token", http.MethodPost, "/api/v0/files/cp?arg=/ci/a&arg=/ci/b", true},
+		{"ci-token", http.MethodPost, "/api/v0/files/cp?arg=/ci/a&arg=/other", false},
+		{"ci-token", http.MethodPost, "/api/v0/name/publish?arg=/ipfs/bafkqaaa&key=ci-site", true},
+		{"ci-token", http.MethodPost, "/api/v0/name/publish?arg=/ipfs/bafkqaaa&k=ci-site", true},
+		{"ci-token", http.MethodPost, "/api/v0/name/publish?arg=/ipfs/bafkqaaa&k=self", false},
+		{"ci-token", http.MethodPost, "/api/v0/name/publish?arg=/ipfs/bafkqaaa", false},
+		{"ci-token", http.MethodPost, "/api/v0/cat/bafkqaaa", false},
+		{"ci-token", http.MethodGet, "/api/v0/openapi.json", true},
+		{"ci-token", http.MethodPost, "/api/v0/openapi.json", false},
+		{"admin-token", http.MethodPost, "/api/v0/cat/bafkqaaa", true},
+		{"admin-token", http.MethodPost, "/api/v0/files/ls?arg=/ci", true},
+		{"admin-token", http.MethodPost, "/api/v0/files/ls?arg=/secret/x", false},
+		{"admin-token", http.MethodPost, "/api/v0/files/cp?arg=/ci/a&arg=/secret", false},
+		{"admin-token", http.MethodPost, "/api/v0/pin/add?arg=bafkqaaa", false},
+		{"admin-token", http.MethodPost, "/api/v0/unknown", true},
+		{"bad-token", http.MethodPost, "/api/v0/version", false},
+	} {
+		r := httptest.NewRequest(tc.method, tc.url, nil)
+		r.Header.Set("Authorization", "Bearer "+tc.token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if tc.allowed {
+			require.Equal(t, http.StatusOK, w.Code, "%s %s %s", tc.token, tc.method, tc.url)
+		} else {
+			require.Equal(t, http.StatusForbidden, w.Code, "%s %s %s", tc.token, tc.method, tc.url)
+		}
+	}
+}
+
+func TestRPCRolesBodyArgs(t *testing.T) {
+	root := &cmds.Command{
+		Subcommands: map[string]*cmds.Command{
+			"pin": {
+				Subcommands: map[string]*cmds.Command{
+					"add": {
+						Arguments: []cmds.Argument{cmds.StringArg("ipfs-path", true, true, "").EnableStdin()},
+						Run:       func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return nil },
+					},
+				},
+			},
+		},
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v0/pin/add?arg=bafkqaaa", strings.NewReader(""))
+	r.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	req, ok := parseRPCRequest(root, r)
+	require.True(t, ok)
+	require.True(t, req.bodyArgs)
+
+	// the arguments in the body cannot be checked
+	allow := config.RPCRule{Path: "/api/v0/pin/add", Args: []string{"bafkqaaa"}}
+	require.False(t, matchesRPCRule(allow, req, true))
+	deny := config.RPCRule{Path: "/api/v0/pin/add", Args: []string{"bafyforbidden"}}
+	require.True(t, matchesRPCRule(deny, req, false))
+}
+
+func TestRPCRolesUndefined(t *testing.T) {
+	_, err := convertAuthorizationsMap(&config.API{Authorizations: map[string]*config.RPCAuthScope{
+		"ci": {AuthSecret: "ci-token", Roles: []string{"missing"}},
+	}})
+	require.ErrorContains(t, err, `role "missing"`)
+}
@@ -0,0 +1,244 @@
+package corehttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	config "github.com/ipfs/kubo/config"
+)
+
+var (
+	// jwksMinRefresh bounds how often a JWKS is reloaded when a token is
+	// signed with a key it does not hold.
+	jwksMinRefresh = time.Minute
+	// jwksMaxAge is how long a JWKS fetched from a URL is used before being
+	// fetched again.
+	jwksMaxAge = time.Hour
+	// jwksHTTPClient fetches the JWKS given as URLs.
+	jwksHTTPClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// jwtAlgorithms are the signature algorithms accepted for tokens: only
+// asymmetric ones, as the keys are public.
+var jwtAlgorithms = []jose.SignatureAlgorithm{
+	jose.EdDSA,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+}
+
+// jwks is a JSON Web Key Set read from a file or fetched from a URL.
+type jwks struct {
+	source string
+
+	mu     sync.Mutex
+	keys   jose.JSONWebKeySet
+	loaded time.Time
+	// loading is set while the set is reloaded, outside of mu.
+	loading bool
+}
+
+func (k *jwks) isURL() bool {
+	return strings.HasPrefix(k.source, "https://")
+}
+
+func (k *jwks) load() (jose.JSONWebKeySet, error) {
+	var keys jose.JSONWebKeySet
+	var data []byte
+	if k.isURL() {
+		resp, err := jwksHTTPClient.Get(k.source)
+		if err != nil {
+			return keys, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return keys, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return keys, err
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(k.source)
+		if err != nil {
+			return keys, err
+		}
+	}
+
+	err := json.Unmarshal(data, &keys)
+	return keys, err
+}
+
+// get returns the verification keys of the set with the given key ID, or all
+// of them when kid is empty. The set is reloaded when it holds no such key or
+// is too old, at most once per jwksMinRefresh. While it is reloaded, the
+// other callers get the keys loaded before.
+func (k *jwks) get(kid string, now time.Time) []jose.JSONWebKey {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys := k.find(kid)
+	stale := k.loaded.IsZero() || (k.isURL() && now.Sub(k.loaded) > jwksMaxAge)
+	if (len(keys) == 0 || stale) && !k.loading && (k.loaded.IsZero() || now.Sub(k.loaded) >= jwksMinRefresh) {
+		k.loaded = now
+		k.loading = true
+		k.mu.Unlock()
+		set, err := k.load()
+		k.mu.Lock()
+		k.loading = false
+		if err != nil {
+			log.Errorf("loading JWKS %s: %s", k.source, err)
+			return keys
+		}
+		k.keys = set
+		keys = k.find(kid)
+	}
+	return keys
+}
+
+func (k *jwks) find(kid string) []jose.JSONWebKey {
+	keys := k.keys.Keys
+	if kid != "" {
+		keys = k.keys.Key(kid)
+	}
+	return slices.DeleteFunc(slices.Clone(keys), func(key jose.JSONWebKey) bool {
+		return key.Use == "enc"
+	})
+}
+
+// rpcJWTVerifier authenticates the JSON Web Tokens of a user of
+// API.Authorizations.
+type rpcJWTVerifier struct {
+	user     string
+	scope    config.RPCAuthScope
+	roles    []*config.RPCRole
+	allRoles map[string]*config.RPCRole
+	keys     *jwks
+}
+
+func newRPCJWTVerifiers(api *config.API) ([]*rpcJWTVerifier, error) {
+	var verifiers []*rpcJWTVerifier
+	for user, scope := range api.Authorizations {
+		if scope == nil || scope.JWT == nil {
+			continue
+		}
+		roles, err := api.AuthorizationRoles(user)
+		if err != nil {
+			return nil, err
+		}
+		v := &rpcJWTVerifier{
+			user:     user,
+			scope:    *scope,
+			roles:    roles,
+			allRoles: api.Roles,
+			keys:     &jwks{source: scope.JWT.JWKS},
+		}
+		// load the keys now, to report errors early
+		v.keys.get("", time.Now())
+		verifiers = append(verifiers, v)
+	}
+	return verifiers, nil
+}
+
+var errJWTSignature = errors.New("token is not signed by a key of the JWKS")
+
+// verify checks token, and returns the authorizations it grants.
+func (v *rpcJWTVerifier) verify(token string, now time.Time) (rpcAuthScopeWithUser, error) {
+	tok, err := jwt.ParseSigned(token, jwtAlgorithms)
+	if err != nil {
+		return rpcAuthScopeWithUser{}, err
+	}
+
+	var claims jwt.Claims
+	var custom map[string]interface{}
+	verified := false
+	for _, key := range v.keys.get(tok.Headers[0].KeyID, now) {
+		if tok.Claims(key.Public(), &claims, &custom) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return rpcAuthScopeWithUser{}, errJWTSignature
+	}
+
+	if claims.Expiry == nil {
+		return rpcAuthScopeWithUser{}, errors.New("token has no expiry")
+	}
+	err = claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:      v.scope.JWT.Issuer,
+		AnyAudience: jwt.Audience{v.scope.JWT.Audience},
+		Time:        now,
+	}, v.scope.JWT.Leeway.WithDefault(config.DefaultRPCJWTLeeway))
+	if err != nil {
+		return rpcAuthScopeWithUser{}, err
+	}
+
+	auth := rpcAuthScopeWithUser{
+		RPCAuthScope: v.scope,
+		User:         v.user,
+		Subject:      claims.Subject,
+		Roles:        slices.Clone(v.roles),
+	}
+	auth.AllowedPaths = slices.Clone(v.scope.AllowedPaths)
+	if name := v.scope.JWT.PathsClaim.WithDefault(""); name != "" {
+		auth.AllowedPaths = append(auth.AllowedPaths, claimStrings(custom[name])...)
+	}
+	if name := v.scope.JWT.RolesClaim.WithDefault(""); name != "" {
+		for _, role := range claimStrings(custom[name]) {
+			// roles unknown to this node may be meant for other services
+			if r := v.allRoles[role]; r != nil {
+				auth.Roles = append(auth.Roles, r)
+			}
+		}
+	}
+	return auth, nil
+}
+
+// claimStrings returns the values of a claim holding either an array of
+// strings, or space-separated values like the "scope" claim.
+func claimStrings(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []interface{}:
+		values := make([]string, 0, len(c))
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// verifyRPCJWT returns the authorizations granted by the bearer token of the
+// authorization header, if it is a JSON Web Token accepted by a verifier.
+func verifyRPCJWT(verifiers []*rpcJWTVerifier, header string) (rpcAuthScopeWithUser, bool) {
+	if len(verifiers) == 0 || len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return rpcAuthScopeWithUser{}, false
+	}
+	token := strings.TrimSpace(header[7:])
+	now := time.Now()
+	for _, v := range verifiers {
+		auth, err := v.verify(token, now)
+		if err == nil {
+			return auth, true
+		}
+		log.Debugf("RPC token not accepted for user %q: %s", v.user, err)
+	}
+	return rpcAuthScopeWithUser{}, false
+}
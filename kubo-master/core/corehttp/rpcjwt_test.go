@@ -0,0 +1,230 @@
+package corehttp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	config "github.com/ipfs/kubo/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testJWTKey struct {
+	kid string
+	sk  ed25519.PrivateKey
+}
+
+func newTestJWTKey(t *testing.T, kid string) testJWTKey {
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return testJWTKey{kid: kid, sk: sk}
+}
+
+func (k testJWTKey) jwk() jose.JSONWebKey {
+	return jose.JSONWebKey{Key: k.sk.Public(), KeyID: k.kid, Algorithm: string(jose.EdDSA), Use: "sig"}
+}
+
+func (k testJWTKey) sign(t *testing.T, claims jwt.Claims, custom map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.EdDSA, Key: k.sk},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", k.kid))
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).Claims(custom).Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+func writeJWKS(t *testing.T, path string, keys ...testJWTKey) {
+	set := jose.JSONWebKeySet{}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, k.jwk())
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestRPCJWT(t *testing.T) {
+	key := newTestJWTKey(t, "k1")
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, key)
+
+	deployer := &config.RPCRole{Allow: []config.RPCRule{{Path: "/api/v0/pin/add"}}}
+	api := &config.API{
+		Authorizations: map[string]*config.RPCAuthScope{
+			"drive": {
+				AllowedPaths: []string{"/api/v0/id"},
+				JWT: &config.RPCJWT{
+					JWKS:       jwksPath,
+					Issuer:     "https://idp.example.com",
+					Audience:   "kubo",
+					PathsClaim: config.NewOptionalString("kubo_paths"),
+					RolesClaim: config.NewOptionalString("kubo_roles"),
+				},
+			},
+		},
+		Roles: map[string]*config.RPCRole{"deployer": deployer},
+	}
+	require.NoError(t, api.CheckAuthorizations())
+	verifiers, err := newRPCJWTVerifiers(api)
+	require.NoError(t, err)
+	require.Len(t, verifiers, 1)
+	v := verifiers[0]
+
+	now := time.Now()
+	valid := jwt.Claims{
+		Issuer:   "https://idp.example.com",
+		Subject:  "alice",
+		Audience: jwt.Audience{"kubo"},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+
+	t.Run("claims grant paths and roles", func(t *testing.T) {
+		auth, err := v.verify(key.sign(t, valid, map[string]interface{}{
+			"kubo_paths": []string{"/api/v0/files"},
+			"kubo_roles": "deployer unknown",
+		}), now)
+		require.NoError(t, err)
+		require.Equal(t, "drive", auth.User)
+		require.Equal(t, []string{"/api/v0/id", "/api/v0/files"}, auth.AllowedPaths)
+		require.Equal(t, []*config.RPCRole{deployer}, auth.Roles)
+
+		// the paths of the config are not changed
+		require.Equal(t, []string{"/api/v0/id"}, api.Authorizations["drive"].AllowedPaths)
+	})
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		expired := valid
+		expired.Expiry = jwt.NewNumericDate(now.Add(-2 * time.Minute))
+		noExpiry := valid
+		noExpiry.Expiry = nil
+		wrongIssuer := valid
+		wrongIssuer.Issuer = "https://other.example.com"
+		wrongAudience := valid
+		wrongAudience.Audience = jwt.Audience{"other"}
+
+		for name, token := range map[string]string{
+			"expired":        key.sign(t, expired, nil),
+			"no expiry":      key.sign(t, noExpiry, nil),
+			"wrong issuer":   key.sign(t, wrongIssuer, nil),
+			"wrong audience": key.sign(t, wrongAudience, nil),
+			"unknown key":    newTestJWTKey(t, "k1").sign(t, valid, nil),
+			"garbage":        "not.a.token",
+		} {
+			_, err := v.verify(token, now)
+			require.Error(t, err, name)
+		}
+
+		// within the leeway
+		expired.Expiry = jwt.NewNumericDate(now.Add(-30 * time.Second))
+		_, err := v.verify(key.sign(t, expired, nil), now)
+		require.NoError(t, err)
+	})
+
+	t.Run("symmetric algorithms are rejected", func(t *testing.T) {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("0123456789abcdef0123456789abcdef")}, nil)
+		require.NoError(t, err)
+		token, err := jwt.Signed(signer).Claims(valid).Serialize()
+		require.NoError(t, err)
+		_, err = v.verify(token, now)
+		require.Error(t, err)
+	})
+
+	t.Run("rotated keys are reloaded", func(t *testing.T) {
+		rotated := newTestJWTKey(t, "k2")
+		writeJWKS(t, jwksPath, key, rotated)
+		token := rotated.sign(t, valid, nil)
+
+		// the set is not reloaded more than once per jwksMinRefresh
+		_, err := v.verify(token, v.keys.loaded.Add(jwksMinRefresh/2))
+		require.ErrorIs(t, err, errJWTSignature)
+		_, err = v.verify(token, now.Add(jwksMinRefresh))
+		require.NoError(t, err)
+	})
+
+	t.Run("bearer header", func(t *testing.T) {
+		token := key.sign(t, valid, nil)
+		_, ok := verifyRPCJWT(verifiers, "Bearer "+token)
+		require.True(t, ok)
+		_, ok = verifyRPCJWT(verifiers, "Basic "+token)
+		require.False(t, ok)
+		_, ok = verifyRPCJWT(nil, "Bearer "+token)
+		require.False(t, ok)
+	})
+}
+
+func TestRPCJWTURL(t *testing.T) {
+	key := newTestJWTKey(t, "k1")
+	var requests atomic.Int32
+	var blocked chan struct{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if blocked != nil {
+			<-blocked
+		}
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.jwk()}})
+	}))
+	defer srv.Close()
+
+	defaultClient := jwksHTTPClient
+	jwksHTTPClient = srv.Client()
+	defer func() { jwksHTTPClient = defaultClient }()
+
+	verifiers, err := newRPCJWTVerifiers(&config.API{
+		Authorizations: map[string]*config.RPCAuthScope{
+			"drive": {
+				AllowedPaths: []string{"/api/v0/id"},
+				JWT:          &config.RPCJWT{JWKS: srv.URL, Issuer: "idp", Audience: "kubo"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, requests.Load())
+
+	now := time.Now()
+	token := key.sign(t, jwt.Claims{
+		Issuer:   "idp",
+		Audience: jwt.Audience{"kubo"},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}, nil)
+	_, err = verifiers[0].verify(token, now)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, requests.Load())
+
+	// the set is fetched again once too old
+	_, err = verifiers[0].verify(token, now.Add(jwksMaxAge+time.Minute))
+	require.Error(t, err) // the token expired meanwhile
+	require.EqualValues(t, 2, requests.Load())
+
+	// the cached keys are used while the set is fetched
+	blocked = make(chan struct{})
+	later := now.Add(2*jwksMaxAge + 2*time.Minute)
+	done := make(chan []jose.JSONWebKey)
+	go func() { done <- verifiers[0].keys.get("k2", later) }()
+	require.Eventually(t, func() bool { return requests.Load() == 3 }, 10*time.Second, 10*time.Millisecond)
+	require.Len(t, verifiers[0].keys.get("k1", later), 1)
+	require.EqualValues(t, 3, requests.Load())
+	close(blocked)
+	require.Empty(t, <-done)
+}
+
+func TestRPCJWTConfig(t *testing.T) {
+	for name, scope := range map[string]*config.RPCAuthScope{
+		"no JWKS":     {JWT: &config.RPCJWT{Issuer: "idp", Audience: "kubo"}},
+		"http JWKS":   {JWT: &config.RPCJWT{JWKS: "http://idp/jwks", Issuer: "idp", Audience: "kubo"}},
+		"no issuer":   {JWT: &config.RPCJWT{JWKS: "jwks.json", Audience: "kubo"}},
+		"no audience": {JWT: &config.RPCJWT{JWKS: "jwks.json", Issuer: "idp"}},
+		"AuthSecret":  {AuthSecret: "secret", JWT: &config.RPCJWT{JWKS: "jwks.json", Issuer: "idp", Audience: "kubo"}},
+	} {
+		api := &config.API{Authorizations: map[string]*config.RPCAuthScope{"user": scope}}
+		require.Error(t, api.CheckAuthorizations(), name)
+	}
+}
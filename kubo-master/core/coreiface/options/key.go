@@ -10,6 +10,7 @@ const (
 type KeyGenerateSettings struct {
 	Algorithm string
 	Size      int
+	Seed      []byte
 }
 
 type KeyRenameSettings struct {
@@ -79,6 +80,16 @@ func (keyOpts) Size(size int) KeyGenerateOption {
 	}
 }
 
+// Seed is an option for Key.Generate which specifies the 32 bytes seed the
+// key is derived from, making the generation deterministic. It is only
+// supported by options.Ed25519Key. Default is nil (random key).
+func (keyOpts) Seed(seed []byte) KeyGenerateOption {
+	return func(settings *KeyGenerateSettings) error {
+		settings.Seed = seed
+		return nil
+	}
+}
+
 // Force is an option for Key.Rename which specifies whether to allow to
 // replace existing keys.
 func (keyOpts) Force(force bool) KeyRenameOption {
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"context"
 	"strings"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/ipfs/go-cid"
 	iface "github.com/ipfs/kubo/core/coreiface"
 	opt "github.com/ipfs/kubo/core/coreiface/options"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
 	mbase "github.com/multiformats/go-multibase"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +31,7 @@ func (tp *TestSuite) TestKey(t *testing.T) {
 	t.Run("TestGenerate", tp.TestGenerate)
 	t.Run("TestGenerateSize", tp.TestGenerateSize)
 	t.Run("TestGenerateType", tp.TestGenerateType)
+	t.Run("TestGenerateSeed", tp.TestGenerateSeed)
 	t.Run("TestGenerateExisting", tp.TestGenerateExisting)
 	t.Run("TestList", tp.TestList)
 	t.Run("TestRename", tp.TestRename)
@@ -142,6 +145,31 @@ func (tp *TestSuite) TestGenerateType(t *testing.T) {
 	require.True(t, strings.HasPrefix(k.Path().String(), "/ipns/12"))
 }
 
+func (tp *TestSuite) TestGenerateSeed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	api, err := tp.makeAPI(t, ctx)
+	require.NoError(t, err)
+
+	seed := bytes.Repeat([]byte{42}, 32)
+	sk, _, err := crypto.GenerateEd25519Key(bytes.NewReader(seed))
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(sk)
+	require.NoError(t, err)
+
+	k1, err := api.Key().Generate(ctx, "foo", opt.Key.Type(opt.Ed25519Key), opt.Key.Seed(seed))
+	require.NoError(t, err)
+	require.Equal(t, id, k1.ID())
+
+	k2, err := api.Key().Generate(ctx, "bar", opt.Key.Type(opt.Ed25519Key), opt.Key.Seed(seed))
+	require.NoError(t, err)
+	require.Equal(t, id, k2.ID())
+
+	_, err = api.Key().Generate(ctx, "baz", opt.Key.Type(opt.RSAKey), opt.Key.Seed(seed))
+	require.Error(t, err)
+}
+
 func (tp *TestSuite) TestGenerateExisting(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -0,0 +1,197 @@
+package corerepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/kubo/core"
+
+	bserv "github.com/ipfs/boxo/blockservice"
+	bstore "github.com/ipfs/boxo/blockstore"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/mfs"
+	pin "github.com/ipfs/boxo/pinning/pinner"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// Kinds of roots reported by DiskUsage.
+const (
+	DuRootRecursive = "recursive"
+	DuRootDirect    = "direct"
+	DuRootMFS       = "mfs"
+)
+
+// DuRoot is a DAG root that keeps blocks alive in the blockstore, either
+// because it is pinned or because it is referenced from MFS.
+type DuRoot struct {
+	Cid  cid.Cid
+	Type string
+	// Name is the pin name for pins, and the MFS path for MFS entries.
+	Name string
+}
+
+// RootUsage describes how many bytes of the blockstore are held by a single
+// root. Unique bytes are held by no other root and would be freed by
+// "repo gc" if the root went away. Shared bytes are also reachable from at
+// least one other root.
+type RootUsage struct {
+	DuRoot
+	NumBlocks     uint64
+	TotalSize     uint64
+	UniqueSize    uint64
+	SharedSize    uint64
+	MissingBlocks uint64
+}
+
+// duBlock tracks the holders of a single block while walking the roots.
+type duBlock struct {
+	size    uint64
+	holders int
+	missing bool
+	// owner is the index of the first root that reached the block, and
+	// the only one when holders is 1.
+	owner int
+	// last is the index of the last root that reached the block, and is
+	// used to visit each block at most once per root.
+	last int
+}
+
+// DuRoots returns the roots that "repo gc" preserves: recursive and direct
+// pins, and the top-level entries of the MFS root.
+func DuRoots(ctx context.Context, pinning pin.Pinner, filesRoot *mfs.Root) ([]DuRoot, error) {
+	var roots []DuRoot
+
+	for p := range pinning.RecursiveKeys(ctx, true) {
+		if p.Err != nil {
+			return nil, p.Err
+		}
+		roots = append(roots, DuRoot{Cid: p.Pin.Key, Type: DuRootRecursive, Name: p.Pin.Name})
+	}
+	for p := range pinning.DirectKeys(ctx, true) {
+		if p.Err != nil {
+			return nil, p.Err
+		}
+		roots = append(roots, DuRoot{Cid: p.Pin.Key, Type: DuRootDirect, Name: p.Pin.Name})
+	}
+
+	if filesRoot == nil {
+		return roots, nil
+	}
+	err := filesRoot.GetDirectory().ForEachEntry(ctx, func(nl mfs.NodeListing) error {
+		c, err := cid.Decode(nl.Hash)
+		if err != nil {
+			return fmt.Errorf("invalid cid for MFS entry %q: %w", nl.Name, err)
+		}
+		roots = append(roots, DuRoot{Cid: c, Type: DuRootMFS, Name: "/" + nl.Name})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// DiskUsage computes, for every root returned by DuRoots, the number of bytes
+// it holds alone and the number of bytes it shares with other roots. Only
+// local blocks are considered: missing blocks are counted but never fetched.
+func DiskUsage(ctx context.Context, n *core.IpfsNode) ([]RootUsage, error) {
+	unlocker := n.Blockstore.PinLock(ctx)
+	defer unlocker.Unlock(ctx)
+
+	roots, err := DuRoots(ctx, n.Pinning, n.FilesRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	bs := n.Blockstore
+	ng := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+	return Usage(ctx, roots, dag.GetLinksWithDAG(ng), bs)
+}
+
+// Usage walks each root with getLinks and attributes the size of every block
+// reported by bs to the roots that reach it. Blocks shared between roots are
+// only walked once per root. Direct roots only reach their root block.
+func Usage(ctx context.Context, roots []DuRoot, getLinks dag.GetLinks, bs bstore.Blockstore) ([]RootUsage, error) {
+	// The blockstore is keyed by multihash, so the same block reached
+	// through CIDs with different codecs or versions is counted once.
+	blocks := make(map[string]*duBlock)
+	usage := make([]RootUsage, len(roots))
+
+	for i, root := range roots {
+		ru := &usage[i]
+		ru.DuRoot = root
+
+		var walkErr error
+		visit := func(c cid.Cid) bool {
+			k := string(c.Hash())
+			if b, ok := blocks[k]; ok {
+				if b.last == i {
+					return false
+				}
+				b.last = i
+				b.holders++
+				if b.missing {
+					ru.MissingBlocks++
+				}
+				ru.NumBlocks++
+				ru.TotalSize += b.size
+				return true
+			}
+
+			var size uint64
+			var missing bool
+			if c.Prefix().MhType != mh.IDENTITY {
+				s, err := bs.GetSize(ctx, c)
+				switch {
+				case ipld.IsNotFound(err):
+					missing = true
+					ru.MissingBlocks++
+				case err != nil:
+					walkErr = err
+					return false
+				default:
+					size = uint64(s)
+				}
+			}
+
+			blocks[k] = &duBlock{size: size, holders: 1, missing: missing, owner: i, last: i}
+			ru.NumBlocks++
+			ru.TotalSize += size
+			return true
+		}
+
+		// Missing blocks were already accounted for in visit, so they
+		// simply end the walk along that branch.
+		tolerantGetLinks := func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+			links, err := getLinks(ctx, c)
+			if ipld.IsNotFound(err) {
+				return nil, nil
+			}
+			return links, err
+		}
+
+		// Direct pins only keep their root block.
+		if root.Type == DuRootDirect {
+			visit(root.Cid)
+		} else if err := dag.Walk(ctx, tolerantGetLinks, root.Cid, visit); err != nil {
+			return nil, fmt.Errorf("walking %s: %w", root.Cid, err)
+		}
+		if walkErr != nil {
+			return nil, walkErr
+		}
+	}
+
+	for _, b := range blocks {
+		if b.holders == 1 {
+			usage[b.owner].UniqueSize += b.size
+		}
+	}
+	for i := range usage {
+		usage[i].SharedSize = usage[i].TotalSize - usage[i].UniqueSize
+	}
+
+	return usage, nil
+}
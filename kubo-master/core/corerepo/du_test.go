@@ -0,0 +1,100 @@
+package corerepo
+
+import (
+	"context"
+	"testing"
+
+	bserv "github.com/ipfs/boxo/blockservice"
+	bstore "github.com/ipfs/boxo/blockstore"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+func TestUsage(t *testing.T) {
+	ctx := context.Background()
+	bs := bstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+
+	add := func(nd ipld.Node) ipld.Node {
+		t.Helper()
+		if err := dserv.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+		return nd
+	}
+	size := func(nd ipld.Node) uint64 {
+		return uint64(len(nd.RawData()))
+	}
+
+	shared := add(dag.NewRawNode([]byte("shared between a and b")))
+	onlyA := add(dag.NewRawNode([]byte("only in a")))
+	onlyB := add(dag.NewRawNode([]byte("only in b, twice")))
+
+	a := new(dag.ProtoNode)
+	a.AddNodeLink("shared", shared)
+	a.AddNodeLink("only", onlyA)
+	add(a)
+
+	b := new(dag.ProtoNode)
+	b.AddNodeLink("shared", shared)
+	b.AddNodeLink("only", onlyB)
+	b.AddNodeLink("again", onlyB)
+	add(b)
+
+	// c links to a block that is not in the blockstore.
+	missing := dag.NewRawNode([]byte("not stored"))
+	c := new(dag.ProtoNode)
+	c.AddNodeLink("missing", missing)
+	add(c)
+
+	// d also links to the missing block.
+	d := new(dag.ProtoNode)
+	d.AddNodeLink("missing", missing)
+	d.SetData([]byte("d"))
+	add(d)
+
+	roots := []DuRoot{
+		{Cid: a.Cid(), Type: DuRootRecursive},
+		{Cid: b.Cid(), Type: DuRootRecursive},
+		{Cid: c.Cid(), Type: DuRootMFS, Name: "/c"},
+		{Cid: d.Cid(), Type: DuRootRecursive},
+		// a direct pin only holds its root block, not shared with b
+		{Cid: b.Cid(), Type: DuRootDirect},
+	}
+	usage, err := Usage(ctx, roots, dag.GetLinksWithDAG(dserv), bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(usage) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(usage))
+	}
+
+	ua := usage[0]
+	if ua.UniqueSize != size(a)+size(onlyA) || ua.SharedSize != size(shared) || ua.NumBlocks != 3 {
+		t.Errorf("unexpected usage for a: %+v", ua)
+	}
+
+	ub := usage[1]
+	if ub.UniqueSize != size(onlyB) || ub.SharedSize != size(b)+size(shared) || ub.NumBlocks != 3 {
+		t.Errorf("unexpected usage for b: %+v", ub)
+	}
+
+	uc := usage[2]
+	if uc.UniqueSize != size(c) || uc.SharedSize != 0 || uc.MissingBlocks != 1 || uc.Name != "/c" {
+		t.Errorf("unexpected usage for c: %+v", uc)
+	}
+
+	ud := usage[3]
+	if ud.UniqueSize != size(d) || ud.MissingBlocks != 1 || ud.NumBlocks != 2 {
+		t.Errorf("unexpected usage for d: %+v", ud)
+	}
+
+	ubDirect := usage[4]
+	if ubDirect.UniqueSize != 0 || ubDirect.SharedSize != size(b) || ubDirect.NumBlocks != 1 {
+		t.Errorf("unexpected usage for direct b: %+v", ubDirect)
+	}
+}
@@ -0,0 +1,406 @@
+package corerepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core"
+	coreiface "github.com/ipfs/kubo/core/coreiface"
+	"github.com/ipfs/kubo/core/coreiface/options"
+
+	bserv "github.com/ipfs/boxo/blockservice"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/files"
+	filestore "github.com/ipfs/boxo/filestore"
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	pin "github.com/ipfs/boxo/pinning/pinner"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// FilestoreRoot returns the directory the paths recorded in the filestore
+// are relative to.
+func FilestoreRoot(n *core.IpfsNode) string {
+	return filepath.Dir(n.Repo.Path())
+}
+
+// FilestoreAbsPath returns the absolute path of the file backing a
+// filestore entry.
+func FilestoreAbsPath(n *core.IpfsNode, r *filestore.ListRes) string {
+	return filepath.Join(FilestoreRoot(n), filepath.FromSlash(r.FilePath))
+}
+
+// FilestoreFileEntries returns the filestore entries backed by the file at
+// the given absolute path.
+func FilestoreFileEntries(ctx context.Context, n *core.IpfsNode, path string) ([]*filestore.ListRes, error) {
+	if n.Filestore == nil {
+		return nil, filestore.ErrFilestoreNotEnabled
+	}
+
+	next, err := filestore.ListAll(ctx, n.Filestore, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*filestore.ListRes
+	for r := next(ctx); r != nil; r = next(ctx) {
+		if r.Status == filestore.StatusOtherError {
+			return nil, errors.New(r.ErrorMsg)
+		}
+		if FilestoreAbsPath(n, r) == path {
+			entries = append(entries, r)
+		}
+	}
+	return entries, ctx.Err()
+}
+
+// FilestoreFiles returns the absolute paths of all the files referenced by
+// the filestore.
+func FilestoreFiles(ctx context.Context, n *core.IpfsNode) (map[string]struct{}, error) {
+	if n.Filestore == nil {
+		return nil, filestore.ErrFilestoreNotEnabled
+	}
+
+	next, err := filestore.ListAll(ctx, n.Filestore, false)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]struct{})
+	for r := next(ctx); r != nil; r = next(ctx) {
+		if r.Status == filestore.StatusOtherError {
+			return nil, errors.New(r.ErrorMsg)
+		}
+		paths[FilestoreAbsPath(n, r)] = struct{}{}
+	}
+	return paths, ctx.Err()
+}
+
+// reindexMu serializes the reindexing of files, which read and update the
+// recursive pins: the pin lock only keeps the garbage collector out.
+var reindexMu sync.Mutex
+
+// PinUpdate records a recursive pin that was moved from one root to another,
+// because it contained the previous version of the files at Paths.
+type PinUpdate struct {
+	From  cid.Cid
+	To    cid.Cid
+	Paths []string
+}
+
+// FilestoreReindex re-adds the files at the given absolute paths to the
+// filestore after their content changed, and updates every recursive pin that
+// contained the previous version of one of the files, whether as its root or
+// nested in a UnixFS directory. Stale filestore entries for the files are
+// removed.
+//
+// Files that changed together must be reindexed in the same call: an updated
+// pin is only stored once all its blocks can be read, which the old blocks of
+// the other changed files cannot. Calls are serialized.
+func FilestoreReindex(ctx context.Context, n *core.IpfsNode, api coreiface.CoreAPI, paths ...string) ([]PinUpdate, error) {
+	reindexMu.Lock()
+	defer reindexMu.Unlock()
+
+	entries := make(map[string][]*filestore.ListRes, len(paths))
+	oldLeaves := make(map[string]string)
+	for _, path := range paths {
+		if _, ok := entries[path]; ok {
+			continue
+		}
+		es, err := FilestoreFileEntries(ctx, n, path)
+		if err != nil {
+			return nil, err
+		}
+		if len(es) == 0 {
+			continue
+		}
+		entries[path] = es
+		for _, e := range es {
+			oldLeaves[string(e.Key.Hash())] = path
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	unlocker := n.Blockstore.PinLock(ctx)
+	defer unlocker.Unlock(ctx)
+
+	dserv := dag.NewDAGService(bserv.New(n.Blockstore, offline.Exchange(n.Blockstore)))
+	rw := &fileRewriter{
+		ctx:       ctx,
+		dserv:     dserv,
+		oldLeaves: oldLeaves,
+		newRoots:  make(map[string]cid.Cid, len(entries)),
+		files:     make(map[cid.Cid]string),
+		contains:  make(map[cid.Cid]bool),
+	}
+
+	var pinned []pin.Pinned
+	for p := range n.Pinning.RecursiveKeys(ctx, true) {
+		if p.Err != nil {
+			return nil, p.Err
+		}
+		has, err := rw.hasFile(p.Pin.Key)
+		if err != nil {
+			return nil, err
+		}
+		if has {
+			pinned = append(pinned, p.Pin)
+		}
+	}
+
+	for _, path := range paths {
+		if _, ok := entries[path]; !ok || rw.newRoots[path].Defined() {
+			continue
+		}
+		var prefix *cid.Prefix
+		for _, p := range pinned {
+			if old := rw.firstFileRoot(p.Key, path); old.Defined() {
+				p := old.Prefix()
+				prefix = &p
+				break
+			}
+		}
+
+		newRoot, err := filestoreAdd(ctx, n, api, path, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rw.newRoots[path] = newRoot
+	}
+
+	// Pinner.Update cannot be used here: it diffs the old and the new DAG,
+	// and the old leaves can no longer be read from the changed files.
+	var updates []PinUpdate
+	for _, p := range pinned {
+		to, err := rw.rewrite(p.Key)
+		if err != nil {
+			return updates, err
+		}
+		if to.Equals(p.Key) {
+			continue
+		}
+		nd, err := dserv.Get(ctx, to)
+		if err != nil {
+			return updates, err
+		}
+		if err := n.Pinning.Pin(ctx, nd, true, p.Name); err != nil {
+			return updates, err
+		}
+		if err := n.Pinning.Unpin(ctx, p.Key, true); err != nil {
+			return updates, err
+		}
+		u := PinUpdate{From: p.Key, To: to}
+		for _, path := range paths {
+			if rw.firstFileRoot(p.Key, path).Defined() && !slices.Contains(u.Paths, path) {
+				u.Paths = append(u.Paths, path)
+			}
+		}
+		updates = append(updates, u)
+	}
+	if err := n.Pinning.Flush(ctx); err != nil {
+		return updates, err
+	}
+
+	for _, es := range entries {
+		for _, e := range es {
+			if r := filestore.Verify(ctx, n.Filestore, e.Key); r.Status != filestore.StatusOk {
+				if err := n.Filestore.FileManager().DeleteBlock(ctx, e.Key); err != nil {
+					return updates, err
+				}
+			}
+		}
+	}
+
+	return updates, nil
+}
+
+// filestoreAdd adds the file at path to the filestore without copying it,
+// with the CID version and hash function of prefix when it is set.
+func filestoreAdd(ctx context.Context, n *core.IpfsNode, api coreiface.CoreAPI, path string, prefix *cid.Prefix) (cid.Cid, error) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if st.IsDir() {
+		return cid.Undef, fmt.Errorf("%s is a directory", path)
+	}
+	f, err := files.NewSerialFile(path, false, st)
+	if err != nil {
+		return cid.Undef, err
+	}
+	defer f.Close()
+
+	opts := []options.UnixfsAddOption{
+		options.Unixfs.Nocopy(true),
+		options.Unixfs.RawLeaves(true),
+		options.Unixfs.Pin(false),
+		options.Unixfs.Chunker(cfg.Import.UnixFSChunker.WithDefault(config.DefaultUnixFSChunker)),
+	}
+	if prefix != nil {
+		opts = append(opts,
+			options.Unixfs.CidVersion(int(prefix.Version)),
+			options.Unixfs.Hash(prefix.MhType),
+		)
+	}
+
+	p, err := api.Unixfs().Add(ctx, f, opts...)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return p.RootCid(), nil
+}
+
+// fileRewriter finds the UnixFS file DAGs built from the filestore leaves of
+// changed files, and replaces them with the new roots of the files in the
+// DAGs that contain them.
+type fileRewriter struct {
+	ctx   context.Context
+	dserv ipld.DAGService
+	// oldLeaves maps the multihashes of the old leaves to their file.
+	oldLeaves map[string]string
+	newRoots  map[string]cid.Cid
+
+	// files holds the file of the nodes whose whole DAG is made of the old
+	// leaves of that file.
+	files map[cid.Cid]string
+	// contains is true for nodes that reach at least one of oldLeaves.
+	contains map[cid.Cid]bool
+}
+
+// hasFile reports whether the DAG under c contains any of the old leaves.
+func (rw *fileRewriter) hasFile(c cid.Cid) (bool, error) {
+	if has, ok := rw.contains[c]; ok {
+		return has, nil
+	}
+
+	if path, ok := rw.oldLeaves[string(c.Hash())]; ok {
+		rw.contains[c] = true
+		rw.files[c] = path
+		return true, nil
+	}
+
+	nd, err := rw.dserv.Get(rw.ctx, c)
+	if err != nil {
+		if rw.ctx.Err() != nil {
+			return false, rw.ctx.Err()
+		}
+		// Missing blocks and broken references to other files cannot
+		// contain the files we are looking for.
+		rw.contains[c] = false
+		return false, nil
+	}
+
+	has := false
+	var file string
+	isFile := len(nd.Links()) > 0
+	for i, l := range nd.Links() {
+		childHas, err := rw.hasFile(l.Cid)
+		if err != nil {
+			return false, err
+		}
+		has = has || childHas
+		if i == 0 {
+			file = rw.files[l.Cid]
+		}
+		isFile = isFile && file != "" && rw.files[l.Cid] == file
+	}
+	if isFile {
+		fsn, err := ft.ExtractFSNode(nd)
+		isFile = err == nil && fsn.Type() == ft.TFile
+	}
+
+	rw.contains[c] = has
+	if isFile {
+		rw.files[c] = file
+	}
+	return has, nil
+}
+
+// firstFileRoot returns the topmost node of the file at path found under c,
+// if any.
+func (rw *fileRewriter) firstFileRoot(c cid.Cid, path string) cid.Cid {
+	if file, ok := rw.files[c]; ok {
+		if file == path {
+			return c
+		}
+		return cid.Undef
+	}
+	if !rw.contains[c] {
+		return cid.Undef
+	}
+	nd, err := rw.dserv.Get(rw.ctx, c)
+	if err != nil {
+		return cid.Undef
+	}
+	for _, l := range nd.Links() {
+		if f := rw.firstFileRoot(l.Cid, path); f.Defined() {
+			return f
+		}
+	}
+	return cid.Undef
+}
+
+// rewrite returns the root of the DAG under c with every file made of the
+// old leaves of a file replaced by its new root. Only dag-pb nodes can be
+// rewritten.
+func (rw *fileRewriter) rewrite(c cid.Cid) (cid.Cid, error) {
+	if file, ok := rw.files[c]; ok {
+		return rw.newRoots[file], nil
+	}
+	if !rw.contains[c] {
+		return c, nil
+	}
+
+	nd, err := rw.dserv.Get(rw.ctx, c)
+	if err != nil {
+		return cid.Undef, err
+	}
+	pbnd, ok := nd.(*dag.ProtoNode)
+	if !ok {
+		return cid.Undef, fmt.Errorf("cannot update %s: only dag-pb nodes can be rewritten", c)
+	}
+
+	links := pbnd.Links()
+	for i, l := range links {
+		to, err := rw.rewrite(l.Cid)
+		if err != nil {
+			return cid.Undef, err
+		}
+		if to.Equals(l.Cid) {
+			continue
+		}
+		child, err := rw.dserv.Get(rw.ctx, to)
+		if err != nil {
+			return cid.Undef, err
+		}
+		size, err := child.Size()
+		if err != nil {
+			return cid.Undef, err
+		}
+		links[i] = &ipld.Link{Name: l.Name, Size: size, Cid: to}
+	}
+
+	pbnd = pbnd.Copy().(*dag.ProtoNode)
+	if err := pbnd.SetLinks(links); err != nil {
+		return cid.Undef, err
+	}
+
+	if err := rw.dserv.Add(rw.ctx, pbnd); err != nil {
+		return cid.Undef, err
+	}
+	return pbnd.Cid(), nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/events"
 	"github.com/ipfs/kubo/gc"
 	"github.com/ipfs/kubo/repo"
 
@@ -88,7 +89,7 @@ func GarbageCollect(n *core.IpfsNode, ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	rmed := gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots)
+	rmed := notifyGC(ctx, n, gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots))
 
 	return CollectResult(ctx, rmed, nil)
 }
@@ -156,7 +157,39 @@ func GarbageCollectAsync(n *core.IpfsNode, ctx context.Context) <-chan gc.Result
 		return out
 	}
 
-	return gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots)
+	return notifyGC(ctx, n, gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots))
+}
+
+// notifyGC forwards the results of a garbage collection run, and emits events
+// when the run starts and completes.
+func notifyGC(ctx context.Context, n *core.IpfsNode, gcOut <-chan gc.Result) <-chan gc.Result {
+	n.Events.Emit(events.GCStarted, nil)
+
+	out := make(chan gc.Result)
+	go func() {
+		defer close(out)
+		start := time.Now()
+		var ev events.GCEvent
+		for res := range gcOut {
+			if res.Error != nil {
+				ev.Errors = append(ev.Errors, res.Error.Error())
+			} else if res.KeyRemoved.Defined() {
+				ev.Removed++
+			}
+			// keep reading the results once the caller stopped, so that
+			// the run completes
+			select {
+			case out <- res:
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			ev.Errors = append(ev.Errors, err.Error())
+		}
+		ev.Duration = time.Since(start).String()
+		n.Events.Emit(events.GCCompleted, ev)
+	}()
+	return out
 }
 
 func PeriodicGC(ctx context.Context, node *core.IpfsNode) error {
@@ -0,0 +1,373 @@
+// Package events holds the events of the node, for the event stream of the
+// RPC API and the webhooks.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the events.
+const (
+	PinAdded         = "pin.added"
+	PinFailed        = "pin.failed"
+	PinRemoved       = "pin.removed"
+	RemotePinStatus  = "pin.remote.status"
+	MFSRootChanged   = "mfs.root.changed"
+	PeerConnected    = "peer.connected"
+	PeerDisconnected = "peer.disconnected"
+	GCStarted        = "gc.started"
+	GCCompleted      = "gc.completed"
+	ProvideProgress  = "provide.progress"
+	IpnsRepublished  = "ipns.republished"
+
+	// Lost is sent first to subscribers resuming after an event which is no
+	// longer held by the bus, or was emitted by another run of the daemon.
+	// The events held by the bus follow. It has no ID.
+	Lost = "events.lost"
+)
+
+// DefaultBufferSize is the number of events held by the bus for subscribers
+// to resume from.
+const DefaultBufferSize = 1024
+
+// ErrSlowSubscriber ends subscriptions which do not keep up with the events.
+// They can resume from the last event they received.
+var ErrSlowSubscriber = errors.New("subscriber too slow, events were dropped: resume from the last event received")
+
+// Event is an event of the node.
+type Event struct {
+	// ID identifies the event, to resume a subscription after it.
+	ID   string `json:",omitempty"`
+	Type string
+	Time time.Time
+	Data any `json:",omitempty"`
+}
+
+// PinEvent is the data of pin.added, pin.failed and pin.removed events.
+type PinEvent struct {
+	Path      string
+	Cid       string `json:",omitempty"`
+	Recursive bool
+	Name      string `json:",omitempty"`
+	Error     string `json:",omitempty"`
+}
+
+// RemotePinEvent is the data of pin.remote.status events, sent when the
+// status of a pin requested to a remote pinning service changes.
+type RemotePinEvent struct {
+	Service   string
+	RequestID string
+	Cid       string
+	Name      string `json:",omitempty"`
+	Status    string
+}
+
+// MFSEvent is the data of mfs.root.changed events.
+type MFSEvent struct {
+	Cid string
+}
+
+// PeerEvent is the data of peer.connected and peer.disconnected events.
+type PeerEvent struct {
+	Peer string
+}
+
+// GCEvent is the data of gc.completed events.
+type GCEvent struct {
+	Removed  int
+	Errors   []string `json:",omitempty"`
+	Duration string
+}
+
+// ProvideEvent is the data of provide.progress events, sent while the
+// content of the node is reprovided.
+type ProvideEvent struct {
+	// Provided is the number of CIDs in the reprovided batch.
+	Provided uint
+	Duration string
+	// Complete is set on the last event of a reprovide run.
+	Complete bool
+}
+
+// IpnsEvent is the data of ipns.republished events.
+type IpnsEvent struct {
+	Name  string
+	Value string
+}
+
+// LostEvent is the data of events.lost events.
+type LostEvent struct {
+	LastEventID string
+}
+
+// UnmarshalJSON decodes an event, with the data of the known types decoded
+// into their data type.
+func (ev *Event) UnmarshalJSON(b []byte) error {
+	type event Event
+	var raw struct {
+		event
+		Data json.RawMessage
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*ev = Event(raw.event)
+	if len(raw.Data) == 0 {
+		return nil
+	}
+
+	var err error
+	switch ev.Type {
+	case PinAdded, PinFailed, PinRemoved:
+		ev.Data, err = decodeData[PinEvent](raw.Data)
+	case RemotePinStatus:
+		ev.Data, err = decodeData[RemotePinEvent](raw.Data)
+	case MFSRootChanged:
+		ev.Data, err = decodeData[MFSEvent](raw.Data)
+	case PeerConnected, PeerDisconnected:
+		ev.Data, err = decodeData[PeerEvent](raw.Data)
+	case GCCompleted:
+		ev.Data, err = decodeData[GCEvent](raw.Data)
+	case ProvideProgress:
+		ev.Data, err = decodeData[ProvideEvent](raw.Data)
+	case IpnsRepublished:
+		ev.Data, err = decodeData[IpnsEvent](raw.Data)
+	case Lost:
+		ev.Data, err = decodeData[LostEvent](raw.Data)
+	default:
+		ev.Data, err = decodeData[any](raw.Data)
+	}
+	return err
+}
+
+func decodeData[T any](b []byte) (any, error) {
+	var data T
+	err := json.Unmarshal(b, &data)
+	return data, err
+}
+
+// Match returns whether events of the given type pass the filters. Filters
+// ending with "*" match any type with the same prefix. Empty filters match
+// all the types.
+func Match(filters []string, typ string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if prefix, ok := strings.CutSuffix(f, "*"); ok {
+			if strings.HasPrefix(typ, prefix) {
+				return true
+			}
+		} else if f == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus dispatches the events of the node to handlers and subscriptions, and
+// holds the last ones for subscribers to resume from. A nil Bus ignores all
+// events.
+type Bus struct {
+	lock sync.Mutex
+	// epoch prefixes the IDs of the events, which are only valid for one run
+	// of the daemon.
+	epoch    string
+	seq      uint64
+	ring     []Event
+	head     int
+	handlers []handler
+	subs     map[*Subscription]struct{}
+}
+
+type handler struct {
+	filters []string
+	f       func(Event)
+}
+
+// NewBus creates a bus holding the given number of events.
+func NewBus(size int) *Bus {
+	var epoch [4]byte
+	_, _ = rand.Read(epoch[:])
+	return &Bus{
+		epoch: hex.EncodeToString(epoch[:]),
+		ring:  make([]Event, 0, size),
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Emit sends an event of the given type to the handlers and subscriptions.
+// It does not block.
+func (b *Bus) Emit(typ string, data any) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.seq++
+	ev := Event{
+		ID:   b.epoch + "-" + strconv.FormatUint(b.seq, 10),
+		Type: typ,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+	if len(b.ring) < cap(b.ring) {
+		b.ring = append(b.ring, ev)
+	} else if cap(b.ring) > 0 {
+		b.ring[b.head] = ev
+		b.head = (b.head + 1) % cap(b.ring)
+	}
+
+	for _, h := range b.handlers {
+		if Match(h.filters, typ) {
+			h.f(ev)
+		}
+	}
+	for s := range b.subs {
+		if Match(s.filters, typ) {
+			s.send(ev)
+		}
+	}
+}
+
+// Handle calls f with the events passing the filters, from now on. f is
+// called synchronously by Emit, and must not block.
+func (b *Bus) Handle(filters []string, f func(Event)) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.handlers = append(b.handlers, handler{filters, f})
+}
+
+// Subscribed returns whether events of the given type are handled or
+// subscribed to. It lets callers skip the work needed only to emit an event.
+func (b *Bus) Subscribed(typ string) bool {
+	if b == nil {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, h := range b.handlers {
+		if Match(h.filters, typ) {
+			return true
+		}
+	}
+	for s := range b.subs {
+		if Match(s.filters, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe subscribes to the events passing the filters. When lastEventID
+// is set, the subscription first receives the events held by the bus which
+// were emitted after it.
+func (b *Bus) Subscribe(filters []string, lastEventID string) (*Subscription, error) {
+	if b == nil {
+		return nil, errors.New("the node has no event bus")
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	backlog, err := b.backlog(lastEventID)
+	if err != nil {
+		return nil, err
+	}
+	s := &Subscription{
+		bus:     b,
+		filters: filters,
+		ch:      make(chan Event, cap(b.ring)+64),
+	}
+	for _, ev := range backlog {
+		if ev.Type == Lost || Match(filters, ev.Type) {
+			s.ch <- ev
+		}
+	}
+	b.subs[s] = struct{}{}
+	return s, nil
+}
+
+// backlog returns the events held by the bus which were emitted after the
+// event with the given ID. It must be called with the lock held.
+func (b *Bus) backlog(lastEventID string) ([]Event, error) {
+	if lastEventID == "" {
+		return nil, nil
+	}
+	epoch, seqStr, ok := strings.Cut(lastEventID, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid event ID %q", lastEventID)
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event ID %q", lastEventID)
+	}
+
+	held := make([]Event, 0, len(b.ring))
+	held = append(held, b.ring[b.head:]...)
+	held = append(held, b.ring[:b.head]...)
+	oldest := b.seq - uint64(len(held)) + 1
+	if epoch != b.epoch || seq > b.seq || seq+1 < oldest {
+		lost := Event{Type: Lost, Time: time.Now().UTC(), Data: LostEvent{LastEventID: lastEventID}}
+		return append([]Event{lost}, held...), nil
+	}
+	return held[seq+1-oldest:], nil
+}
+
+// Subscription receives the events of a bus.
+type Subscription struct {
+	bus     *Bus
+	filters []string
+	ch      chan Event
+	err     error
+}
+
+// Events returns the channel of the events. It is closed when the
+// subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err returns the reason why the subscription ended, if it was not closed.
+// It must be called once the channel of the events is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	s.end(nil)
+}
+
+// send sends an event to the subscription, which ends when the event does
+// not fit. It must be called with the lock of the bus held.
+func (s *Subscription) send(ev Event) {
+	select {
+	case s.ch <- ev:
+	default:
+		s.end(ErrSlowSubscriber)
+	}
+}
+
+// end ends the subscription. It must be called with the lock of the bus held.
+func (s *Subscription) end(err error) {
+	if _, ok := s.bus.subs[s]; !ok {
+		return
+	}
+	delete(s.bus.subs, s)
+	s.err = err
+	close(s.ch)
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, s *Subscription, n int) []Event {
+	t.Helper()
+	out := make([]Event, 0, n)
+	for range n {
+		select {
+		case ev := <-s.Events():
+			out = append(out, ev)
+		default:
+			t.Fatalf("expected %d events, got %d", n, len(out))
+		}
+	}
+	select {
+	case ev, ok := <-s.Events():
+		if ok {
+			t.Fatalf("unexpected event %v", ev)
+		}
+	default:
+	}
+	return out
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match(nil, PinAdded))
+	assert.True(t, Match([]string{"gc.started", PinAdded}, PinAdded))
+	assert.True(t, Match([]string{"pin.*"}, RemotePinStatus))
+	assert.True(t, Match([]string{"*"}, PeerConnected))
+	assert.False(t, Match([]string{"pin.*"}, GCCompleted))
+	assert.False(t, Match([]string{"pin"}, PinAdded))
+}
+
+func TestBus(t *testing.T) {
+	t.Run("subscriptions receive the events passing their filters", func(t *testing.T) {
+		b := NewBus(8)
+		b.Emit(PinAdded, nil)
+		all, err := b.Subscribe(nil, "")
+		require.NoError(t, err)
+		pins, err := b.Subscribe([]string{"pin.*"}, "")
+		require.NoError(t, err)
+		assert.True(t, b.Subscribed(GCStarted))
+
+		b.Emit(GCStarted, nil)
+		b.Emit(PinRemoved, PinEvent{Cid: "bafkqaaa"})
+
+		evs := receive(t, all, 2)
+		assert.Equal(t, GCStarted, evs[0].Type)
+		assert.Equal(t, PinRemoved, evs[1].Type)
+		assert.NotEqual(t, evs[0].ID, evs[1].ID)
+		evs = receive(t, pins, 1)
+		assert.Equal(t, PinEvent{Cid: "bafkqaaa"}, evs[0].Data)
+
+		all.Close()
+		_, ok := <-all.Events()
+		assert.False(t, ok)
+		assert.NoError(t, all.Err())
+		assert.False(t, b.Subscribed(GCStarted))
+		assert.True(t, b.Subscribed(PinAdded))
+	})
+
+	t.Run("subscriptions resume after the last event received", func(t *testing.T) {
+		b := NewBus(4)
+		var ids []string
+		h := func(ev Event) { ids = append(ids, ev.ID) }
+		b.Handle(nil, h)
+		for i := range 3 {
+			b.Emit(fmt.Sprint("test.", i), nil)
+		}
+
+		s, err := b.Subscribe(nil, ids[0])
+		require.NoError(t, err)
+		evs := receive(t, s, 2)
+		assert.Equal(t, ids[1:], []string{evs[0].ID, evs[1].ID})
+
+		s, err = b.Subscribe([]string{"test.2"}, ids[0])
+		require.NoError(t, err)
+		evs = receive(t, s, 1)
+		assert.Equal(t, ids[2], evs[0].ID)
+
+		s, err = b.Subscribe(nil, ids[2])
+		require.NoError(t, err)
+		receive(t, s, 0)
+
+		_, err = b.Subscribe(nil, "invalid")
+		assert.Error(t, err)
+	})
+
+	t.Run("subscriptions resuming after an event no longer held are told", func(t *testing.T) {
+		b := NewBus(2)
+		var ids []string
+		b.Handle(nil, func(ev Event) { ids = append(ids, ev.ID) })
+		for i := range 4 {
+			b.Emit(fmt.Sprint("test.", i), nil)
+		}
+
+		for _, id := range []string{ids[0], "00000000-1", b.epoch + "-100"} {
+			s, err := b.Subscribe(nil, id)
+			require.NoError(t, err)
+			evs := receive(t, s, 3)
+			assert.Equal(t, Lost, evs[0].Type)
+			assert.Empty(t, evs[0].ID)
+			assert.Equal(t, LostEvent{LastEventID: id}, evs[0].Data)
+			assert.Equal(t, ids[2:], []string{evs[1].ID, evs[2].ID})
+		}
+
+		// the last held event is still resumable
+		s, err := b.Subscribe(nil, ids[1])
+		require.NoError(t, err)
+		receive(t, s, 2)
+	})
+
+	t.Run("slow subscriptions end", func(t *testing.T) {
+		b := NewBus(1)
+		s, err := b.Subscribe(nil, "")
+		require.NoError(t, err)
+		for range cap(s.ch) + 1 {
+			b.Emit(PeerConnected, nil)
+		}
+		n := 0
+		for range s.Events() {
+			n++
+		}
+		assert.Equal(t, cap(s.ch), n)
+		assert.ErrorIs(t, s.Err(), ErrSlowSubscriber)
+		s.Close()
+	})
+
+	t.Run("a nil bus ignores events", func(t *testing.T) {
+		var b *Bus
+		b.Emit(PinAdded, nil)
+		b.Handle(nil, func(Event) {})
+		assert.False(t, b.Subscribed(PinAdded))
+		_, err := b.Subscribe(nil, "")
+		assert.Error(t, err)
+	})
+}
+
+func TestEventJSON(t *testing.T) {
+	for _, ev := range []Event{
+		{ID: "a-1", Type: PinAdded, Data: PinEvent{Path: "/ipfs/bafkqaaa", Cid: "bafkqaaa", Recursive: true}},
+		{ID: "a-2", Type: GCStarted},
+		{ID: "a-3", Type: GCCompleted, Data: GCEvent{Removed: 1, Duration: "1s"}},
+		{Type: Lost, Data: LostEvent{LastEventID: "b-1"}},
+		{ID: "a-4", Type: "future.event", Data: map[string]any{"Key": "value"}},
+	} {
+		b, err := json.Marshal(&ev)
+		require.NoError(t, err)
+		var out Event
+		require.NoError(t, json.Unmarshal(b, &out))
+		assert.Equal(t, ev, out)
+	}
+}
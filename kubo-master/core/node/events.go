@@ -0,0 +1,43 @@
+package node
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"go.uber.org/fx"
+
+	"github.com/ipfs/kubo/core/events"
+)
+
+// EventBus creates the bus of the events of the node.
+func EventBus() *events.Bus {
+	return events.NewBus(events.DefaultBufferSize)
+}
+
+// PeerEvents emits events when peers connect to the node or disconnect from
+// it.
+func PeerEvents(lc fx.Lifecycle, h host.Host, bus *events.Bus) error {
+	sub, err := h.EventBus().Subscribe(new(event.EvtPeerConnectednessChanged))
+	if err != nil {
+		return err
+	}
+	go func() {
+		for e := range sub.Out() {
+			evt := e.(event.EvtPeerConnectednessChanged)
+			switch evt.Connectedness {
+			case network.Connected:
+				bus.Emit(events.PeerConnected, events.PeerEvent{Peer: evt.Peer.String()})
+			case network.NotConnected:
+				bus.Emit(events.PeerDisconnected, events.PeerEvent{Peer: evt.Peer.String()})
+			}
+		}
+	}()
+	lc.Append(fx.Hook{
+		OnStop: func(context.Context) error {
+			return sub.Close()
+		},
+	})
+	return nil
+}
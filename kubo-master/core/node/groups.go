@@ -12,13 +12,16 @@ import (
 	blockstore "github.com/ipfs/boxo/blockstore"
 	offline "github.com/ipfs/boxo/exchange/offline"
 	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	keystore "github.com/ipfs/boxo/keystore"
 	util "github.com/ipfs/boxo/util"
 	"github.com/ipfs/go-log/v2"
 	"github.com/ipfs/kubo/config"
 	"github.com/ipfs/kubo/core/node/libp2p"
+	"github.com/ipfs/kubo/keystore/remotesigner"
 	"github.com/ipfs/kubo/p2p"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p-pubsub/timecache"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
 	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
 	"go.uber.org/fx"
@@ -273,14 +276,22 @@ func Identity(cfg *config.Config) fx.Option {
 
 	// Private Key
 
-	if cfg.Identity.PrivKey == "" {
+	var sk crypto.PrivKey
+	switch socket := cfg.Keystore.RemoteSigner.WithDefault(""); {
+	case cfg.Identity.PrivKey != "":
+		sk, err = cfg.Identity.DecodePrivateKey("passphrase todo!")
+	case socket != "":
+		// the identity is held by the remote signer
+		sk, err = remotesigner.NewKeystore(socket).Identity()
+		if errors.Is(err, keystore.ErrNoSuchKey) {
+			err = fmt.Errorf("no private key in config, and no %q key in the remote signer", remotesigner.SelfKey)
+		}
+	default:
 		return fx.Options( // No PK (usually in tests)
 			fx.Provide(PeerID(id)),
 			fx.Provide(libp2p.Peerstore),
 		)
 	}
-
-	sk, err := cfg.Identity.DecodePrivateKey("passphrase todo!")
 	if err != nil {
 		return fx.Error(err)
 	}
@@ -357,6 +368,7 @@ func Online(bcfg *BuildCfg, cfg *config.Config, userResourceOverrides rcmgr.Part
 		PeerWith(cfg.Peering.Peers...),
 
 		fx.Invoke(IpnsRepublisher(repubPeriod, recordLifetime)),
+		fx.Invoke(PeerEvents),
 
 		fx.Provide(p2p.New),
 
@@ -450,6 +462,8 @@ func IPFS(ctx context.Context, bcfg *BuildCfg) fx.Option {
 		IPNS,
 		Networked(bcfg, cfg, userResourceOverrides),
 		fx.Provide(BlockService(cfg)),
+		fx.Provide(EventBus),
+		fx.Provide(Webhooks(cfg)),
 		Core,
 	)
 }
@@ -1,18 +1,22 @@
 package node
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
 	util "github.com/ipfs/boxo/util"
 	record "github.com/libp2p/go-libp2p-record"
 	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/peerstore"
 	madns "github.com/multiformats/go-multiaddr-dns"
 
 	"github.com/ipfs/boxo/namesys"
 	"github.com/ipfs/boxo/namesys/republisher"
+	"github.com/ipfs/kubo/core/events"
 	"github.com/ipfs/kubo/repo"
 	irouting "github.com/ipfs/kubo/routing"
 )
@@ -45,9 +49,9 @@ func Namesys(cacheSize int, cacheMaxTTL time.Duration) func(rt irouting.ProvideM
 }
 
 // IpnsRepublisher runs new IPNS republisher service
-func IpnsRepublisher(repubPeriod time.Duration, recordLifetime time.Duration) func(lcStartStop, namesys.NameSystem, repo.Repo, crypto.PrivKey) error {
-	return func(lc lcStartStop, namesys namesys.NameSystem, repo repo.Repo, privKey crypto.PrivKey) error {
-		repub := republisher.NewRepublisher(namesys, repo.Datastore(), privKey, repo.Keystore())
+func IpnsRepublisher(repubPeriod time.Duration, recordLifetime time.Duration) func(lcStartStop, namesys.NameSystem, repo.Repo, crypto.PrivKey, *events.Bus) error {
+	return func(lc lcStartStop, ns namesys.NameSystem, repo repo.Repo, privKey crypto.PrivKey, bus *events.Bus) error {
+		repub := republisher.NewRepublisher(&republishNotifier{ns, bus}, repo.Datastore(), privKey, repo.Keystore())
 
 		if repubPeriod != 0 {
 			if !util.Debug && (repubPeriod < time.Minute || repubPeriod > (time.Hour*24)) {
@@ -65,3 +69,25 @@ func IpnsRepublisher(repubPeriod time.Duration, recordLifetime time.Duration) fu
 		return nil
 	}
 }
+
+// republishNotifier emits an event for each record published by the
+// republisher.
+type republishNotifier struct {
+	namesys.Publisher
+	bus *events.Bus
+}
+
+func (p *republishNotifier) Publish(ctx context.Context, sk crypto.PrivKey, value path.Path, options ...namesys.PublishOption) error {
+	if err := p.Publisher.Publish(ctx, sk, value, options...); err != nil {
+		return err
+	}
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return err
+	}
+	p.bus.Emit(events.IpnsRepublished, events.IpnsEvent{
+		Name:  ipns.NameFromPeer(id).String(),
+		Value: value.String(),
+	})
+	return nil
+}
@@ -1,18 +1,27 @@
 package libp2p
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/keystore/remotesigner"
 	"github.com/ipshipyard/p2p-forge/client"
 	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/metrics"
+	"github.com/libp2p/go-libp2p/core/network"
 	quic "github.com/libp2p/go-libp2p/p2p/transport/quic"
+	"github.com/libp2p/go-libp2p/p2p/transport/quicreuse"
 	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
 	webrtc "github.com/libp2p/go-libp2p/p2p/transport/webrtc"
 	"github.com/libp2p/go-libp2p/p2p/transport/websocket"
 	webtransport "github.com/libp2p/go-libp2p/p2p/transport/webtransport"
+	quicgo "github.com/quic-go/quic-go"
+	"golang.org/x/crypto/hkdf"
 
 	"go.uber.org/fx"
 )
@@ -22,6 +31,7 @@ func Transports(tptConfig config.Transports) interface{} {
 		fx.In
 		Fprint   PNetFingerprint         `optional:"true"`
 		ForgeMgr *client.P2PForgeCertMgr `optional:"true"`
+		Key      crypto.PrivKey          `optional:"true"`
 	},
 	) (opts Libp2pOpts, err error) {
 		privateNetworkEnabled := params.Fprint != nil
@@ -67,6 +77,14 @@ func Transports(tptConfig config.Transports) interface{} {
 			opts.Opts = append(opts.Opts, libp2p.Transport(webtransport.New))
 		}
 
+		if rk, ok := params.Key.(*remotesigner.PrivKey); ok && (tptConfig.Network.QUIC.WithDefault(true) || tptConfig.Network.WebTransport.WithDefault(true)) {
+			quicReuse, err := remoteSignerQUICReuse(rk)
+			if err != nil {
+				return opts, err
+			}
+			opts.Opts = append(opts.Opts, quicReuse)
+		}
+
 		if tptConfig.Network.WebRTCDirect.WithDefault(!privateNetworkEnabled) {
 			if privateNetworkEnabled {
 				return opts, fmt.Errorf(
@@ -85,3 +103,49 @@ func BandwidthCounter() (opts Libp2pOpts, reporter *metrics.BandwidthCounter) {
 	opts.Opts = append(opts.Opts, libp2p.BandwidthReporter(reporter))
 	return opts, reporter
 }
+
+// remoteSignerQUICReuse returns the QUIC connection manager used when the
+// identity is held by the remote signer: go-libp2p derives the QUIC stateless
+// reset and token keys from the raw private key, which is not available, so
+// they are derived from a signature of the signer instead. The signature, and
+// thus the keys, are stable across restarts for Ed25519 and RSA keys.
+func remoteSignerQUICReuse(sk *remotesigner.PrivKey) (libp2p.Option, error) {
+	sig, err := sk.Sign([]byte("kubo remote signer: libp2p quic keys"))
+	if err != nil {
+		return nil, fmt.Errorf("deriving QUIC keys with the remote signer: %w", err)
+	}
+	var resetKey quicgo.StatelessResetKey
+	var tokenKey quicgo.TokenGeneratorKey
+	r := hkdf.New(sha256.New, sig, nil, []byte("libp2p quic keys"))
+	if _, err := io.ReadFull(r, resetKey[:]); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, tokenKey[:]); err != nil {
+		return nil, err
+	}
+
+	return libp2p.QUICReuse(func(rcmgr network.ResourceManager, lifecycle fx.Lifecycle) (*quicreuse.ConnManager, error) {
+		// same as the default constructor of go-libp2p
+		cm, err := quicreuse.NewConnManager(resetKey, tokenKey,
+			quicreuse.ConnContext(func(ctx context.Context, clientInfo *quicgo.ClientInfo) (context.Context, error) {
+				addr, err := quicreuse.ToQuicMultiaddr(clientInfo.RemoteAddr, quicgo.Version1)
+				if err != nil {
+					addr = nil
+				}
+				scope, err := rcmgr.OpenConnection(network.DirInbound, false, addr)
+				if err != nil {
+					return ctx, err
+				}
+				ctx = network.WithConnManagementScope(ctx, scope)
+				context.AfterFunc(ctx, scope.Done)
+				return ctx, nil
+			}),
+			quicreuse.VerifySourceAddress(rcmgr.VerifySourceAddress),
+		)
+		if err != nil {
+			return nil, err
+		}
+		lifecycle.Append(fx.StopHook(cm.Close))
+		return cm, nil
+	}), nil
+}
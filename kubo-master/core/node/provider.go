@@ -1,8 +1,11 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/ipfs/boxo/blockstore"
@@ -11,8 +14,10 @@ import (
 	pin "github.com/ipfs/boxo/pinning/pinner"
 	provider "github.com/ipfs/boxo/provider"
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/kubo/core/events"
 	"github.com/ipfs/kubo/repo"
 	irouting "github.com/ipfs/kubo/routing"
+	"github.com/multiformats/go-multihash"
 	"go.uber.org/fx"
 )
 
@@ -22,11 +27,14 @@ import (
 const sampledBatchSize = 1000
 
 func ProviderSys(reprovideInterval time.Duration, acceleratedDHTClient bool, provideWorkerCount int) fx.Option {
-	return fx.Provide(func(lc fx.Lifecycle, cr irouting.ProvideManyRouter, keyProvider provider.KeyChanFunc, repo repo.Repo, bs blockstore.Blockstore) (provider.System, error) {
+	return fx.Provide(func(lc fx.Lifecycle, cr irouting.ProvideManyRouter, keyProvider provider.KeyChanFunc, repo repo.Repo, bs blockstore.Blockstore, bus *events.Bus) (provider.System, error) {
+		// Reprovides are announced through the router in batches, which
+		// are reported as provide.progress events.
+		pr := &progressRouter{ProvideManyRouter: cr, bus: bus}
 		opts := []provider.Option{
-			provider.Online(cr),
+			provider.Online(pr),
 			provider.ReproviderInterval(reprovideInterval),
-			provider.KeyProvider(keyProvider),
+			provider.KeyProvider(pr.keyProvider(keyProvider)),
 			provider.ProvideWorkerCount(provideWorkerCount),
 		}
 		if !acceleratedDHTClient && reprovideInterval > 0 {
@@ -129,6 +137,78 @@ https://github.com/ipfs/kubo/blob/master/docs/config.md#routingaccelerateddhtcli
 	})
 }
 
+// progressRouter emits a provide.progress event for each batch of keys
+// reprovided through it.
+type progressRouter struct {
+	irouting.ProvideManyRouter
+	bus *events.Bus
+
+	mu sync.Mutex
+	// last is the last key of the running reprovide, once it is known.
+	last multihash.Multihash
+}
+
+func (r *progressRouter) ProvideMany(ctx context.Context, keys []multihash.Multihash) error {
+	start := time.Now()
+	if err := r.ProvideManyRouter.ProvideMany(ctx, keys); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	complete := r.last != nil && slices.ContainsFunc(keys, func(k multihash.Multihash) bool {
+		return bytes.Equal(k, r.last)
+	})
+	if complete {
+		r.last = nil
+	}
+	r.mu.Unlock()
+
+	r.bus.Emit(events.ProvideProgress, events.ProvideEvent{
+		Provided: uint(len(keys)),
+		Duration: time.Since(start).String(),
+		Complete: complete,
+	})
+	return nil
+}
+
+func (r *progressRouter) Ready() bool {
+	if ready, ok := r.ProvideManyRouter.(provider.Ready); ok {
+		return ready.Ready()
+	}
+	return true
+}
+
+// keyProvider wraps kcf to record the last key of each reprovide, so that
+// the batch holding it is reported as complete.
+func (r *progressRouter) keyProvider(kcf provider.KeyChanFunc) provider.KeyChanFunc {
+	return func(ctx context.Context) (<-chan cid.Cid, error) {
+		in, err := kcf(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out := make(chan cid.Cid)
+		go func() {
+			defer close(out)
+			c, ok := <-in
+			for ok {
+				next, more := <-in
+				if !more {
+					r.mu.Lock()
+					r.last = c.Hash()
+					r.mu.Unlock()
+				}
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+				c, ok = next, more
+			}
+		}()
+		return out, nil
+	}
+}
+
 // ONLINE/OFFLINE
 
 // OnlineProviders groups units managing provider routing records online
@@ -1,12 +1,15 @@
 package node
 
 import (
+	"context"
+
 	blockstore "github.com/ipfs/boxo/blockstore"
 	"github.com/ipfs/go-datastore"
 	config "github.com/ipfs/kubo/config"
 	"go.uber.org/fx"
 
 	"github.com/ipfs/boxo/filestore"
+	"github.com/ipfs/kubo/blocks/carstore"
 	"github.com/ipfs/kubo/core/node/helpers"
 	"github.com/ipfs/kubo/repo"
 	"github.com/ipfs/kubo/thirdparty/verifbs"
@@ -26,18 +29,34 @@ func Datastore(repo repo.Repo) datastore.Datastore {
 // BaseBlocks is the lower level blockstore without GC or Filestore layers
 type BaseBlocks blockstore.Blockstore
 
-// BaseBlockstoreCtor creates cached blockstore backed by the provided datastore
-func BaseBlockstoreCtor(cacheOpts blockstore.CacheOpts, hashOnRead bool, writeThrough bool) func(mctx helpers.MetricsCtx, repo repo.Repo, lc fx.Lifecycle) (bs BaseBlocks, err error) {
-	return func(mctx helpers.MetricsCtx, repo repo.Repo, lc fx.Lifecycle) (bs BaseBlocks, err error) {
+// BaseBlockstoreCtor creates cached blockstore backed by the provided datastore,
+// and by the CAR files attached to the repo
+func BaseBlockstoreCtor(cacheOpts blockstore.CacheOpts, hashOnRead bool, writeThrough bool) func(mctx helpers.MetricsCtx, repo repo.Repo, lc fx.Lifecycle) (bs BaseBlocks, cars *carstore.Store, err error) {
+	return func(mctx helpers.MetricsCtx, repo repo.Repo, lc fx.Lifecycle) (bs BaseBlocks, cars *carstore.Store, err error) {
+		ctx := helpers.LifecycleCtx(mctx, lc)
+
 		// hash security
 		bs = blockstore.NewBlockstore(repo.Datastore(),
 			blockstore.WriteThrough(writeThrough),
 		)
 		bs = &verifbs.VerifBS{Blockstore: bs}
-		bs, err = blockstore.CachedBlockstore(helpers.LifecycleCtx(mctx, lc), bs, cacheOpts)
+		bs, err = blockstore.CachedBlockstore(ctx, bs, cacheOpts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// above the cache, as its bloom filter only knows the blocks of the
+		// datastore
+		cars, err = carstore.New(ctx, bs, repo.Datastore())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		lc.Append(fx.Hook{
+			OnStop: func(context.Context) error {
+				return cars.Close()
+			},
+		})
+		bs = cars
 
 		bs = blockstore.NewIdStore(bs)
 
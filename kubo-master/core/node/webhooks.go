@@ -0,0 +1,29 @@
+package node
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.uber.org/fx"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/events"
+	"github.com/ipfs/kubo/core/webhooks"
+)
+
+// Webhooks creates the dispatcher of the events of the node to the webhooks
+// of the config. The daemon starts it.
+func Webhooks(cfg *config.Config) func(lc fx.Lifecycle, bus *events.Bus, id peer.ID) (*webhooks.Dispatcher, error) {
+	return func(lc fx.Lifecycle, bus *events.Bus, id peer.ID) (*webhooks.Dispatcher, error) {
+		d, err := webhooks.New(cfg.Webhooks, bus, id.String())
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				return d.Close()
+			},
+		})
+		return d, nil
+	}
+}
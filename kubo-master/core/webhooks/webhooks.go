@@ -0,0 +1,379 @@
+// Package webhooks notifies HTTP endpoints of the events of the daemon, with
+// signed JSON POST requests.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	version "github.com/ipfs/kubo"
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/events"
+)
+
+var log = logging.Logger("webhooks")
+
+// Headers of the requests sent to webhooks.
+const (
+	EventHeader    = "X-Kubo-Event"
+	DeliveryHeader = "X-Kubo-Delivery"
+	// SignatureHeader holds "sha256=" followed by the hex encoded
+	// HMAC-SHA256 of the body, keyed with the secret of the webhook.
+	SignatureHeader = "X-Kubo-Signature"
+)
+
+// States of deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	// queueSize is the number of events waiting for delivery to a webhook,
+	// newer events failing once it is reached.
+	queueSize = 1024
+	// recentDeliveries is the number of deliveries kept per webhook.
+	recentDeliveries = 20
+)
+
+// Event is the body of the requests sent to webhooks.
+type Event struct {
+	events.Event
+	// Node is the peer ID of the node sending the event.
+	Node string
+}
+
+// UnmarshalJSON decodes an event, which would otherwise be decoded by the
+// UnmarshalJSON method of the embedded events.Event, dropping Node.
+func (ev *Event) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &ev.Event); err != nil {
+		return err
+	}
+	var node struct{ Node string }
+	if err := json.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	ev.Node = node.Node
+	return nil
+}
+
+// Delivery is the state of the delivery of an event to a webhook.
+type Delivery struct {
+	ID          string
+	Type        string
+	Time        time.Time
+	State       string
+	Attempts    int
+	LastAttempt time.Time `json:",omitempty"`
+	StatusCode  int       `json:",omitempty"`
+	Error       string    `json:",omitempty"`
+}
+
+// Status is the delivery state of a webhook.
+type Status struct {
+	Name      string
+	URL       string
+	Events    []string `json:",omitempty"`
+	Delivered int
+	Failed    int
+	Pending   int
+	// Recent holds the last deliveries, newest first.
+	Recent []Delivery `json:",omitempty"`
+}
+
+// Dispatcher sends the events of the node to the webhooks of the config.
+// Each webhook receives its events in order, failed deliveries being retried
+// with an exponential backoff. Events are only sent once the dispatcher was
+// started, which the daemon does. A nil Dispatcher sends no events.
+type Dispatcher struct {
+	node   string
+	bus    *events.Bus
+	client *http.Client
+	hooks  []*hook
+
+	lock    sync.Mutex
+	started bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type hook struct {
+	name         string
+	url          string
+	events       []string
+	secret       []byte
+	maxRetries   int
+	retryBackoff time.Duration
+	maxBackoff   time.Duration
+	timeout      time.Duration
+
+	queue chan *Delivery
+
+	lock      sync.Mutex
+	body      map[*Delivery][]byte
+	delivered int
+	failed    int
+	recent    []*Delivery
+}
+
+// New creates a dispatcher sending the events of the given bus to the given
+// webhooks, on behalf of the node with the given peer ID.
+func New(cfg map[string]*config.Webhook, bus *events.Bus, node string) (*Dispatcher, error) {
+	d := &Dispatcher{
+		node:   node,
+		bus:    bus,
+		client: &http.Client{},
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	for name, c := range cfg {
+		if c == nil {
+			return nil, fmt.Errorf("webhook %q: missing config", name)
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook %q: invalid URL %q, expected an http or https URL", name, c.URL)
+		}
+		h := &hook{
+			name:         name,
+			url:          c.URL,
+			events:       slices.Clone(c.Events),
+			secret:       []byte(c.Secret),
+			maxRetries:   int(c.MaxRetries.WithDefault(config.DefaultWebhookMaxRetries)),
+			retryBackoff: c.RetryBackoff.WithDefault(config.DefaultWebhookRetryBackoff),
+			maxBackoff:   c.MaxBackoff.WithDefault(config.DefaultWebhookMaxBackoff),
+			timeout:      c.Timeout.WithDefault(config.DefaultWebhookTimeout),
+			queue:        make(chan *Delivery, queueSize),
+			body:         make(map[*Delivery][]byte),
+		}
+		if h.maxRetries < 0 {
+			return nil, fmt.Errorf("webhook %q: MaxRetries must not be negative", name)
+		}
+		if h.retryBackoff <= 0 || h.maxBackoff <= 0 || h.timeout <= 0 {
+			return nil, fmt.Errorf("webhook %q: RetryBackoff, MaxBackoff and Timeout must be positive", name)
+		}
+		d.hooks = append(d.hooks, h)
+	}
+	sort.Slice(d.hooks, func(i, j int) bool {
+		return d.hooks[i].name < d.hooks[j].name
+	})
+	return d, nil
+}
+
+// Start starts delivering the events emitted from now on.
+func (d *Dispatcher) Start() {
+	if d == nil {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.started || d.ctx.Err() != nil {
+		return
+	}
+	d.started = true
+	for _, h := range d.hooks {
+		d.bus.Handle(h.events, func(ev events.Event) {
+			if d.ctx.Err() == nil {
+				h.enqueue(ev, d.node)
+			}
+		})
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			h.run(d.ctx, d.client)
+		}()
+	}
+}
+
+// Close stops the deliveries, dropping the events not delivered yet.
+func (d *Dispatcher) Close() error {
+	if d == nil {
+		return nil
+	}
+	d.cancel()
+	d.wg.Wait()
+	return nil
+}
+
+// Status returns the delivery state of the webhooks, sorted by name.
+func (d *Dispatcher) Status() []Status {
+	if d == nil {
+		return nil
+	}
+	out := make([]Status, 0, len(d.hooks))
+	for _, h := range d.hooks {
+		h.lock.Lock()
+		st := Status{
+			Name:      h.name,
+			URL:       h.url,
+			Events:    slices.Clone(h.events),
+			Delivered: h.delivered,
+			Failed:    h.failed,
+			Pending:   len(h.body),
+			Recent:    make([]Delivery, len(h.recent)),
+		}
+		for i, dv := range h.recent {
+			st.Recent[i] = *dv
+		}
+		h.lock.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
+
+// enqueue queues an event for delivery. Events which do not fit in the queue
+// are failed.
+func (h *hook) enqueue(ev events.Event, node string) {
+	body, err := json.Marshal(&Event{Event: ev, Node: node})
+	if err != nil {
+		log.Errorf("encoding %s event: %s", ev.Type, err)
+		return
+	}
+	dv := &Delivery{
+		ID:    ev.ID,
+		Type:  ev.Type,
+		Time:  ev.Time,
+		State: DeliveryPending,
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.recent = append([]*Delivery{dv}, h.recent...)
+	if len(h.recent) > recentDeliveries {
+		h.recent = h.recent[:recentDeliveries]
+	}
+	select {
+	case h.queue <- dv:
+		h.body[dv] = body
+	default:
+		dv.State = DeliveryFailed
+		dv.Error = "delivery queue full"
+		h.failed++
+		log.Errorf("webhook %q: dropping %s event %s: delivery queue full", h.name, ev.Type, ev.ID)
+	}
+}
+
+func (h *hook) run(ctx context.Context, client *http.Client) {
+	for {
+		select {
+		case dv := <-h.queue:
+			h.deliver(ctx, client, dv)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver sends an event to the webhook, retrying failed attempts.
+func (h *hook) deliver(ctx context.Context, client *http.Client, dv *Delivery) {
+	h.lock.Lock()
+	body := h.body[dv]
+	h.lock.Unlock()
+
+	backoff := h.retryBackoff
+	for attempt := 0; ; attempt++ {
+		code, err := h.post(ctx, client, dv, body)
+		retry := err != nil && retryable(code) && attempt < h.maxRetries
+
+		h.lock.Lock()
+		dv.Attempts++
+		dv.LastAttempt = time.Now().UTC()
+		dv.StatusCode = code
+		dv.Error = ""
+		switch {
+		case err == nil:
+			dv.State = DeliverySucceeded
+			h.delivered++
+		case !retry:
+			dv.State = DeliveryFailed
+			dv.Error = err.Error()
+			h.failed++
+		default:
+			dv.Error = err.Error()
+		}
+		if !retry {
+			delete(h.body, dv)
+		}
+		h.lock.Unlock()
+
+		if !retry {
+			if err != nil {
+				log.Errorf("webhook %q: failed to deliver %s event %s: %s", h.name, dv.Type, dv.ID, err)
+			}
+			return
+		}
+
+		log.Debugf("webhook %q: retrying %s event %s in %s: %s", h.name, dv.Type, dv.ID, backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		backoff = min(2*backoff, h.maxBackoff)
+	}
+}
+
+// post makes a delivery attempt, and returns the HTTP status code of the
+// response, if any.
+func (h *hook) post(ctx context.Context, client *http.Client, dv *Delivery, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", version.GetUserAgentVersion())
+	req.Header.Set(EventHeader, dv.Type)
+	req.Header.Set(DeliveryHeader, dv.ID)
+	if len(h.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(h.secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable returns whether a delivery attempt which failed with the given
+// status code is retried. Attempts which got no response are retried.
+func retryable(code int) bool {
+	return code == 0 || code >= 500 ||
+		code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// Sign returns the value of the SignatureHeader of a request with the given
+// body, sent to a webhook with the given secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
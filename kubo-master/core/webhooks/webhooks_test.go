@@ -0,0 +1,175 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receiver struct {
+	lock     sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	return len(rc.requests)
+}
+
+func waitStatus(t *testing.T, d *Dispatcher, name string, cond func(Status) bool) Status {
+	t.Helper()
+	var st Status
+	require.Eventually(t, func() bool {
+		for _, s := range d.Status() {
+			if s.Name == name {
+				st = s
+				return cond(s)
+			}
+		}
+		return false
+	}, 10*time.Second, 10*time.Millisecond)
+	return st
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("delivers signed events to the subscribed webhooks", func(t *testing.T) {
+		all, pins := &receiver{}, &receiver{}
+		allSrv, pinsSrv := httptest.NewServer(all), httptest.NewServer(pins)
+		defer allSrv.Close()
+		defer pinsSrv.Close()
+
+		bus := events.NewBus(events.DefaultBufferSize)
+		d, err := New(map[string]*config.Webhook{
+			"all":  {URL: allSrv.URL, Secret: "s3cr3t"},
+			"pins": {URL: pinsSrv.URL, Events: []string{"pin.*"}},
+		}, bus, "12D3KooW")
+		require.NoError(t, err)
+		defer d.Close()
+
+		// events are dropped until the dispatcher is started
+		bus.Emit(events.PinAdded, events.PinEvent{Path: "/ipfs/bafkqaaa"})
+		d.Start()
+		bus.Emit(events.PinAdded, events.PinEvent{Path: "/ipfs/bafkqaaa", Cid: "bafkqaaa", Recursive: true})
+		bus.Emit(events.GCCompleted, events.GCEvent{Removed: 2})
+
+		waitStatus(t, d, "all", func(s Status) bool { return s.Delivered == 2 })
+		st := waitStatus(t, d, "pins", func(s Status) bool { return s.Delivered == 1 })
+		assert.Equal(t, 0, st.Failed)
+		assert.Equal(t, 0, st.Pending)
+		require.Len(t, st.Recent, 1)
+		assert.Equal(t, events.PinAdded, st.Recent[0].Type)
+		assert.Equal(t, DeliverySucceeded, st.Recent[0].State)
+		assert.Equal(t, http.StatusOK, st.Recent[0].StatusCode)
+
+		require.Equal(t, 2, all.count())
+		r, body := all.requests[0], all.bodies[0]
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, events.PinAdded, r.Header.Get(EventHeader))
+		assert.Equal(t, Sign([]byte("s3cr3t"), body), r.Header.Get(SignatureHeader))
+
+		var ev Event
+		require.NoError(t, json.Unmarshal(body, &ev))
+		assert.Equal(t, r.Header.Get(DeliveryHeader), ev.ID)
+		assert.Equal(t, events.PinAdded, ev.Type)
+		assert.Equal(t, "12D3KooW", ev.Node)
+		assert.Equal(t, events.PinEvent{Path: "/ipfs/bafkqaaa", Cid: "bafkqaaa", Recursive: true}, ev.Data)
+
+		assert.Equal(t, events.GCCompleted, all.requests[1].Header.Get(EventHeader))
+		assert.Empty(t, pins.requests[0].Header.Get(SignatureHeader))
+	})
+
+	t.Run("retries failed deliveries", func(t *testing.T) {
+		rc := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		srv := httptest.NewServer(rc)
+		defer srv.Close()
+
+		bus := events.NewBus(events.DefaultBufferSize)
+		d, err := New(map[string]*config.Webhook{
+			"hook": {URL: srv.URL, RetryBackoff: config.NewOptionalDuration(time.Millisecond)},
+		}, bus, "")
+		require.NoError(t, err)
+		defer d.Close()
+		d.Start()
+
+		bus.Emit(events.PinAdded, events.PinEvent{})
+		st := waitStatus(t, d, "hook", func(s Status) bool { return s.Delivered == 1 })
+		assert.Equal(t, 3, st.Recent[0].Attempts)
+		assert.Empty(t, st.Recent[0].Error)
+		assert.Equal(t, 3, rc.count())
+		assert.Equal(t, rc.requests[0].Header.Get(DeliveryHeader), rc.requests[2].Header.Get(DeliveryHeader))
+	})
+
+	t.Run("fails deliveries once out of retries", func(t *testing.T) {
+		unavailable := httptest.NewServer(&receiver{statuses: []int{http.StatusBadGateway, http.StatusBadGateway}})
+		defer unavailable.Close()
+		invalid := httptest.NewServer(&receiver{statuses: []int{http.StatusBadRequest}})
+		defer invalid.Close()
+
+		bus := events.NewBus(events.DefaultBufferSize)
+		d, err := New(map[string]*config.Webhook{
+			"retries":   {URL: unavailable.URL, MaxRetries: config.NewOptionalInteger(1), RetryBackoff: config.NewOptionalDuration(time.Millisecond)},
+			"permanent": {URL: invalid.URL},
+		}, bus, "")
+		require.NoError(t, err)
+		defer d.Close()
+		d.Start()
+
+		bus.Emit(events.GCCompleted, events.GCEvent{})
+		st := waitStatus(t, d, "retries", func(s Status) bool { return s.Failed == 1 })
+		assert.Equal(t, 2, st.Recent[0].Attempts)
+		assert.Equal(t, DeliveryFailed, st.Recent[0].State)
+		assert.Equal(t, http.StatusBadGateway, st.Recent[0].StatusCode)
+		assert.Contains(t, st.Recent[0].Error, "502")
+
+		// client errors are not retried
+		st = waitStatus(t, d, "permanent", func(s Status) bool { return s.Failed == 1 })
+		assert.Equal(t, 1, st.Recent[0].Attempts)
+		assert.Equal(t, http.StatusBadRequest, st.Recent[0].StatusCode)
+	})
+
+	t.Run("validates the config", func(t *testing.T) {
+		for _, cfg := range []*config.Webhook{
+			nil,
+			{URL: "ftp://example.com"},
+			{URL: "/relative"},
+			{URL: "https://example.com", MaxRetries: config.NewOptionalInteger(-1)},
+			{URL: "https://example.com", Timeout: config.NewOptionalDuration(0)},
+		} {
+			_, err := New(map[string]*config.Webhook{"hook": cfg}, nil, "")
+			assert.Error(t, err, cfg)
+		}
+	})
+
+	t.Run("a nil dispatcher sends no events", func(t *testing.T) {
+		var d *Dispatcher
+		d.Start()
+		assert.Empty(t, d.Status())
+		assert.NoError(t, d.Close())
+	})
+}
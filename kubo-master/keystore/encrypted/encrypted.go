@@ -0,0 +1,474 @@
+// Package encrypted implements a keystore.Keystore encrypting the keys with a
+// passphrase.
+//
+// The keys are stored in the same files as in a keystore.FSKeystore, sealed
+// with XChaCha20-Poly1305 under a key derived from the passphrase with
+// scrypt. The key derivation parameters are stored in the ParamsFile of the
+// keystore directory, whose presence marks the keystore as encrypted. The
+// identity of the node is stored in the IdentityFile.
+package encrypted
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	keystore "github.com/ipfs/boxo/keystore"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// ParamsFile is the file of the keystore directory holding the key
+	// derivation parameters.
+	ParamsFile = ".encryption"
+	// IdentityFile is the file of the keystore directory holding the
+	// identity of the node.
+	IdentityFile = ".identity"
+
+	keyFilenamePrefix = "key_"
+	version           = 1
+	kdfScrypt         = "scrypt"
+)
+
+// checkPlaintext is sealed in the ParamsFile to detect incorrect passphrases.
+var checkPlaintext = []byte("kubo encrypted keystore")
+
+// scryptN is the scrypt cost parameter of new keystores and envelopes.
+var scryptN = 1 << 15
+
+var (
+	// ErrLocked is returned when accessing the keys of a locked keystore.
+	ErrLocked = errors.New("keystore is locked")
+	// ErrIncorrectPassphrase is returned when the passphrase does not
+	// decrypt the keystore or an envelope.
+	ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+	// ErrNotEncrypted is returned by Open when the keystore is not encrypted.
+	ErrNotEncrypted = errors.New("keystore is not encrypted")
+)
+
+// envelope is data sealed under a key derived from a passphrase.
+type envelope struct {
+	Version int
+	KDF     string
+	N, R, P int
+	Salt    []byte
+	// Ciphertext is the nonce followed by the sealed data.
+	Ciphertext []byte
+}
+
+func newEnvelope() (*envelope, error) {
+	env := &envelope{Version: version, KDF: kdfScrypt, N: scryptN, R: 8, P: 1, Salt: make([]byte, 16)}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+func (env *envelope) aead(passphrase []byte) (cipher.AEAD, error) {
+	if env.Version != version || env.KDF != kdfScrypt {
+		return nil, fmt.Errorf("unsupported encryption: version %d, %s", env.Version, env.KDF)
+	}
+	key, err := scrypt.Key(passphrase, env.Salt, env.N, env.R, env.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key)
+}
+
+func seal(aead cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrIncorrectPassphrase
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, ad)
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+	return plaintext, nil
+}
+
+// Seal encrypts data with the given passphrase, into a self-contained
+// envelope to be decrypted with Unseal.
+func Seal(passphrase, data []byte) ([]byte, error) {
+	env, err := newEnvelope()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := env.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if env.Ciphertext, err = seal(aead, data, nil); err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// Unseal decrypts an envelope created by Seal.
+func Unseal(passphrase, data []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("not an encrypted envelope: %w", err)
+	}
+	aead, err := env.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, env.Ciphertext, nil)
+}
+
+// IsEncrypted returns whether the keystore in dir is encrypted.
+func IsEncrypted(dir string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dir, ParamsFile))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Keystore is an encrypted keystore.Keystore. It is locked until Unlock is
+// called with its passphrase: until then, keys can be listed but not read or
+// written.
+type Keystore struct {
+	dir string
+
+	mu   sync.RWMutex
+	aead cipher.AEAD
+}
+
+var _ keystore.Keystore = (*Keystore)(nil)
+
+// Open returns the locked encrypted keystore in dir.
+func Open(dir string) (*Keystore, error) {
+	ok, err := IsEncrypted(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotEncrypted
+	}
+	return &Keystore{dir: dir}, nil
+}
+
+// Unlock derives the encryption key of the keystore from passphrase, and
+// returns ErrIncorrectPassphrase if it does not match.
+func (ks *Keystore) Unlock(passphrase []byte) error {
+	data, err := os.ReadFile(filepath.Join(ks.dir, ParamsFile))
+	if err != nil {
+		return err
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("reading %s: %w", ParamsFile, err)
+	}
+	aead, err := env.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	if _, err := open(aead, env.Ciphertext, []byte(ParamsFile)); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.aead = aead
+	return nil
+}
+
+// Locked returns whether the keystore still needs to be unlocked.
+func (ks *Keystore) Locked() bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.aead == nil
+}
+
+func (ks *Keystore) cipher() (cipher.AEAD, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if ks.aead == nil {
+		return nil, ErrLocked
+	}
+	return ks.aead, nil
+}
+
+// Has returns whether or not a key exists in the Keystore
+func (ks *Keystore) Has(name string) (bool, error) {
+	fn, err := encode(name)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(filepath.Join(ks.dir, fn))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Put stores a key in the Keystore, if a key with the same name already
+// exists, returns keystore.ErrKeyExists
+func (ks *Keystore) Put(name string, k ci.PrivKey) error {
+	fn, err := encode(name)
+	if err != nil {
+		return err
+	}
+	data, err := ks.seal(fn, k)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(ks.dir, fn), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o400)
+	if err != nil {
+		if os.IsExist(err) {
+			err = keystore.ErrKeyExists
+		}
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return err
+}
+
+// Get retrieves a key from the Keystore if it exists, and returns
+// keystore.ErrNoSuchKey otherwise.
+func (ks *Keystore) Get(name string) (ci.PrivKey, error) {
+	fn, err := encode(name)
+	if err != nil {
+		return nil, err
+	}
+	return ks.get(fn, keystore.ErrNoSuchKey)
+}
+
+// Delete removes a key from the Keystore
+func (ks *Keystore) Delete(name string) error {
+	fn, err := encode(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(ks.dir, fn))
+}
+
+// List return a list of key identifier
+func (ks *Keystore) List() ([]string, error) {
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if name, err := decode(e.Name()); err == nil {
+			list = append(list, name)
+		}
+	}
+	return list, nil
+}
+
+// Identity returns the identity of the node, or nil if it is not stored in
+// the keystore.
+func (ks *Keystore) Identity() (ci.PrivKey, error) {
+	return ks.get(IdentityFile, nil)
+}
+
+// SetIdentity replaces the identity of the node.
+func (ks *Keystore) SetIdentity(k ci.PrivKey) error {
+	data, err := ks.seal(IdentityFile, k)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(ks.dir, IdentityFile), data)
+}
+
+func (ks *Keystore) seal(fn string, k ci.PrivKey) ([]byte, error) {
+	aead, err := ks.cipher()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ci.MarshalPrivateKey(k)
+	if err != nil {
+		return nil, err
+	}
+	// the file name is authenticated to prevent swapping key files
+	return seal(aead, b, []byte(fn))
+}
+
+func (ks *Keystore) get(fn string, errNotFound error) (ci.PrivKey, error) {
+	aead, err := ks.cipher()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(ks.dir, fn))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errNotFound
+		}
+		return nil, err
+	}
+	b, err := open(aead, data, []byte(fn))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", fn, err)
+	}
+	return ci.UnmarshalPrivateKey(b)
+}
+
+// Encrypt encrypts the plaintext keystore in dir with passphrase, and stores
+// identity in it if it is not nil. It returns the unlocked keystore.
+func Encrypt(dir string, passphrase []byte, identity ci.PrivKey) (*Keystore, error) {
+	if ok, err := IsEncrypted(dir); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, errors.New("keystore is already encrypted")
+	}
+	src, err := keystore.NewFSKeystore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := newEnvelope()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := env.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if env.Ciphertext, err = seal(aead, checkPlaintext, []byte(ParamsFile)); err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = replaceDir(dir, func(tmp string) error {
+		if err := os.WriteFile(filepath.Join(tmp, ParamsFile), params, 0o400); err != nil {
+			return err
+		}
+		dst := &Keystore{dir: tmp, aead: aead}
+		if identity != nil {
+			if err := dst.SetIdentity(identity); err != nil {
+				return err
+			}
+		}
+		return copyKeys(src, dst)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Keystore{dir: dir, aead: aead}, nil
+}
+
+// Decrypt turns the keystore back into a plaintext keystore.FSKeystore. It
+// returns the identity of the node, which is not part of FSKeystore, or nil
+// if the keystore did not store it.
+func (ks *Keystore) Decrypt() (ci.PrivKey, error) {
+	identity, err := ks.Identity()
+	if err != nil {
+		return nil, err
+	}
+	err = replaceDir(ks.dir, func(tmp string) error {
+		dst, err := keystore.NewFSKeystore(tmp)
+		if err != nil {
+			return err
+		}
+		return copyKeys(ks, dst)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func copyKeys(src, dst keystore.Keystore) error {
+	names, err := src.List()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		k, err := src.Get(name)
+		if err != nil {
+			return fmt.Errorf("reading key %q: %w", name, err)
+		}
+		if err := dst.Put(name, k); err != nil {
+			return fmt.Errorf("writing key %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// replaceDir replaces dir with a new directory filled by fill.
+func replaceDir(dir string, fill func(tmp string) error) error {
+	tmp := dir + ".new"
+	old := dir + ".old"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.Mkdir(tmp, 0o700); err != nil {
+		return err
+	}
+	if err := fill(tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(dir, old); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		// put the original keystore back
+		os.Rename(old, dir)
+		os.RemoveAll(tmp)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+func writeFileAtomic(fn string, data []byte) error {
+	tmp := fn + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.WriteFile(tmp, data, 0o400); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// encode and decode mirror the file names of keystore.FSKeystore.
+
+func encode(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("key name must be at least one character")
+	}
+	return keyFilenamePrefix + strings.ToLower(codec.EncodeToString([]byte(name))), nil
+}
+
+func decode(fn string) (string, error) {
+	if !strings.HasPrefix(fn, keyFilenamePrefix) {
+		return "", errors.New("key's filename has unexpected format")
+	}
+	b, err := codec.DecodeString(strings.ToUpper(fn[len(keyFilenamePrefix):]))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+var codec = base32.StdEncoding.WithPadding(base32.NoPadding)
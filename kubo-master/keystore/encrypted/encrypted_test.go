@@ -0,0 +1,141 @@
+package encrypted
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	keystore "github.com/ipfs/boxo/keystore"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+)
+
+func init() {
+	// keep the tests fast
+	scryptN = 1 << 10
+}
+
+func genKey(t *testing.T) ci.PrivKey {
+	k, _, err := ci.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keystore")
+	plain, err := keystore.NewFSKeystore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foo, identity := genKey(t), genKey(t)
+	if err := plain.Put("foo", foo); err != nil {
+		t.Fatal(err)
+	}
+	fooFile, err := os.ReadFile(filepath.Join(dir, "key_mzxw6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("expected ErrNotEncrypted, got %v", err)
+	}
+	if _, err := Encrypt(dir, []byte("secret"), identity); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Encrypt(dir, []byte("secret"), identity); err == nil {
+		t.Fatal("expected an already encrypted keystore to be rejected")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "key_mzxw6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, fooFile) {
+		t.Fatal("key is stored in plaintext")
+	}
+
+	ks, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, err := ks.List(); err != nil || !slices.Equal(names, []string{"foo"}) {
+		t.Fatalf("unexpected list %v (%v)", names, err)
+	}
+	if _, err := ks.Get("foo"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	if err := ks.Unlock([]byte("wrong")); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Fatalf("expected ErrIncorrectPassphrase, got %v", err)
+	}
+	if err := ks.Unlock([]byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	if k, err := ks.Get("foo"); err != nil || !k.Equals(foo) {
+		t.Fatalf("unexpected key (%v)", err)
+	}
+	if k, err := ks.Identity(); err != nil || !k.Equals(identity) {
+		t.Fatalf("unexpected identity (%v)", err)
+	}
+	if _, err := ks.Get("bar"); !errors.Is(err, keystore.ErrNoSuchKey) {
+		t.Fatalf("expected ErrNoSuchKey, got %v", err)
+	}
+	bar := genKey(t)
+	if err := ks.Put("bar", bar); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Put("bar", bar); !errors.Is(err, keystore.ErrKeyExists) {
+		t.Fatalf("expected ErrKeyExists, got %v", err)
+	}
+
+	// a key file moved to another name does not decrypt
+	if err := os.Remove(filepath.Join(dir, "key_mjqxe")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key_mjqxe"), data, 0o400); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Get("bar"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Fatalf("expected swapped key file to be rejected, got %v", err)
+	}
+	if err := ks.Delete("bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ks.Decrypt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equals(identity) {
+		t.Fatal("unexpected identity")
+	}
+	if ok, err := IsEncrypted(dir); err != nil || ok {
+		t.Fatalf("expected plaintext keystore (%v)", err)
+	}
+	if k, err := plain.Get("foo"); err != nil || !k.Equals(foo) {
+		t.Fatalf("unexpected key (%v)", err)
+	}
+	if names, err := plain.List(); err != nil || !slices.Equal(names, []string{"foo"}) {
+		t.Fatalf("unexpected list %v (%v)", names, err)
+	}
+}
+
+func TestSeal(t *testing.T) {
+	data, err := Seal([]byte("secret"), []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unseal([]byte("wrong"), data); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Fatalf("expected ErrIncorrectPassphrase, got %v", err)
+	}
+	got, err := Unseal([]byte("secret"), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("unexpected data %q", got)
+	}
+}
@@ -0,0 +1,245 @@
+// Package remotesigner implements a keystore.Keystore whose private keys are
+// held by an external signer process, so that they never enter the daemon.
+//
+// The signer listens on a unix socket. Each connection carries a single JSON
+// Request, answered with a single JSON Response:
+//
+//	{"Method": "list"}
+//	  -> {"Keys": [{"Name": "foo", "PublicKey": "<base64 libp2p public key>"}]}
+//	{"Method": "sign", "Key": "foo", "Data": "<base64>"}
+//	  -> {"Signature": "<base64>"}
+//
+// A failed request is answered with {"Error": "..."}. The key named "self",
+// if any, is the identity of the node.
+package remotesigner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	keystore "github.com/ipfs/boxo/keystore"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+	pb "github.com/libp2p/go-libp2p/core/crypto/pb"
+)
+
+const (
+	// MethodList lists the keys of the signer.
+	MethodList = "list"
+	// MethodSign signs Data with Key.
+	MethodSign = "sign"
+
+	// SelfKey is the name of the key holding the identity of the node.
+	SelfKey = "self"
+)
+
+// Timeout bounds each request to the signer.
+var Timeout = 30 * time.Second
+
+var (
+	// ErrReadOnly is returned when adding or removing keys, which are
+	// managed by the signer.
+	ErrReadOnly = errors.New("keys are managed by the remote signer")
+	// ErrNotExportable is returned by PrivKey.Raw.
+	ErrNotExportable = errors.New("private key is held by the remote signer and cannot be exported")
+)
+
+// Request is a request to the signer.
+type Request struct {
+	Method string
+	Key    string `json:",omitempty"`
+	Data   []byte `json:",omitempty"`
+}
+
+// KeyInfo describes a key of the signer.
+type KeyInfo struct {
+	Name string
+	// PublicKey is the public key, marshaled with crypto.MarshalPublicKey.
+	PublicKey []byte
+}
+
+// Response is the response of the signer.
+type Response struct {
+	Keys      []KeyInfo `json:",omitempty"`
+	Signature []byte    `json:",omitempty"`
+	Error     string    `json:",omitempty"`
+}
+
+// Client sends requests to the signer listening on a unix socket.
+type Client struct {
+	socket string
+}
+
+// NewClient returns a client of the signer listening on socket.
+func NewClient(socket string) *Client {
+	return &Client{socket: socket}
+}
+
+func (c *Client) call(req Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", c.socket, Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to remote signer: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
+		return nil, err
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("sending request to remote signer: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("reading response of remote signer: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("remote signer: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// List returns the keys of the signer.
+func (c *Client) List() ([]KeyInfo, error) {
+	resp, err := c.call(Request{Method: MethodList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// Sign signs data with the given key of the signer.
+func (c *Client) Sign(key string, data []byte) ([]byte, error) {
+	resp, err := c.call(Request{Method: MethodSign, Key: key, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// Keystore is a read-only keystore.Keystore listing the keys of the signer.
+// The key named SelfKey is left out, as the keystore does not hold the
+// identity of the node.
+type Keystore struct {
+	client *Client
+}
+
+var _ keystore.Keystore = (*Keystore)(nil)
+
+// NewKeystore returns the keystore of the signer listening on socket. The
+// signer is only contacted when the keys are accessed.
+func NewKeystore(socket string) *Keystore {
+	return &Keystore{client: NewClient(socket)}
+}
+
+// Has returns whether or not a key exists in the Keystore
+func (ks *Keystore) Has(name string) (bool, error) {
+	names, err := ks.List()
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(names, name), nil
+}
+
+// Put returns ErrReadOnly.
+func (ks *Keystore) Put(name string, k ci.PrivKey) error {
+	return ErrReadOnly
+}
+
+// Get returns the key of the signer with the given name, and
+// keystore.ErrNoSuchKey if there is none.
+func (ks *Keystore) Get(name string) (ci.PrivKey, error) {
+	if name == SelfKey {
+		return nil, keystore.ErrNoSuchKey
+	}
+	return ks.get(name)
+}
+
+// Identity returns the key of the signer holding the identity of the node.
+func (ks *Keystore) Identity() (ci.PrivKey, error) {
+	return ks.get(SelfKey)
+}
+
+func (ks *Keystore) get(name string) (ci.PrivKey, error) {
+	keys, err := ks.client.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.Name != name {
+			continue
+		}
+		pub, err := ci.UnmarshalPublicKey(k.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("public key of %q: %w", name, err)
+		}
+		return &PrivKey{client: ks.client, name: name, pub: pub}, nil
+	}
+	return nil, keystore.ErrNoSuchKey
+}
+
+// Delete returns ErrReadOnly.
+func (ks *Keystore) Delete(name string) error {
+	return ErrReadOnly
+}
+
+// List return a list of key identifier
+func (ks *Keystore) List() ([]string, error) {
+	keys, err := ks.client.List()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.Name != SelfKey {
+			names = append(names, k.Name)
+		}
+	}
+	return names, nil
+}
+
+// PrivKey is a private key held by the signer: its signatures are made by the
+// signer, and it cannot be exported.
+type PrivKey struct {
+	client *Client
+	name   string
+	pub    ci.PubKey
+}
+
+var _ ci.PrivKey = (*PrivKey)(nil)
+
+// Sign asks the signer to sign data, and checks the signature.
+func (k *PrivKey) Sign(data []byte) ([]byte, error) {
+	sig, err := k.client.Sign(k.name, data)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := k.pub.Verify(data, sig); err != nil || !ok {
+		return nil, fmt.Errorf("remote signer returned an invalid signature for %q", k.name)
+	}
+	return sig, nil
+}
+
+// GetPublic returns the public key.
+func (k *PrivKey) GetPublic() ci.PubKey {
+	return k.pub
+}
+
+// Type returns the type of the key.
+func (k *PrivKey) Type() pb.KeyType {
+	return k.pub.Type()
+}
+
+// Raw returns ErrNotExportable.
+func (k *PrivKey) Raw() ([]byte, error) {
+	return nil, ErrNotExportable
+}
+
+// Equals returns whether k and o are the same private key, comparing their
+// public keys.
+func (k *PrivKey) Equals(o ci.Key) bool {
+	sk, ok := o.(ci.PrivKey)
+	return ok && k.pub.Equals(sk.GetPublic())
+}
@@ -0,0 +1,85 @@
+package remotesigner
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	keystore "github.com/ipfs/boxo/keystore"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	backend := keystore.NewMemKeystore()
+	foo, _, err := ci.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, _, err := ci.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Put("foo", foo); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Put(SelfKey, self); err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "signer.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go Serve(l, backend)
+
+	ks := NewKeystore(socket)
+	if names, err := ks.List(); err != nil || !slices.Equal(names, []string{"foo"}) {
+		t.Fatalf("unexpected list %v (%v)", names, err)
+	}
+	if ok, err := ks.Has("foo"); err != nil || !ok {
+		t.Fatalf("expected foo to exist (%v)", err)
+	}
+	if _, err := ks.Get("bar"); !errors.Is(err, keystore.ErrNoSuchKey) {
+		t.Fatalf("expected ErrNoSuchKey, got %v", err)
+	}
+	if err := ks.Put("bar", foo); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+
+	sk, err := ks.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sk.Equals(foo) || !sk.GetPublic().Equals(foo.GetPublic()) {
+		t.Fatal("unexpected key")
+	}
+	sig, err := sk.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := foo.GetPublic().Verify([]byte("hello"), sig); err != nil || !ok {
+		t.Fatalf("invalid signature (%v)", err)
+	}
+	if _, err := ci.MarshalPrivateKey(sk); err == nil {
+		t.Fatal("expected the key not to be exportable")
+	}
+
+	id, err := ks.Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.Equals(self) {
+		t.Fatal("unexpected identity")
+	}
+
+	if err := backend.Delete("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sk.Sign([]byte("hello")); err == nil {
+		t.Fatal("expected signing with a removed key to fail")
+	}
+}
@@ -0,0 +1,85 @@
+package remotesigner
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	keystore "github.com/ipfs/boxo/keystore"
+	logging "github.com/ipfs/go-log/v2"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+)
+
+var log = logging.Logger("remotesigner")
+
+// Serve answers the requests of the connections accepted by l with the keys
+// of ks, until l is closed.
+func Serve(l net.Listener, ks keystore.Keystore) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serveConn(conn, ks)
+	}
+}
+
+func serveConn(conn net.Conn, ks keystore.Keystore) {
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		log.Debugf("reading request: %s", err)
+		return
+	}
+	resp, err := handle(req, ks)
+	if err != nil {
+		resp = &Response{Error: err.Error()}
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		log.Debugf("writing response: %s", err)
+	}
+}
+
+func handle(req Request, ks keystore.Keystore) (*Response, error) {
+	switch req.Method {
+	case MethodList:
+		names, err := ks.List()
+		if err != nil {
+			return nil, err
+		}
+		resp := &Response{Keys: make([]KeyInfo, 0, len(names))}
+		for _, name := range names {
+			sk, err := ks.Get(name)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := ci.MarshalPublicKey(sk.GetPublic())
+			if err != nil {
+				return nil, err
+			}
+			resp.Keys = append(resp.Keys, KeyInfo{Name: name, PublicKey: pub})
+		}
+		return resp, nil
+	case MethodSign:
+		sk, err := ks.Get(req.Key)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("signing %d bytes with %q", len(req.Data), req.Key)
+		sig, err := sk.Sign(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Signature: sig}, nil
+	default:
+		return nil, errors.New("unknown method: " + req.Method)
+	}
+}
@@ -0,0 +1,235 @@
+// Package alias implements a local registry of names for content paths, so
+// that "alias:docs/readme.md" can be used in place of "/ipns/k51.../readme.md".
+package alias
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+const (
+	// Namespace is the namespace of alias paths.
+	Namespace = "alias"
+	// Prefix is the prefix of alias paths, as written by users.
+	Prefix = Namespace + ":"
+)
+
+// aliasPrefix is the datastore prefix of the alias table. The target of each
+// alias is stored under aliasPrefix/<name>.
+var aliasPrefix = datastore.NewKey("/local/aliases")
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ErrNotFound is returned when an alias is not registered.
+var ErrNotFound = errors.New("alias not found")
+
+// Alias associates a name to a content path.
+type Alias struct {
+	Name   string
+	Target string
+}
+
+// aliasPath is a [path.Path] starting with an alias, such as
+// "alias:docs/readme.md".
+type aliasPath struct {
+	segments []string
+}
+
+var _ path.Path = aliasPath{}
+
+// NewPath parses a path starting with an alias, such as
+// "alias:docs/readme.md".
+func NewPath(str string) (path.Path, error) {
+	rest, ok := strings.CutPrefix(str, Prefix)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an alias path", str)
+	}
+	segments := path.StringToSegments(rest)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%q is missing the alias name", str)
+	}
+	if err := checkName(segments[0]); err != nil {
+		return nil, err
+	}
+	return aliasPath{segments: segments}, nil
+}
+
+// IsPath returns whether str is written as an alias path.
+func IsPath(str string) bool {
+	return strings.HasPrefix(str, Prefix)
+}
+
+func (p aliasPath) String() string {
+	return Prefix + strings.Join(p.segments, "/")
+}
+
+func (p aliasPath) Namespace() string {
+	return Namespace
+}
+
+func (p aliasPath) Mutable() bool {
+	return true
+}
+
+func (p aliasPath) Segments() []string {
+	return append([]string{Namespace}, p.segments...)
+}
+
+// Resolve replaces the alias of p with its target. Paths that do not start
+// with an alias are returned as is.
+func Resolve(ctx context.Context, ds datastore.Datastore, p path.Path) (path.Path, error) {
+	if p.Namespace() != Namespace {
+		return p, nil
+	}
+	segments := p.Segments()[1:]
+	target, err := Get(ctx, ds, segments[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 1 {
+		return target, nil
+	}
+	return path.Join(target, segments[1:]...)
+}
+
+// Set registers name as an alias of target, replacing any previous target.
+func Set(ctx context.Context, ds datastore.Datastore, name string, target path.Path) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if target.Namespace() == Namespace {
+		return errors.New("the target of an alias cannot be another alias")
+	}
+	key := aliasPrefix.ChildString(name)
+	if err := ds.Put(ctx, key, []byte(target.String())); err != nil {
+		return err
+	}
+	return ds.Sync(ctx, key)
+}
+
+// Get returns the target of the given alias.
+func Get(ctx context.Context, ds datastore.Datastore, name string) (path.Path, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	data, err := ds.Get(ctx, aliasPrefix.ChildString(name))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return nil, err
+	}
+	return path.NewPath(string(data))
+}
+
+// Remove unregisters the given alias.
+func Remove(ctx context.Context, ds datastore.Datastore, name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	key := aliasPrefix.ChildString(name)
+	has, err := ds.Has(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err = ds.Delete(ctx, key); err != nil {
+		return err
+	}
+	return ds.Sync(ctx, key)
+}
+
+// List returns the registered aliases, sorted by name.
+func List(ctx context.Context, ds datastore.Datastore) ([]Alias, error) {
+	results, err := ds.Query(ctx, query.Query{
+		Prefix: aliasPrefix.String(),
+		Orders: []query.Order{query.OrderByKey{}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var aliases []Alias
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		aliases = append(aliases, Alias{
+			Name:   datastore.RawKey(r.Key).Name(),
+			Target: string(r.Value),
+		})
+	}
+	return aliases, nil
+}
+
+// Export returns the alias table as JSON, as read by [Import].
+func Export(ctx context.Context, ds datastore.Datastore) ([]byte, error) {
+	aliases, err := List(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	if aliases == nil {
+		aliases = []Alias{}
+	}
+	return json.MarshalIndent(aliases, "", "  ")
+}
+
+// Import registers the aliases of a table written by [Export]. Unless force
+// is set, nothing is registered if an alias of the table is already
+// registered with a different target. It returns the imported aliases.
+func Import(ctx context.Context, ds datastore.Datastore, data []byte, force bool) ([]Alias, error) {
+	var aliases []Alias
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("invalid alias table: %w", err)
+	}
+
+	targets := make([]path.Path, len(aliases))
+	for i, a := range aliases {
+		if err := checkName(a.Name); err != nil {
+			return nil, err
+		}
+		target, err := path.NewPath(a.Target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target of alias %s: %w", a.Name, err)
+		}
+		targets[i] = target
+		if force {
+			continue
+		}
+		current, err := Get(ctx, ds, a.Name)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if current.String() != target.String() {
+			return nil, fmt.Errorf("alias %s is already registered for %s", a.Name, current)
+		}
+	}
+
+	for i, a := range aliases {
+		if err := Set(ctx, ds, a.Name, targets[i]); err != nil {
+			return nil, err
+		}
+	}
+	return aliases, nil
+}
+
+func checkName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid alias name %q: must start with a letter or digit, and contain only letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
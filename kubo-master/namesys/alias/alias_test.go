@@ -0,0 +1,113 @@
+package alias
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-datastore"
+)
+
+const (
+	ipnsTarget = "/ipns/k51qzi5uqu5dgutdk6i1ynyzgkqngpha5xpgia3a5qqp4jsh0u4csozksxel3r"
+	ipfsTarget = "/ipfs/bafkqaaa"
+)
+
+func mustPath(t *testing.T, s string) path.Path {
+	p, err := path.NewPath(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestNewPath(t *testing.T) {
+	p, err := NewPath("alias:docs/a/b/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "alias:docs/a/b" || p.Namespace() != Namespace || !p.Mutable() {
+		t.Fatalf("unexpected path %s", p)
+	}
+	if segs := p.Segments(); len(segs) != 4 || segs[1] != "docs" {
+		t.Fatalf("unexpected segments %v", segs)
+	}
+
+	for _, s := range []string{"docs", "alias:", "alias:-docs"} {
+		if _, err = NewPath(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+
+	if err := Set(ctx, ds, "docs", mustPath(t, ipnsTarget)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(ctx, ds, "bad/name", mustPath(t, ipnsTarget)); err == nil {
+		t.Fatal("expected invalid name to fail")
+	}
+	ap, err := NewPath("alias:docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Set(ctx, ds, "loop", ap); err == nil {
+		t.Fatal("expected alias target to fail")
+	}
+
+	p, err := NewPath("alias:docs/readme.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := Resolve(ctx, ds, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.String() != ipnsTarget+"/readme.md" {
+		t.Fatalf("unexpected resolved path %s", resolved)
+	}
+	if resolved, err = Resolve(ctx, ds, mustPath(t, ipfsTarget)); err != nil || resolved.String() != ipfsTarget {
+		t.Fatalf("expected non-alias path to be returned as is, got %v, %v", resolved, err)
+	}
+	unknown, _ := NewPath("alias:unknown")
+	if _, err = Resolve(ctx, ds, unknown); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Export and import.
+	data, err := Export(ctx, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := datastore.NewMapDatastore()
+	if err = Set(ctx, other, "docs", mustPath(t, ipfsTarget)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Import(ctx, other, data, false); err == nil {
+		t.Fatal("expected conflicting import to fail")
+	}
+	if _, err = Import(ctx, other, data, true); err != nil {
+		t.Fatal(err)
+	}
+	aliases, err := List(ctx, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 1 || aliases[0] != (Alias{Name: "docs", Target: ipnsTarget}) {
+		t.Fatalf("unexpected aliases %v", aliases)
+	}
+
+	if err = Remove(ctx, ds, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if err = Remove(ctx, ds, "docs"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if data, err = Export(ctx, ds); err != nil || string(data) != "[]" {
+		t.Fatalf("expected empty table, got %s, %v", data, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package history keeps track of the IPNS records published by this node, so
+// that earlier values of a name can be listed and published again.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// historyPrefix is the datastore prefix of the history entries. Entries of a
+// name are stored under historyPrefix/<name>/<publish time>, so
+// that they are listed in the order they were published.
+var historyPrefix = datastore.NewKey("/local/ipns-history")
+
+// Entry describes an IPNS record published by this node.
+type Entry struct {
+	Sequence  uint64
+	Value     string
+	Validity  time.Time
+	TTL       time.Duration
+	Published time.Time
+}
+
+// Add records the publication of rec for name at the given time.
+func Add(ctx context.Context, ds datastore.Datastore, name ipns.Name, rec *ipns.Record, published time.Time) error {
+	e := Entry{Published: published.UTC()}
+	var err error
+	if e.Sequence, err = rec.Sequence(); err != nil {
+		return err
+	}
+	value, err := rec.Value()
+	if err != nil {
+		return err
+	}
+	e.Value = value.String()
+	if e.Validity, err = rec.Validity(); err != nil {
+		return err
+	}
+	e.Validity = e.Validity.UTC()
+	if e.TTL, err = rec.TTL(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	key := nameKey(name).ChildString(fmt.Sprintf("%020d", published.UnixNano()))
+	if err = ds.Put(ctx, key, data); err != nil {
+		return err
+	}
+	return ds.Sync(ctx, key)
+}
+
+// List returns the history of name, oldest first.
+func List(ctx context.Context, ds datastore.Datastore, name ipns.Name) ([]Entry, error) {
+	results, err := ds.Query(ctx, query.Query{
+		Prefix: nameKey(name).String(),
+		Orders: []query.Order{query.OrderByKey{}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var entries []Entry
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		var e Entry
+		if err = json.Unmarshal(r.Value, &e); err != nil {
+			return nil, fmt.Errorf("invalid IPNS history entry %s: %w", r.Key, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Find returns the latest entry of the history of name with the given
+// sequence number.
+func Find(ctx context.Context, ds datastore.Datastore, name ipns.Name, seq uint64) (Entry, error) {
+	entries, err := List(ctx, ds, name)
+	if err != nil {
+		return Entry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Sequence == seq {
+			return entries[i], nil
+		}
+	}
+	return Entry{}, fmt.Errorf("no record with sequence number %d in the history of %s", seq, name)
+}
+
+func nameKey(name ipns.Name) datastore.Key {
+	return historyPrefix.ChildString(name.String())
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+
+	sk, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := ipns.NameFromPeer(pid)
+
+	values := []string{
+		"/ipfs/bafkqaaa",
+		"/ipfs/bafkqabtimvwgy3yk",
+		"/ipfs/bafkqaaa",
+	}
+	start := time.Now()
+	for i, v := range values {
+		p, err := path.NewPath(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec, err := ipns.NewRecord(sk, p, uint64(i), start.Add(time.Hour), time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = Add(ctx, ds, name, rec, start.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := List(ctx, ds, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(values) {
+		t.Fatalf("expected %d entries, got %d", len(values), len(entries))
+	}
+	for i, e := range entries {
+		if e.Sequence != uint64(i) || e.Value != values[i] || e.TTL != time.Minute {
+			t.Fatalf("unexpected entry %d: %+v", i, e)
+		}
+	}
+
+	e, err := Find(ctx, ds, name, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Value != values[1] {
+		t.Fatalf("unexpected value %s", e.Value)
+	}
+	if _, err = Find(ctx, ds, name, 3); err == nil {
+		t.Fatal("expected unknown sequence number to fail")
+	}
+
+	other, err := List(ctx, ds, ipns.NameFromPeer(peer.ID("other")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other) != 0 {
+		t.Fatal("expected empty history")
+	}
+}
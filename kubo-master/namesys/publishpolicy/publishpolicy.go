@@ -0,0 +1,56 @@
+// Package publishpolicy stores the state of the IPNS publish policies, which
+// keep IPNS names pointing to MFS paths (see Ipns.PublishPolicies).
+package publishpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+)
+
+// statePrefix is the datastore prefix of the policy states, stored under
+// statePrefix/<key name>.
+var statePrefix = datastore.NewKey("/local/ipns-publish-policies")
+
+// State is the state of the publish policy of a key.
+type State struct {
+	// MFS is the MFS path of the policy when it was last checked.
+	MFS string
+	// Value is the last value published by the policy.
+	Value string `json:",omitempty"`
+	// Published is when Value was published.
+	Published time.Time `json:",omitzero"`
+	// Pending is the value of the MFS path waiting to be published, and
+	// PendingSince when it was first seen.
+	Pending      string    `json:",omitempty"`
+	PendingSince time.Time `json:",omitzero"`
+	// Error is the error of the last attempt to publish Pending.
+	Error string `json:",omitempty"`
+}
+
+// Load returns the state of the policy of the given key, or a zero State if
+// it has not run yet.
+func Load(ctx context.Context, ds datastore.Datastore, key string) (State, error) {
+	var st State
+	data, err := ds.Get(ctx, statePrefix.ChildString(key))
+	if err != nil {
+		if errors.Is(err, datastore.ErrNotFound) {
+			return st, nil
+		}
+		return st, err
+	}
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+// Save stores the state of the policy of the given key.
+func Save(ctx context.Context, ds datastore.Datastore, key string, st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return ds.Put(ctx, statePrefix.ChildString(key), data)
+}
@@ -10,6 +10,8 @@ import (
 	pluginnopfs "github.com/ipfs/kubo/plugin/plugins/nopfs"
 	pluginpebbleds "github.com/ipfs/kubo/plugin/plugins/pebbleds"
 	pluginpeerlog "github.com/ipfs/kubo/plugin/plugins/peerlog"
+	plugins3ds "github.com/ipfs/kubo/plugin/plugins/s3ds"
+	pluginsqliteds "github.com/ipfs/kubo/plugin/plugins/sqliteds"
 )
 
 // DO NOT EDIT THIS FILE
@@ -23,6 +25,8 @@ func init() {
 	Preload(pluginflatfs.Plugins...)
 	Preload(pluginlevelds.Plugins...)
 	Preload(pluginpebbleds.Plugins...)
+	Preload(pluginsqliteds.Plugins...)
+	Preload(plugins3ds.Plugins...)
 	Preload(pluginpeerlog.Plugins...)
 	Preload(pluginfxtest.Plugins...)
 	Preload(pluginnopfs.Plugins...)
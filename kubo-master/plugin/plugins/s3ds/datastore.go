@@ -0,0 +1,319 @@
+package s3ds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// defaultWorkers is the number of concurrent requests used to commit a
+// batch.
+const defaultWorkers = 32
+
+// Config are the settings of an S3 datastore.
+type Config struct {
+	// Bucket is the name of the bucket, which must already exist.
+	Bucket string
+	// RootDirectory is a prefix prepended to every key in the bucket, so
+	// that several datastores can share a bucket.
+	RootDirectory string
+	Region        string
+	// Endpoint is the URL of the S3 API, such as http://localhost:9000 for
+	// a local MinIO. It defaults to AWS.
+	Endpoint string
+	// AccessKey and SecretKey are optional. When they are not set, the
+	// credentials are read from the AWS_* or MINIO_* environment
+	// variables, the AWS credentials file, or the instance metadata.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// PathStyle forces path-style requests (endpoint/bucket/key), which
+	// most S3 stand-ins require.
+	PathStyle bool
+	// Workers is the number of concurrent requests used to commit a batch.
+	Workers int
+}
+
+// Datastore stores every key as an object in an S3 bucket.
+type Datastore struct {
+	Config
+	client *minio.Client
+}
+
+var _ ds.Batching = (*Datastore)(nil)
+
+// NewDatastore returns a datastore for the bucket described by conf. No
+// request is made until the datastore is used.
+func NewDatastore(conf Config) (*Datastore, error) {
+	if conf.Bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	if conf.Workers <= 0 {
+		conf.Workers = defaultWorkers
+	}
+
+	host, secure := "s3.amazonaws.com", true
+	if conf.Endpoint != "" {
+		u, err := url.Parse(conf.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", conf.Endpoint, err)
+		}
+		if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid endpoint %q: expected http(s)://host[:port]", conf.Endpoint)
+		}
+		host, secure = u.Host, u.Scheme == "https"
+	}
+
+	var creds *credentials.Credentials
+	if conf.AccessKey != "" || conf.SecretKey != "" {
+		creds = credentials.NewStaticV4(conf.AccessKey, conf.SecretKey, conf.SessionToken)
+	} else {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		})
+	}
+
+	lookup := minio.BucketLookupAuto
+	if conf.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(host, &minio.Options{
+		Creds:        creds,
+		Secure:       secure,
+		Region:       conf.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Datastore{Config: conf, client: client}, nil
+}
+
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	_, err := d.client.PutObject(ctx, d.Bucket, d.s3Path(key.String()), bytes.NewReader(value), int64(len(value)), minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	return err
+}
+
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	return nil
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	obj, err := d.client.GetObject(ctx, d.Bucket, d.s3Path(key.String()), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, parseError(err)
+	}
+	defer obj.Close()
+
+	value, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	return value, nil
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	_, err := d.GetSize(ctx, key)
+	if errors.Is(err, ds.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	info, err := d.client.StatObject(ctx, d.Bucket, d.s3Path(key.String()), minio.StatObjectOptions{})
+	if err != nil {
+		return -1, parseError(err)
+	}
+	return int(info.Size), nil
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	return d.client.RemoveObject(ctx, d.Bucket, d.s3Path(key.String()), minio.RemoveObjectOptions{})
+}
+
+// Query lists the objects under the query prefix. Values are fetched one
+// object at a time, so prefer KeysOnly queries on large datastores.
+func (d *Datastore) Query(ctx context.Context, q dsq.Query) (dsq.Results, error) {
+	// A prefix of /foo matches /foo/bar but not /foobar.
+	prefix := cleanPrefix(q.Prefix)
+	listPrefix := d.s3Path(prefix)
+	if prefix != "/" || listPrefix != "" {
+		listPrefix += "/"
+	}
+	root := d.s3Path("/")
+
+	ctx, cancel := context.WithCancel(ctx)
+	objects := d.client.ListObjects(ctx, d.Bucket, minio.ListObjectsOptions{
+		Prefix:    listPrefix,
+		Recursive: true,
+	})
+
+	it := dsq.Iterator{
+		Next: func() (dsq.Result, bool) {
+			obj, ok := <-objects
+			if !ok {
+				return dsq.Result{}, false
+			}
+			if obj.Err != nil {
+				return dsq.Result{Error: parseError(obj.Err)}, true
+			}
+
+			e := dsq.Entry{
+				Key:  "/" + strings.TrimPrefix(strings.TrimPrefix(obj.Key, root), "/"),
+				Size: int(obj.Size),
+			}
+			if !q.KeysOnly {
+				value, err := d.Get(ctx, ds.RawKey(e.Key))
+				if err != nil {
+					return dsq.Result{Error: err}, true
+				}
+				e.Value = value
+				e.Size = len(value)
+			}
+			return dsq.Result{Entry: e}, true
+		},
+		Close: func() error {
+			cancel()
+			return nil
+		},
+	}
+
+	naive := q
+	naive.Prefix = ""
+	return dsq.NaiveQueryApply(naive, dsq.ResultsFromIterator(q, it)), nil
+}
+
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	return &batch{
+		ds:  d,
+		ops: make(map[string]batchOp),
+	}, nil
+}
+
+func (d *Datastore) Close() error {
+	return nil
+}
+
+// s3Path returns the object name of a datastore key.
+func (d *Datastore) s3Path(p string) string {
+	return strings.TrimPrefix(path.Join(d.RootDirectory, p), "/")
+}
+
+type batchOp struct {
+	value  []byte
+	delete bool
+}
+
+// batch buffers writes in memory and sends them concurrently on Commit.
+// S3 has no transactions, so a failed commit may be partially applied.
+type batch struct {
+	ds  *Datastore
+	ops map[string]batchOp
+}
+
+func (b *batch) Put(ctx context.Context, key ds.Key, value []byte) error {
+	b.ops[key.String()] = batchOp{value: value}
+	return nil
+}
+
+func (b *batch) Delete(ctx context.Context, key ds.Key) error {
+	b.ops[key.String()] = batchOp{delete: true}
+	return nil
+}
+
+func (b *batch) Commit(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan string)
+	errs := make(chan error, b.ds.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < b.ds.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range keys {
+				var err error
+				op := b.ops[k]
+				if op.delete {
+					err = b.ds.Delete(ctx, ds.RawKey(k))
+				} else {
+					err = b.ds.Put(ctx, ds.RawKey(k), op.value)
+				}
+				if err != nil {
+					select {
+					case errs <- fmt.Errorf("%s: %w", k, err):
+					default:
+					}
+					cancel()
+				}
+			}
+		}()
+	}
+
+loop:
+	for k := range b.ops {
+		select {
+		case keys <- k:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(keys)
+	wg.Wait()
+	close(errs)
+
+	var err error
+	for e := range errs {
+		err = errors.Join(err, e)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	b.ops = make(map[string]batchOp)
+	return nil
+}
+
+// parseError maps S3 "not found" errors to ds.ErrNotFound.
+func parseError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return ds.ErrNotFound
+	}
+	return err
+}
+
+// cleanPrefix normalizes a query prefix the same way as
+// dsq.NaiveQueryApply.
+func cleanPrefix(prefix string) string {
+	if prefix == "" {
+		return "/"
+	}
+	if prefix[0] != '/' {
+		prefix = "/" + prefix
+	}
+	return path.Clean(prefix)
+}
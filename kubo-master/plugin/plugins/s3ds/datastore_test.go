@@ -0,0 +1,255 @@
+package s3ds
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	dstest "github.com/ipfs/go-datastore/test"
+)
+
+// fakeS3 is a minimal in-memory stand-in for the S3 API, serving a single
+// bucket with path-style requests.
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string
+	Prefix                string
+	KeyCount              int
+	MaxKeys               int
+	IsTruncated           bool
+	NextContinuationToken string `xml:",omitempty"`
+	Contents              []listEntry
+}
+
+type listEntry struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int
+	StorageClass string
+}
+
+var lastModified = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if key == "" && r.Method == http.MethodGet {
+		f.list(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := readBody(r)
+		if err != nil {
+			f.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = data
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// readBody returns the object data of a PUT request, decoding the
+// aws-chunked encoding minio-go uses for signed uploads over plain HTTP.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Header.Get("X-Amz-Decoded-Content-Length") == "" {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2) // trailing CRLF
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	after := q.Get("continuation-token")
+	maxKeys := 1000
+	if mk, err := strconv.Atoi(q.Get("max-keys")); err == nil && mk > 0 {
+		maxKeys = mk
+	}
+
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	res := listBucketResult{Name: f.bucket, Prefix: prefix, MaxKeys: maxKeys}
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+		res.IsTruncated = true
+		res.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		res.Contents = append(res.Contents, listEntry{
+			Key:          k,
+			LastModified: lastModified.Format(time.RFC3339),
+			ETag:         `"etag"`,
+			Size:         len(f.objects[k]),
+			StorageClass: "STANDARD",
+		})
+	}
+	res.KeyCount = len(res.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func newDatastore(t *testing.T, root string) (*Datastore, *fakeS3) {
+	fake := &fakeS3{bucket: "test", objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	d, err := NewDatastore(Config{
+		Bucket:        "test",
+		RootDirectory: root,
+		Region:        "us-east-1",
+		Endpoint:      srv.URL,
+		AccessKey:     "access",
+		SecretKey:     "secret",
+		PathStyle:     true,
+		Workers:       4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, fake
+}
+
+func TestSuite(t *testing.T) {
+	d, _ := newDatastore(t, "")
+	dstest.SubtestAll(t, d)
+}
+
+func TestRootDirectory(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newDatastore(t, "node1")
+
+	if err := d.Put(ctx, ds.NewKey("/blocks/foo"), []byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put(ctx, ds.NewKey("/blocksfoo"), []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.objects["node1/blocks/foo"]; !ok {
+		t.Fatalf("expected object under root directory, got %v", fake.objects)
+	}
+
+	res, err := d.Query(ctx, dsq.Query{Prefix: "/blocks", KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != "/blocks/foo" || entries[0].Size != 3 {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+
+	if _, err := d.Get(ctx, ds.NewKey("/missing")); err != ds.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestConfigParser(t *testing.T) {
+	parse := (&s3dsPlugin{}).DatastoreConfigParser()
+
+	if _, err := parse(map[string]interface{}{"type": "s3ds"}); err == nil {
+		t.Fatal("expected missing bucket to fail")
+	}
+	if _, err := parse(map[string]interface{}{"bucket": "b", "workers": 1.5}); err == nil {
+		t.Fatal("expected fractional workers to fail")
+	}
+
+	c, err := parse(map[string]interface{}{
+		"bucket":    "b",
+		"endpoint":  "http://localhost:9000",
+		"secretKey": "secret",
+		"pathStyle": true,
+		"workers":   float64(8),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := c.DiskSpec()
+	if _, ok := spec["secretKey"]; ok {
+		t.Fatal("credentials must not be part of the disk spec")
+	}
+	if spec["endpoint"] != "http://localhost:9000" {
+		t.Fatalf("unexpected disk spec: %v", spec)
+	}
+}
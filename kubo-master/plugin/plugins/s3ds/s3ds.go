@@ -0,0 +1,107 @@
+package s3ds
+
+import (
+	"fmt"
+
+	"github.com/ipfs/kubo/plugin"
+	"github.com/ipfs/kubo/repo"
+	"github.com/ipfs/kubo/repo/fsrepo"
+)
+
+// Plugins is exported list of plugins that will be loaded.
+var Plugins = []plugin.Plugin{
+	&s3dsPlugin{},
+}
+
+type s3dsPlugin struct{}
+
+var _ plugin.PluginDatastore = (*s3dsPlugin)(nil)
+
+func (*s3dsPlugin) Name() string {
+	return "ds-s3"
+}
+
+func (*s3dsPlugin) Version() string {
+	return "0.1.0"
+}
+
+func (*s3dsPlugin) Init(_ *plugin.Environment) error {
+	return nil
+}
+
+func (*s3dsPlugin) DatastoreTypeName() string {
+	return "s3ds"
+}
+
+type datastoreConfig struct {
+	cfg Config
+}
+
+// DatastoreConfigParser returns a configuration stub for an S3 datastore
+// from the given parameters.
+func (*s3dsPlugin) DatastoreConfigParser() fsrepo.ConfigFromMap {
+	return func(params map[string]interface{}) (fsrepo.DatastoreConfig, error) {
+		var c datastoreConfig
+		var ok bool
+
+		c.cfg.Bucket, ok = params["bucket"].(string)
+		if !ok || c.cfg.Bucket == "" {
+			return nil, fmt.Errorf("'bucket' field is missing or not string")
+		}
+
+		for name, dst := range map[string]*string{
+			"rootDirectory": &c.cfg.RootDirectory,
+			"region":        &c.cfg.Region,
+			"endpoint":      &c.cfg.Endpoint,
+			"accessKey":     &c.cfg.AccessKey,
+			"secretKey":     &c.cfg.SecretKey,
+			"sessionToken":  &c.cfg.SessionToken,
+		} {
+			switch v := params[name].(type) {
+			case string:
+				*dst = v
+			case nil:
+			default:
+				return nil, fmt.Errorf("'%s' field is not string", name)
+			}
+		}
+
+		switch v := params["pathStyle"].(type) {
+		case bool:
+			c.cfg.PathStyle = v
+		case nil:
+		default:
+			return nil, fmt.Errorf("'pathStyle' field is not boolean")
+		}
+
+		switch v := params["workers"].(type) {
+		case float64:
+			if v < 0 || v != float64(int(v)) {
+				return nil, fmt.Errorf("'workers' field must be a positive integer")
+			}
+			c.cfg.Workers = int(v)
+		case int:
+			c.cfg.Workers = v
+		case nil:
+		default:
+			return nil, fmt.Errorf("'workers' field is not a number")
+		}
+
+		return &c, nil
+	}
+}
+
+// DiskSpec only contains the fields that identify where the data lives.
+// Credentials and tuning may change without invalidating the repo.
+func (c *datastoreConfig) DiskSpec() fsrepo.DiskSpec {
+	return map[string]interface{}{
+		"type":          "s3ds",
+		"bucket":        c.cfg.Bucket,
+		"rootDirectory": c.cfg.RootDirectory,
+		"endpoint":      c.cfg.Endpoint,
+	}
+}
+
+func (c *datastoreConfig) Create(string) (repo.Datastore, error) {
+	return NewDatastore(c.cfg)
+}
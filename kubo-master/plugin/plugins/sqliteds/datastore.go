@@ -0,0 +1,333 @@
+package sqliteds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+
+	// pure-Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `CREATE TABLE IF NOT EXISTS data (
+	key   TEXT PRIMARY KEY NOT NULL,
+	value BLOB NOT NULL
+) WITHOUT ROWID`
+
+// Options are the settings of a SQLite datastore.
+type Options struct {
+	// Synchronous is the value of the SQLite synchronous pragma: "off",
+	// "normal" or "full". In WAL mode, "normal" only loses the latest
+	// transactions on power failure, and never corrupts the database.
+	Synchronous string
+}
+
+// Datastore is a datastore keeping all the keys in a single SQLite table.
+// The database is opened in WAL mode so that readers do not block writers.
+type Datastore struct {
+	db   *sql.DB
+	path string
+}
+
+var (
+	_ ds.Batching            = (*Datastore)(nil)
+	_ ds.PersistentDatastore = (*Datastore)(nil)
+)
+
+// NewDatastore opens, and creates if needed, the SQLite database at path.
+func NewDatastore(path string, opts Options) (*Datastore, error) {
+	sync := opts.Synchronous
+	if sync == "" {
+		sync = "normal"
+	}
+	switch sync {
+	case "off", "normal", "full":
+	default:
+		return nil, fmt.Errorf("invalid synchronous mode %q", sync)
+	}
+
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous("+sync+")")
+	params.Add("_pragma", "busy_timeout(10000)")
+	// take the write lock when a transaction starts, instead of failing
+	// with SQLITE_BUSY when a read transaction is upgraded
+	params.Set("_txlock", "immediate")
+
+	// SQLite decodes the escapes of file: URIs, so any path can be opened
+	dsn := &url.URL{Scheme: "file", Path: path, RawQuery: params.Encode()}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing sqlite datastore at %s: %w", path, err)
+	}
+
+	return &Datastore{db: db, path: path}, nil
+}
+
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	return put(ctx, d.db, key, value)
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	return del(ctx, d.db, key)
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	var value []byte
+	err := d.db.QueryRowContext(ctx, "SELECT value FROM data WHERE key = ?", key.String()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ds.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	var exists bool
+	err := d.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM data WHERE key = ?)", key.String()).Scan(&exists)
+	return exists, err
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	var size int
+	err := d.db.QueryRowContext(ctx, "SELECT length(value) FROM data WHERE key = ?", key.String()).Scan(&size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, ds.ErrNotFound
+	}
+	if err != nil {
+		return -1, err
+	}
+	return size, nil
+}
+
+// Query pushes the prefix down to SQLite as a key range, as well as orders,
+// offset and limit when they can be expressed in SQL. Everything else is
+// applied in memory.
+func (d *Datastore) Query(ctx context.Context, q dsq.Query) (dsq.Results, error) {
+	var (
+		sb   strings.Builder
+		args []any
+	)
+
+	switch {
+	case q.KeysOnly && q.ReturnsSizes:
+		sb.WriteString("SELECT key, length(value) FROM data")
+	case q.KeysOnly:
+		sb.WriteString("SELECT key FROM data")
+	default:
+		sb.WriteString("SELECT key, value FROM data")
+	}
+
+	// A prefix of /foo matches /foo/bar but not /foobar. '0' is the byte
+	// right after '/', so the range covers exactly the keys under prefix.
+	if prefix := cleanPrefix(q.Prefix); prefix != "/" {
+		sb.WriteString(" WHERE key >= ? AND key < ?")
+		args = append(args, prefix+"/", prefix+"0")
+	}
+
+	naive := q
+	naive.Prefix = ""
+	if order, ok := sqlOrder(q.Orders); ok && len(q.Filters) == 0 {
+		sb.WriteString(order)
+		switch {
+		case q.Limit > 0:
+			sb.WriteString(" LIMIT ?")
+			args = append(args, q.Limit)
+		case q.Offset > 0:
+			sb.WriteString(" LIMIT -1")
+		}
+		if q.Offset > 0 {
+			sb.WriteString(" OFFSET ?")
+			args = append(args, q.Offset)
+		}
+		naive.Orders = nil
+		naive.Limit = 0
+		naive.Offset = 0
+	}
+
+	rows, err := d.db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	it := dsq.Iterator{
+		Next: func() (dsq.Result, bool) {
+			if !rows.Next() {
+				if err := rows.Err(); err != nil {
+					return dsq.Result{Error: err}, true
+				}
+				return dsq.Result{}, false
+			}
+
+			var e dsq.Entry
+			var err error
+			switch {
+			case q.KeysOnly && q.ReturnsSizes:
+				err = rows.Scan(&e.Key, &e.Size)
+			case q.KeysOnly:
+				err = rows.Scan(&e.Key)
+			default:
+				err = rows.Scan(&e.Key, &e.Value)
+				if e.Value == nil {
+					e.Value = []byte{}
+				}
+				e.Size = len(e.Value)
+			}
+			if err != nil {
+				return dsq.Result{Error: err}, true
+			}
+			return dsq.Result{Entry: e}, true
+		},
+		Close: rows.Close,
+	}
+
+	return dsq.NaiveQueryApply(naive, dsq.ResultsFromIterator(q, it)), nil
+}
+
+// Sync checkpoints the write-ahead log into the database file.
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	_, err := d.db.ExecContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)")
+	return err
+}
+
+// DiskUsage returns the size of the database file and of its write-ahead
+// log.
+func (d *Datastore) DiskUsage(ctx context.Context) (uint64, error) {
+	var total uint64
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		st, err := os.Stat(d.path + suffix)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += uint64(st.Size())
+	}
+	return total, nil
+}
+
+func (d *Datastore) Close() error {
+	return d.db.Close()
+}
+
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	return &batch{
+		ds:  d,
+		ops: make(map[ds.Key]batchOp),
+	}, nil
+}
+
+type batchOp struct {
+	value  []byte
+	delete bool
+}
+
+// batch buffers writes in memory and applies them in a single transaction
+// on Commit.
+type batch struct {
+	ds  *Datastore
+	ops map[ds.Key]batchOp
+}
+
+func (b *batch) Put(ctx context.Context, key ds.Key, value []byte) error {
+	b.ops[key] = batchOp{value: value}
+	return nil
+}
+
+func (b *batch) Delete(ctx context.Context, key ds.Key) error {
+	b.ops[key] = batchOp{delete: true}
+	return nil
+}
+
+func (b *batch) Commit(ctx context.Context) error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+
+	tx, err := b.ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for k, op := range b.ops {
+		if op.delete {
+			err = del(ctx, tx, k)
+		} else {
+			err = put(ctx, tx, k, op.value)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.ops = make(map[ds.Key]batchOp)
+	return nil
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func put(ctx context.Context, e execer, key ds.Key, value []byte) error {
+	if value == nil {
+		// a nil []byte would be stored as NULL
+		value = []byte{}
+	}
+	_, err := e.ExecContext(ctx, "INSERT OR REPLACE INTO data (key, value) VALUES (?, ?)", key.String(), value)
+	return err
+}
+
+func del(ctx context.Context, e execer, key ds.Key) error {
+	_, err := e.ExecContext(ctx, "DELETE FROM data WHERE key = ?", key.String())
+	return err
+}
+
+// cleanPrefix normalizes a query prefix the same way as
+// dsq.NaiveQueryApply.
+func cleanPrefix(prefix string) string {
+	if prefix == "" {
+		return "/"
+	}
+	if prefix[0] != '/' {
+		prefix = "/" + prefix
+	}
+	return path.Clean(prefix)
+}
+
+// sqlOrder returns the ORDER BY clause for orders, if they only sort by key.
+func sqlOrder(orders []dsq.Order) (string, bool) {
+	switch len(orders) {
+	case 0:
+		return "", true
+	case 1:
+		switch orders[0].(type) {
+		case dsq.OrderByKey, *dsq.OrderByKey:
+			return " ORDER BY key ASC", true
+		case dsq.OrderByKeyDescending, *dsq.OrderByKeyDescending:
+			return " ORDER BY key DESC", true
+		}
+	}
+	return "", false
+}
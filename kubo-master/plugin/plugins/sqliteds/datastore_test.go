@@ -0,0 +1,118 @@
+package sqliteds
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	dstest "github.com/ipfs/go-datastore/test"
+)
+
+func newDatastore(t *testing.T) *Datastore {
+	d, err := NewDatastore(filepath.Join(t.TempDir(), "datastore.sqlite"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestSuite(t *testing.T) {
+	dstest.SubtestAll(t, newDatastore(t))
+}
+
+func TestPrefixRange(t *testing.T) {
+	ctx := context.Background()
+	d := newDatastore(t)
+
+	for _, k := range []string{"/foo", "/foo/bar", "/foo/bar/baz", "/foobar", "/foo0"} {
+		if err := d.Put(ctx, ds.NewKey(k), []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := d.Query(ctx, dsq.Query{Prefix: "/foo", KeysOnly: true, Orders: []dsq.Order{dsq.OrderByKey{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Key != "/foo/bar" || entries[1].Key != "/foo/bar/baz" {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	p := filepath.Join(t.TempDir(), "datastore.sqlite")
+
+	d, err := NewDatastore(p, Options{Synchronous: "full"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := d.Batch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(ctx, ds.NewKey("/a"), []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = NewDatastore(p, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	v, err := d.Get(ctx, ds.NewKey("/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "a" {
+		t.Fatalf("expected a, got %q", v)
+	}
+
+	if _, err := NewDatastore(p, Options{Synchronous: "sometimes"}); err == nil {
+		t.Fatal("expected invalid synchronous mode to fail")
+	}
+}
+
+func TestSpecialPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a?b#c%20 d")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "datastore.sqlite")
+
+	d, err := NewDatastore(p, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if err := d.Put(context.Background(), ds.NewKey("/a"), []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Fatal("expected the database at the given path:", err)
+	}
+
+	// the pragmas were not dropped
+	var mode string
+	if err := d.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Fatalf("expected wal journal mode, got %q", mode)
+	}
+}
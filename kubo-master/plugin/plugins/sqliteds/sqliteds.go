@@ -0,0 +1,82 @@
+package sqliteds
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/ipfs/kubo/plugin"
+	"github.com/ipfs/kubo/repo"
+	"github.com/ipfs/kubo/repo/fsrepo"
+)
+
+// Plugins is exported list of plugins that will be loaded.
+var Plugins = []plugin.Plugin{
+	&sqlitedsPlugin{},
+}
+
+type sqlitedsPlugin struct{}
+
+var _ plugin.PluginDatastore = (*sqlitedsPlugin)(nil)
+
+func (*sqlitedsPlugin) Name() string {
+	return "ds-sqlite"
+}
+
+func (*sqlitedsPlugin) Version() string {
+	return "0.1.0"
+}
+
+func (*sqlitedsPlugin) Init(_ *plugin.Environment) error {
+	return nil
+}
+
+func (*sqlitedsPlugin) DatastoreTypeName() string {
+	return "sqliteds"
+}
+
+type datastoreConfig struct {
+	path        string
+	synchronous string
+}
+
+// DatastoreConfigParser returns a configuration stub for a sqlite datastore
+// from the given parameters.
+func (*sqlitedsPlugin) DatastoreConfigParser() fsrepo.ConfigFromMap {
+	return func(params map[string]interface{}) (fsrepo.DatastoreConfig, error) {
+		var c datastoreConfig
+		var ok bool
+
+		c.path, ok = params["path"].(string)
+		if !ok {
+			return nil, fmt.Errorf("'path' field is missing or not string")
+		}
+
+		switch sync := params["synchronous"]; sync {
+		case "off", "normal", "full":
+			c.synchronous = sync.(string)
+		case "", nil:
+		default:
+			return nil, fmt.Errorf("unrecognized value for synchronous: %v", sync)
+		}
+
+		return &c, nil
+	}
+}
+
+func (c *datastoreConfig) DiskSpec() fsrepo.DiskSpec {
+	return map[string]interface{}{
+		"type": "sqliteds",
+		"path": c.path,
+	}
+}
+
+func (c *datastoreConfig) Create(path string) (repo.Datastore, error) {
+	p := c.path
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(path, p)
+	}
+
+	return NewDatastore(p, Options{
+		Synchronous: c.synchronous,
+	})
+}
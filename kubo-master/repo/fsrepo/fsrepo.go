@@ -2,6 +2,7 @@ package fsrepo
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -14,9 +15,12 @@ import (
 
 	filestore "github.com/ipfs/boxo/filestore"
 	keystore "github.com/ipfs/boxo/keystore"
+	enckeystore "github.com/ipfs/kubo/keystore/encrypted"
+	"github.com/ipfs/kubo/keystore/remotesigner"
 	repo "github.com/ipfs/kubo/repo"
 	"github.com/ipfs/kubo/repo/common"
 	dir "github.com/ipfs/kubo/thirdparty/dir"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
 
 	ds "github.com/ipfs/go-datastore"
@@ -113,7 +117,10 @@ type FSRepo struct {
 	filemgr               *filestore.FileManager
 }
 
-var _ repo.Repo = (*FSRepo)(nil)
+var (
+	_ repo.Repo              = (*FSRepo)(nil)
+	_ repo.KeystoreEncrypter = (*FSRepo)(nil)
+)
 
 // Open the FSRepo at path. Returns an error if the repo is not
 // initialized.
@@ -472,7 +479,23 @@ func (r *FSRepo) openUserResourceOverrides() error {
 }
 
 func (r *FSRepo) openKeystore() error {
+	if socket := r.config.Keystore.RemoteSigner.WithDefault(""); socket != "" {
+		r.keystore = remotesigner.NewKeystore(socket)
+		return nil
+	}
+
 	ksp := filepath.Join(r.path, "keystore")
+	encrypted, err := enckeystore.IsEncrypted(ksp)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		// the keystore, and the identity it holds, are available once
+		// UnlockKeystore is called
+		r.keystore, err = enckeystore.Open(ksp)
+		return err
+	}
+
 	ks, err := keystore.NewFSKeystore(ksp)
 	if err != nil {
 		return err
@@ -483,6 +506,143 @@ func (r *FSRepo) openKeystore() error {
 	return nil
 }
 
+// KeystoreLocked returns whether the keystore is encrypted and still needs to
+// be unlocked with UnlockKeystore.
+func (r *FSRepo) KeystoreLocked() bool {
+	ks, ok := r.keystore.(*enckeystore.Keystore)
+	return ok && ks.Locked()
+}
+
+// UnlockKeystore unlocks the encrypted keystore with passphrase, and loads the
+// identity it holds in Identity.PrivKey. The identity is only kept in memory:
+// it is never written back to the config file.
+func (r *FSRepo) UnlockKeystore(passphrase []byte) error {
+	ks, ok := r.keystore.(*enckeystore.Keystore)
+	if !ok {
+		return enckeystore.ErrNotEncrypted
+	}
+	if err := ks.Unlock(passphrase); err != nil {
+		return err
+	}
+	sk, err := ks.Identity()
+	if err != nil || sk == nil {
+		return err
+	}
+	privKey, err := encodePrivKey(sk)
+	if err != nil {
+		return err
+	}
+
+	packageLock.Lock()
+	defer packageLock.Unlock()
+	// Do not modify the *shared* config returned by `r.Config`.
+	conf, err := r.config.Clone()
+	if err != nil {
+		return err
+	}
+	conf.Identity.PrivKey = privKey
+	r.config = conf
+	return nil
+}
+
+// EncryptKeystore encrypts the keystore with passphrase, and moves the
+// identity of the node from the config file to the encrypted keystore.
+func (r *FSRepo) EncryptKeystore(passphrase []byte) error {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+
+	if _, ok := r.keystore.(*enckeystore.Keystore); ok {
+		return errors.New("keystore is already encrypted")
+	}
+	var sk crypto.PrivKey
+	if r.config.Identity.PrivKey != "" {
+		var err error
+		if sk, err = r.config.Identity.DecodePrivateKey(""); err != nil {
+			return err
+		}
+	}
+	ks, err := enckeystore.Encrypt(filepath.Join(r.path, "keystore"), passphrase, sk)
+	if err != nil {
+		return err
+	}
+	r.keystore = ks
+	return r.setPrivKeyUnsynced("")
+}
+
+// DecryptKeystore stores the keys of the unlocked encrypted keystore in
+// plaintext again, and moves the identity of the node back to the config
+// file.
+func (r *FSRepo) DecryptKeystore() error {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+
+	ks, ok := r.keystore.(*enckeystore.Keystore)
+	if !ok {
+		return enckeystore.ErrNotEncrypted
+	}
+	sk, err := ks.Decrypt()
+	if err != nil {
+		return err
+	}
+	r.keystore, err = keystore.NewFSKeystore(filepath.Join(r.path, "keystore"))
+	if err != nil {
+		return err
+	}
+	if sk == nil {
+		return nil
+	}
+	privKey, err := encodePrivKey(sk)
+	if err != nil {
+		return err
+	}
+	return r.setPrivKeyUnsynced(privKey)
+}
+
+// setPrivKeyUnsynced writes Identity.PrivKey to the config file.
+func (r *FSRepo) setPrivKeyUnsynced(privKey string) error {
+	var mapconf map[string]interface{}
+	if err := serialize.ReadConfigFile(r.configFilePath, &mapconf); err != nil {
+		return err
+	}
+	identity, ok := mapconf[config.IdentityTag].(map[string]interface{})
+	if !ok {
+		return errors.New("config has no Identity")
+	}
+	if privKey == "" {
+		delete(identity, config.PrivKeyTag)
+	} else {
+		identity[config.PrivKeyTag] = privKey
+	}
+	return serialize.WriteConfigFile(r.configFilePath, mapconf)
+}
+
+// setIdentityUnsynced stores Identity.PrivKey of updated in the encrypted
+// keystore when it changed, since it must not be written to the config file.
+func (r *FSRepo) setIdentityUnsynced(updated *config.Config) error {
+	if updated.Identity.PrivKey == "" {
+		return nil
+	}
+	sk, err := updated.Identity.DecodePrivateKey("")
+	if err != nil {
+		return err
+	}
+	ks := r.keystore.(*enckeystore.Keystore)
+	// updated may be the config returned by r.Config(), modified in place:
+	// compare with the stored identity.
+	if old, err := ks.Identity(); err == nil && old != nil && old.Equals(sk) {
+		return nil
+	}
+	return ks.SetIdentity(sk)
+}
+
+func encodePrivKey(sk crypto.PrivKey) (string, error) {
+	b, err := crypto.MarshalPrivateKey(sk)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
 // openDatastore returns an error if the config file is not present.
 func (r *FSRepo) openDatastore() error {
 	if r.config.Datastore.Type != "" || r.config.Datastore.Path != "" {
@@ -658,6 +818,15 @@ func (r *FSRepo) SetConfig(updated *config.Config) error {
 	if err != nil {
 		return err
 	}
+	if _, ok := r.keystore.(*enckeystore.Keystore); ok {
+		// the identity is stored in the encrypted keystore instead
+		if err := r.setIdentityUnsynced(updated); err != nil {
+			return err
+		}
+		if identity, ok := m[config.IdentityTag].(map[string]interface{}); ok {
+			delete(identity, config.PrivKeyTag)
+		}
+	}
 	mergedMap := common.MapMergeDeep(mapconf, m)
 	if err := serialize.WriteConfigFile(r.configFilePath, mergedMap); err != nil {
 		return err
@@ -708,10 +877,10 @@ func (r *FSRepo) SetConfigKey(key string, value interface{}) error {
 	// Load private key to guard against it being overwritten.
 	// NOTE: this is a temporary measure to secure this field until we move
 	// keys out of the config file.
+	// The config file holds no private key when the identity is stored in the
+	// encrypted keystore or held by the remote signer.
 	pkval, err := common.MapGetKV(mapconf, config.PrivKeySelector)
-	if err != nil {
-		return err
-	}
+	hasPrivKey := err == nil
 
 	// Set the key in the map.
 	if err := common.MapSetKV(mapconf, key, value); err != nil {
@@ -719,7 +888,11 @@ func (r *FSRepo) SetConfigKey(key string, value interface{}) error {
 	}
 
 	// replace private key, in case it was overwritten.
-	if err := common.MapSetKV(mapconf, config.PrivKeySelector, pkval); err != nil {
+	if !hasPrivKey {
+		if identity, ok := mapconf[config.IdentityTag].(map[string]interface{}); ok {
+			delete(identity, config.PrivKeyTag)
+		}
+	} else if err := common.MapSetKV(mapconf, config.PrivKeySelector, pkval); err != nil {
 		return err
 	}
 
@@ -729,6 +902,10 @@ func (r *FSRepo) SetConfigKey(key string, value interface{}) error {
 	if err != nil {
 		return err
 	}
+	if !hasPrivKey {
+		// keep the identity loaded from the encrypted keystore
+		conf.Identity.PrivKey = r.config.Identity.PrivKey
+	}
 	r.config = conf
 
 	if err := serialize.WriteConfigFile(r.configFilePath, mapconf); err != nil {
@@ -0,0 +1,228 @@
+package migrations
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	gopath "path"
+	"strings"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	mbase "github.com/multiformats/go-multibase"
+)
+
+const (
+	// BundleManifest is the name of the manifest at the root of a migration
+	// bundle.
+	BundleManifest = "manifest.json"
+	// BundleSignature is the name of the file holding the multibase-encoded
+	// signature of the manifest, as output by 'ipfs key sign'.
+	BundleSignature = BundleManifest + ".sig"
+
+	// Prefix of every payload signed with 'ipfs key sign'.
+	signedMessagePrefix = "libp2p-key signed message:"
+)
+
+// BundleManifestData lists the sha2-256 digest, in hex, of every file in a
+// migration bundle, keyed by its path relative to the bundle root.
+type BundleManifestData struct {
+	Files map[string]string
+}
+
+// BundleFetcher fetches files from a migration bundle: a local directory or
+// tarball that has the same layout as the distribution site, for machines
+// that cannot reach it. A bundle is only trusted if its manifest is signed by
+// one of the trusted keys, and a file is only returned if it is listed in the
+// manifest with a matching digest.
+type BundleFetcher struct {
+	path        string
+	trustedKeys []string
+
+	once sync.Once
+	// readFile reads a file by its path relative to the bundle root.
+	readFile func(name string) ([]byte, error)
+	manifest BundleManifestData
+	err      error
+}
+
+var _ Fetcher = (*BundleFetcher)(nil)
+
+// NewBundleFetcher creates a new [BundleFetcher] for the bundle at path,
+// which is either a directory or a tar (optionally gzipped) archive.
+// trustedKeys are the peer IDs, or IPNS names, of the ed25519 keys allowed to
+// sign the bundle manifest. The bundle is opened on first use.
+func NewBundleFetcher(path string, trustedKeys []string) *BundleFetcher {
+	return &BundleFetcher{
+		path:        path,
+		trustedKeys: trustedKeys,
+	}
+}
+
+// Fetch returns the contents of the file at the given path in the bundle.
+func (f *BundleFetcher) Fetch(ctx context.Context, filePath string) ([]byte, error) {
+	f.once.Do(func() {
+		f.err = f.open()
+	})
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	filePath = strings.TrimPrefix(gopath.Clean("/"+filePath), "/")
+	fmt.Printf("Fetching from bundle: %q\n", gopath.Join(f.path, filePath))
+
+	digest, ok := f.manifest.Files[filePath]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", filePath, fs.ErrNotExist)
+	}
+	data, err := f.readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkDigest(data, digest); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return data, nil
+}
+
+func (f *BundleFetcher) Close() error {
+	return nil
+}
+
+func (f *BundleFetcher) open() error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("cannot open migration bundle: %w", err)
+	}
+	if fi.IsDir() {
+		fsys := os.DirFS(f.path)
+		f.readFile = func(name string) ([]byte, error) {
+			return fs.ReadFile(fsys, name)
+		}
+	} else {
+		files, err := readTarBundle(f.path)
+		if err != nil {
+			return fmt.Errorf("cannot read migration bundle %s: %w", f.path, err)
+		}
+		f.readFile = files.readFile
+	}
+
+	manifest, err := f.readFile(BundleManifest)
+	if err != nil {
+		return fmt.Errorf("cannot read migration bundle manifest: %w", err)
+	}
+	sig, err := f.readFile(BundleSignature)
+	if err != nil {
+		return fmt.Errorf("cannot read migration bundle signature: %w", err)
+	}
+	if err = VerifyBundleManifest(manifest, sig, f.trustedKeys); err != nil {
+		return fmt.Errorf("migration bundle %s: %w", f.path, err)
+	}
+
+	if err = json.Unmarshal(manifest, &f.manifest); err != nil {
+		return fmt.Errorf("cannot parse migration bundle manifest: %w", err)
+	}
+	return nil
+}
+
+// VerifyBundleManifest checks that sig, the multibase-encoded output of
+// 'ipfs key sign', is a valid signature of manifest by one of trustedKeys.
+func VerifyBundleManifest(manifest, sig []byte, trustedKeys []string) error {
+	if len(trustedKeys) == 0 {
+		return errors.New("no trusted keys configured in Migration.TrustedKeys")
+	}
+
+	_, signature, err := mbase.Decode(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	payload := append([]byte(signedMessagePrefix), manifest...)
+
+	for _, k := range trustedKeys {
+		id, err := peer.Decode(k)
+		if err != nil {
+			return fmt.Errorf("invalid trusted key %q: %w", k, err)
+		}
+		pk, err := id.ExtractPublicKey()
+		if err != nil {
+			return fmt.Errorf("cannot extract public key from trusted key %q: %w", k, err)
+		}
+		if ok, _ := pk.Verify(payload, signature); ok {
+			return nil
+		}
+	}
+	return errors.New("manifest is not signed by a trusted key")
+}
+
+func checkDigest(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return fmt.Errorf("digest mismatch: expected %s, got %s", want, got)
+	}
+	return nil
+}
+
+// tarBundle is an in-memory copy of the regular files of a tarball bundle,
+// keyed by their path relative to the bundle root.
+type tarBundle map[string][]byte
+
+func (b tarBundle) readFile(name string) ([]byte, error) {
+	data, ok := b[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+func readTarBundle(arcPath string) (tarBundle, error) {
+	fi, err := os.Open(arcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	var r io.Reader = bufio.NewReader(fi)
+	magic, _ := r.(*bufio.Reader).Peek(2)
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("error opening gzip reader: %w", err)
+		}
+		defer gzr.Close()
+		r = gzr
+	}
+
+	files := make(tarBundle)
+	tarr := tar.NewReader(r)
+	for {
+		th, err := tarr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("cannot read archive: %w", err)
+		}
+		if th.Typeflag != tar.TypeReg {
+			continue
+		}
+		if th.Size > defaultFetchLimit {
+			return nil, fmt.Errorf("%s exceeds the maximum size of %d bytes", th.Name, defaultFetchLimit)
+		}
+		data, err := io.ReadAll(tarr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", th.Name, err)
+		}
+		files[strings.TrimPrefix(gopath.Clean("/"+th.Name), "/")] = data
+	}
+	return files, nil
+}
@@ -0,0 +1,197 @@
+package migrations
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mbase "github.com/multiformats/go-multibase"
+)
+
+func newBundleKey(t *testing.T) (crypto.PrivKey, string) {
+	sk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sk, id.String()
+}
+
+// makeBundle returns the files of a signed bundle holding files.
+func makeBundle(t *testing.T, sk crypto.PrivKey, files map[string]string) map[string][]byte {
+	bundle := make(map[string][]byte, len(files)+2)
+	m := BundleManifestData{Files: make(map[string]string, len(files))}
+	for name, data := range files {
+		sum := sha256.Sum256([]byte(data))
+		m.Files[name] = hex.EncodeToString(sum[:])
+		bundle[name] = []byte(data)
+	}
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sk.Sign(append([]byte(signedMessagePrefix), manifest...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encSig, err := mbase.Encode(mbase.Base64url, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle[BundleManifest] = manifest
+	bundle[BundleSignature] = []byte(encSig + "\n")
+	return bundle
+}
+
+func writeBundleDir(t *testing.T, bundle map[string][]byte) string {
+	dir := t.TempDir()
+	for name, data := range bundle {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeBundleTgz(t *testing.T, bundle map[string][]byte) string {
+	p := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	fi, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	gzw := gzip.NewWriter(fi)
+	tw := tar.NewWriter(gzw)
+	for name, data := range bundle {
+		err = tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestBundleFetcher(t *testing.T) {
+	ctx := context.Background()
+	sk, id := newBundleKey(t)
+	bundle := makeBundle(t, sk, map[string]string{
+		"fs-repo-1-to-2/versions":   "v1.0.0\n",
+		"fs-repo-1-to-2/v1.0.0/bin": "binary",
+	})
+
+	for name, p := range map[string]string{
+		"dir": writeBundleDir(t, bundle),
+		"tgz": writeBundleTgz(t, bundle),
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := NewBundleFetcher(p, []string{id})
+			out, err := f.Fetch(ctx, "/fs-repo-1-to-2/versions")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != "v1.0.0\n" {
+				t.Fatalf("unexpected content %q", out)
+			}
+
+			vers, err := DistVersions(ctx, f, "fs-repo-1-to-2", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vers) != 1 || vers[0] != "v1.0.0" {
+				t.Fatalf("unexpected versions %v", vers)
+			}
+
+			if _, err = f.Fetch(ctx, "fs-repo-1-to-2/missing"); err == nil {
+				t.Fatal("expected error fetching file not in manifest")
+			}
+		})
+	}
+}
+
+func TestBundleFetcherIntegrity(t *testing.T) {
+	ctx := context.Background()
+	sk, id := newBundleKey(t)
+	_, otherID := newBundleKey(t)
+	bundle := makeBundle(t, sk, map[string]string{"dist/versions": "v1.0.0\n"})
+
+	// Untrusted signer.
+	dir := writeBundleDir(t, bundle)
+	_, err := NewBundleFetcher(dir, []string{otherID}).Fetch(ctx, "dist/versions")
+	if err == nil || !strings.Contains(err.Error(), "not signed by a trusted key") {
+		t.Fatal("expected untrusted signature error, got:", err)
+	}
+
+	// No trusted keys.
+	_, err = NewBundleFetcher(dir, nil).Fetch(ctx, "dist/versions")
+	if err == nil || !strings.Contains(err.Error(), "no trusted keys") {
+		t.Fatal("expected missing trusted keys error, got:", err)
+	}
+
+	// Tampered file.
+	if err = os.WriteFile(filepath.Join(dir, "dist", "versions"), []byte("v9.9.9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewBundleFetcher(dir, []string{otherID, id}).Fetch(ctx, "dist/versions")
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatal("expected digest mismatch error, got:", err)
+	}
+
+	// Tampered manifest.
+	bundle[BundleManifest] = []byte(strings.Replace(string(bundle[BundleManifest]), "dist", "dust", 1))
+	_, err = NewBundleFetcher(writeBundleDir(t, bundle), []string{id}).Fetch(ctx, "dust/versions")
+	if err == nil || !strings.Contains(err.Error(), "not signed by a trusted key") {
+		t.Fatal("expected invalid signature error, got:", err)
+	}
+}
+
+func TestGetMigrationFetcherBundle(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := GetMigrationFetcher([]string{dir}, "", nil, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf, ok := f.(*BundleFetcher); !ok {
+		t.Fatal("expected BundleFetcher")
+	} else if bf.path != dir || len(bf.trustedKeys) != 1 {
+		t.Fatal("unexpected BundleFetcher settings")
+	}
+
+	f, err = GetMigrationFetcher([]string{"file://" + filepath.ToSlash(dir), "https"}, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf, ok := f.(*MultiFetcher)
+	if !ok {
+		t.Fatal("expected MultiFetcher")
+	}
+	if _, ok = mf.Fetchers()[0].(*BundleFetcher); !ok {
+		t.Fatal("expected BundleFetcher first")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
@@ -151,8 +152,9 @@ func ReadMigrationConfig(repoRoot string, userConfigFile string) (*config.Migrat
 }
 
 // GetMigrationFetcher creates one or more fetchers according to
-// downloadSources,.
-func GetMigrationFetcher(downloadSources []string, distPath string, newIpfsFetcher func(string) Fetcher) (Fetcher, error) {
+// downloadSources. Sources that are absolute paths or file:// URLs are
+// migration bundles, whose manifest must be signed by one of trustedKeys.
+func GetMigrationFetcher(downloadSources []string, distPath string, newIpfsFetcher func(string) Fetcher, trustedKeys ...string) (Fetcher, error) {
 	const httpUserAgent = "kubo/migration"
 	const numTriesPerHTTP = 3
 
@@ -169,6 +171,10 @@ func GetMigrationFetcher(downloadSources []string, distPath string, newIpfsFetch
 		case "":
 			// Ignore empty string
 		default:
+			if filepath.IsAbs(src) {
+				fetchers = append(fetchers, NewBundleFetcher(src, trustedKeys))
+				continue
+			}
 			u, err := url.Parse(src)
 			if err != nil {
 				return nil, fmt.Errorf("bad gateway address: %w", err)
@@ -177,6 +183,9 @@ func GetMigrationFetcher(downloadSources []string, distPath string, newIpfsFetch
 			case "":
 				u.Scheme = "https"
 			case "https", "http":
+			case "file":
+				fetchers = append(fetchers, NewBundleFetcher(filepath.FromSlash(u.Path), trustedKeys))
+				continue
 			default:
 				return nil, errors.New("bad gateway address: url scheme must be http or https")
 			}
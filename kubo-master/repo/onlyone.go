@@ -54,7 +54,39 @@ type ref struct {
 	Repo
 }
 
-var _ Repo = (*ref)(nil)
+var (
+	_ Repo              = (*ref)(nil)
+	_ KeystoreEncrypter = (*ref)(nil)
+)
+
+func (r *ref) KeystoreLocked() bool {
+	ke, ok := r.Repo.(KeystoreEncrypter)
+	return ok && ke.KeystoreLocked()
+}
+
+func (r *ref) UnlockKeystore(passphrase []byte) error {
+	ke, ok := r.Repo.(KeystoreEncrypter)
+	if !ok {
+		return ErrKeystoreEncryptionNotSupported
+	}
+	return ke.UnlockKeystore(passphrase)
+}
+
+func (r *ref) EncryptKeystore(passphrase []byte) error {
+	ke, ok := r.Repo.(KeystoreEncrypter)
+	if !ok {
+		return ErrKeystoreEncryptionNotSupported
+	}
+	return ke.EncryptKeystore(passphrase)
+}
+
+func (r *ref) DecryptKeystore() error {
+	ke, ok := r.Repo.(KeystoreEncrypter)
+	if !ok {
+		return ErrKeystoreEncryptionNotSupported
+	}
+	return ke.DecryptKeystore()
+}
 
 func (r *ref) Close() error {
 	r.parent.mu.Lock()
@@ -67,6 +67,27 @@ type Repo interface {
 	io.Closer
 }
 
+// ErrKeystoreEncryptionNotSupported is returned by repos which cannot encrypt
+// their keystore.
+var ErrKeystoreEncryptionNotSupported = errors.New("repo does not support keystore encryption")
+
+// KeystoreEncrypter is implemented by repos able to encrypt their keystore
+// with a passphrase.
+type KeystoreEncrypter interface {
+	// KeystoreLocked returns whether the keystore is encrypted and still
+	// needs to be unlocked.
+	KeystoreLocked() bool
+
+	// UnlockKeystore unlocks the encrypted keystore.
+	UnlockKeystore(passphrase []byte) error
+
+	// EncryptKeystore encrypts the keystore, and the identity of the node.
+	EncryptKeystore(passphrase []byte) error
+
+	// DecryptKeystore stores the keys of the unlocked keystore in plaintext.
+	DecryptKeystore() error
+}
+
 // Datastore is the interface required from a datastore to be
 // acceptable to FSRepo.
 type Datastore interface {
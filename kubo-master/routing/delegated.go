@@ -241,6 +241,11 @@ func httpRoutingFromConfig(conf config.Router, extraHTTP *ExtraHTTPParams) (rout
 }
 
 func decodePrivKey(keyB64 string) (ic.PrivKey, error) {
+	if keyB64 == "" {
+		// the identity is not in the config, e.g. when it is held by the
+		// remote signer: the router works without it, except for providing
+		return nil, nil
+	}
 	pk, err := base64.StdEncoding.DecodeString(keyB64)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/commands"
+	"github.com/ipfs/kubo/namesys/alias"
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlias(t *testing.T) {
+	t.Parallel()
+
+	h := harness.NewT(t)
+	node := h.NewNode().Init()
+
+	dir := filepath.Join(node.Dir, "docs")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "readme.md"), []byte("hello docs"), 0o644))
+	dirCid := node.IPFS("add", "-r", "-Q", dir).Stdout.Trimmed()
+	fileCid := node.IPFSAddStr("hello file")
+
+	t.Run("set, use, list and remove", func(t *testing.T) {
+		res := node.IPFS("alias", "set", "docs", "/ipfs/"+dirCid)
+		assert.Equal(t, "alias:docs -> /ipfs/"+dirCid+"\n", res.Stdout.String())
+		node.IPFS("alias", "set", "file", fileCid)
+
+		assert.Equal(t, "hello docs", node.IPFS("cat", "alias:docs/readme.md").Stdout.String())
+		assert.Equal(t, "hello file", node.IPFS("cat", "alias:file").Stdout.String())
+
+		res = node.IPFS("alias", "ls", "--enc=json")
+		var list commands.AliasList
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &list))
+		assert.Equal(t, []alias.Alias{
+			{Name: "docs", Target: "/ipfs/" + dirCid},
+			{Name: "file", Target: "/ipfs/" + fileCid},
+		}, list.Aliases)
+
+		res = node.RunIPFS("cat", "alias:unknown/readme.md")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "alias not found: unknown")
+
+		res = node.RunIPFS("alias", "set", "bad/name", fileCid)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "invalid alias name")
+
+		node.IPFS("alias", "rm", "file")
+		res = node.RunIPFS("cat", "alias:file")
+		assert.Error(t, res.Err)
+		assert.Equal(t, "docs /ipfs/"+dirCid+"\n", node.IPFS("alias", "ls").Stdout.String())
+	})
+
+	t.Run("export and import", func(t *testing.T) {
+		table := node.IPFS("alias", "export").Stdout.Bytes()
+
+		other := h.NewNode().Init()
+		other.IPFS("alias", "set", "docs", fileCid)
+		res := other.RunPipeToIPFS(strings.NewReader(string(table)), "alias", "import")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "alias docs is already registered")
+
+		res = other.PipeStrToIPFS(string(table), "alias", "import", "--force")
+		assert.Equal(t, "docs /ipfs/"+dirCid+"\n", res.Stdout.String())
+		assert.Equal(t, table, other.IPFS("alias", "export").Stdout.Bytes())
+	})
+
+	t.Run("gateway", func(t *testing.T) {
+		// aliases are not exposed by default
+		node.StartDaemon()
+		resp := node.GatewayClient().Get("/alias/docs/readme.md")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		node.StopDaemon()
+
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.Gateway.ExposeAliases = config.True
+		})
+		node.StartDaemon()
+		defer node.StopDaemon()
+
+		resp = node.GatewayClient().Get("/alias/docs/readme.md")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello docs", resp.Body)
+
+		resp = node.GatewayClient().Get("/alias/unknown/readme.md")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
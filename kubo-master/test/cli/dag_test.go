@@ -1,14 +1,21 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ipfs/kubo/test/cli/harness"
 	"github.com/ipfs/kubo/test/cli/testutils"
+	"github.com/ipld/go-car/v2"
+	"github.com/ipld/go-car/v2/index"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -101,4 +108,281 @@ func TestDag(t *testing.T) {
 		stat := node.RunIPFS("dag", "stat", "--progress=false", node1Cid, node2Cid)
 		assert.Equal(t, content, stat.Stdout.Bytes())
 	})
+
+	t.Run("ipfs dag export with selectors", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+		// a root and 4 leaves
+		root := node.IPFSAddDeterministic("1MiB", "dag-export", "--chunker=size-262144")
+
+		countBlocks := func(args ...string) int {
+			res := node.IPFS(append([]string{"dag", "export", "--progress=false"}, args...)...)
+			br, err := car.NewBlockReader(bytes.NewReader(res.Stdout.Bytes()))
+			require.NoError(t, err)
+			var n int
+			for {
+				_, err := br.Next()
+				if err == io.EOF {
+					return n
+				}
+				require.NoError(t, err)
+				n++
+			}
+		}
+
+		assert.Equal(t, 5, countBlocks(root))
+		assert.Equal(t, 1, countBlocks("--selector=depth:0", root))
+		assert.Equal(t, 5, countBlocks("--selector=depth:1", root))
+		assert.Equal(t, 2, countBlocks("--selector=path:Links/2/Hash", root))
+		assert.Equal(t, 1, countBlocks(`--selector={"R":{"l":{"depth":1},":>":{"a":{">":{"@":{}}}}}}`, root))
+		assert.Equal(t, 1, countBlocks("--dag-scope=block", root))
+		assert.Equal(t, 2, countBlocks("--entity-bytes=0:10", root))
+		assert.Equal(t, 3, countBlocks("--entity-bytes=262140:262200", root))
+
+		res := node.RunIPFS("dag", "export", "--selector=depth:1", "--dag-scope=all", root)
+		assert.Error(t, res.Err)
+		res = node.RunIPFS("dag", "export", "--dag-scope=block", "--entity-bytes=0:10", root)
+		assert.Error(t, res.Err)
+	})
+
+	t.Run("ipfs dag export and import CARv2", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+		root := node.IPFSAddDeterministic("1MiB", "dag-carv2", "--chunker=size-262144")
+		dir := t.TempDir()
+
+		export := func(name string, args ...string) string {
+			res := node.IPFS(append([]string{"dag", "export", "--progress=false"}, args...)...)
+			p := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(p, res.Stdout.Bytes(), 0o644))
+			return p
+		}
+		v1 := export("v1.car", root)
+		v2 := export("v2.car", "--car-version=2", root)
+		scoped := export("scoped.car", "--car-version=2", "--dag-scope=block", root)
+
+		for p, blocks := range map[string]int{v2: 5, scoped: 1} {
+			r, err := car.OpenReader(p)
+			require.NoError(t, err)
+			assert.EqualValues(t, 2, r.Version)
+			assert.True(t, r.Header.HasIndex())
+			stats, err := r.Inspect(true)
+			require.NoError(t, err)
+			assert.EqualValues(t, blocks, stats.BlockCount)
+			r.Close()
+		}
+
+		res := node.IPFS("dag", "car", "index", v2)
+		assert.Equal(t, []string{
+			"Version: 2",
+			"Roots: " + root,
+			"Blocks: 5",
+			"Index: car-multihash-index-sorted, 5 entries verified",
+		}, res.Stdout.Lines())
+		assert.Contains(t, node.IPFS("dag", "car", "index", v1).Stdout.String(), "Index: none")
+
+		built := filepath.Join(dir, "built.car")
+		res = node.IPFS("dag", "car", "index", "--output", built, v1)
+		assert.Equal(t, "Wrote "+built, res.Stdout.Lines()[0])
+		assert.Contains(t, res.Stdout.String(), "5 entries verified")
+
+		other := harness.NewT(t).NewNode().Init()
+		res = other.IPFS("dag", "import", "--verify-index", "--stats", built)
+		assert.Contains(t, res.Stdout.String(), "Imported 5 blocks")
+		res = other.RunIPFS("dag", "import", "--verify-index", v1)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "not a CARv2")
+
+		// A CARv2 holding the index of another CAR.
+		v1Data, err := os.ReadFile(v1)
+		require.NoError(t, err)
+		otherRoot := node.IPFSAddDeterministic("1MiB", "dag-carv2-other", "--chunker=size-262144")
+		otherIdx, err := car.GenerateIndex(bytes.NewReader(node.IPFS("dag", "export", "--progress=false", otherRoot).Stdout.Bytes()))
+		require.NoError(t, err)
+		var bad bytes.Buffer
+		bad.Write(car.Pragma)
+		_, err = car.NewHeader(uint64(len(v1Data))).WriteTo(&bad)
+		require.NoError(t, err)
+		bad.Write(v1Data)
+		_, err = index.WriteTo(otherIdx, &bad)
+		require.NoError(t, err)
+		badPath := filepath.Join(dir, "bad.car")
+		require.NoError(t, os.WriteFile(badPath, bad.Bytes(), 0o644))
+
+		res = node.RunIPFS("dag", "car", "index", badPath)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "invalid index")
+		res = other.RunIPFS("dag", "import", "--verify-index", badPath)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "invalid CARv2 index")
+	})
+
+	t.Run("ipfs dag car attach", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+		content := "served from an attached CAR file"
+		root := node.PipeStrToIPFS(content, "add", "-Q", "--cid-version=1").Stdout.Trimmed()
+		carPath := filepath.Join(t.TempDir(), "attached.car")
+		car := node.IPFS("dag", "export", "--progress=false", "--car-version=2", root).Stdout.Bytes()
+		require.NoError(t, os.WriteFile(carPath, car, 0o644))
+
+		other := harness.NewT(t).NewNode().Init()
+		res := other.IPFS("dag", "car", "attach", carPath)
+		assert.Equal(t, "attached "+carPath, res.Stdout.Trimmed())
+		assert.Equal(t, carPath+"\t"+root, other.IPFS("dag", "car", "ls").Stdout.Trimmed())
+		res = other.RunIPFS("dag", "car", "attach", carPath)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "already attached")
+
+		assert.Equal(t, content, other.IPFS("cat", "--offline", root).Stdout.String())
+		other.IPFS("pin", "add", root)
+		assert.Empty(t, other.IPFS("pin", "verify", "--verbose=false").Stdout.Trimmed())
+		other.IPFS("repo", "gc")
+		assert.Equal(t, content, other.IPFS("cat", "--offline", root).Stdout.String())
+		assert.NotContains(t, other.IPFS("refs", "local").Stdout.String(), root)
+
+		other.StartDaemon()
+		resp := other.GatewayClient().Get("/ipfs/" + root)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, content, resp.Body)
+		other.IPFS("dag", "car", "detach", carPath)
+		other.StopDaemon()
+
+		assert.Empty(t, other.IPFS("dag", "car", "ls").Stdout.Trimmed())
+		res = other.RunIPFS("block", "stat", "--offline", root)
+		assert.Error(t, res.Err)
+		car2, err := os.ReadFile(carPath)
+		require.NoError(t, err)
+		assert.Equal(t, car, car2)
+	})
+
+	t.Run("ipfs dag diff", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+		dagPut := func(data string, args ...string) string {
+			res := node.PipeStrToIPFS(data, append([]string{"dag", "put"}, args...)...)
+			return res.Stdout.Trimmed()
+		}
+
+		c1 := dagPut(`{"x":1}`)
+		c2 := dagPut(`{"x":2,"y":true}`)
+		// never stored, must not be fetched as both sides link to it
+		missing := "bafyreigdmqpykrgxyaxtlafqpqhzrb7qy2rh75nldvfd4tucqmqqme5yje"
+		a := dagPut(`{"name":"foo","tags":["a","b"],"child":{"/":"` + c1 + `"},"same":{"/":"` + missing + `"}}`)
+		b := dagPut(`{"name":"bar","tags":["a"],"child":{"/":"`+c2+`"},"same":{"/":"`+missing+`"}}`, "--store-codec=dag-json")
+
+		res := node.IPFS("dag", "diff", "--offline", a, b)
+		assert.Equal(t, []string{
+			`~ child: {"/":"` + c1 + `"} => {"/":"` + c2 + `"}`,
+			`~ child/x: 1 => 2`,
+			`+ child/y: true`,
+			`~ name: "foo" => "bar"`,
+			`- tags/1: "b"`,
+		}, res.Stdout.Lines())
+
+		res = node.IPFS("dag", "diff", "--enc=json", a, b)
+		var change struct{ Type, Path string }
+		require.NoError(t, json.Unmarshal([]byte(res.Stdout.Lines()[4]), &change))
+		assert.Equal(t, "remove", change.Type)
+		assert.Equal(t, "tags/1", change.Path)
+
+		res = node.IPFS("dag", "diff", a, a)
+		assert.Empty(t, res.Stdout.String())
+	})
+
+	t.Run("ipfs dag query", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+		dagPut := func(data string) string {
+			return node.PipeStrToIPFS(data, "dag", "put").Stdout.Trimmed()
+		}
+
+		parent := dagPut(`{"height":1}`)
+		head := dagPut(`{"height":2,"parent":{"/":"` + parent + `"}}`)
+		missing := "bafyreigdmqpykrgxyaxtlafqpqhzrb7qy2rh75nldvfd4tucqmqqme5yje"
+		root := dagPut(`{"entries":[{"name":"a","size":10},{"name":"b","size":5000}],"head":{"/":"` + head + `"},"gone":{"/":"` + missing + `"}}`)
+
+		query := func(args ...string) []string {
+			return node.IPFS(append([]string{"dag", "query", "--offline"}, args...)...).Stdout.Lines()
+		}
+
+		assert.Equal(t, []string{`"b"`}, query(root, ".entries[] | select(.size > 1024) | .name"))
+		assert.Equal(t, []string{"a", "b"}, query("-r", root, ".entries[].name"))
+		assert.Equal(t, []string{"1"}, query(root, ".head.parent.height"))
+		assert.Equal(t, []string{`{"/":"` + head + `"}`}, query(root, ".head"))
+		assert.Equal(t, []string{`[10,5000]`, "null"}, query(root, "(.entries | map(.size)), .nope"))
+		assert.Empty(t, query(root, ".gone.height?"))
+
+		res := node.RunIPFS("dag", "query", "--offline", root, ".gone.height")
+		assert.Error(t, res.Err)
+		res = node.RunIPFS("dag", "query", root, ".[")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "invalid query")
+	})
+
+	t.Run("ipfs dag schema validation", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+
+		schemaFile := filepath.Join(node.Dir, "post.ipldsch")
+		err := os.WriteFile(schemaFile, []byte(`
+type Post struct {
+	title String
+	tags [String]
+	parent optional &Post
+}
+`), 0o644)
+		require.NoError(t, err)
+
+		res := node.IPFS("dag", "schema", "add", "blog", schemaFile)
+		assert.Equal(t, "added schema blog with types Post", res.Stdout.Trimmed())
+		assert.Equal(t, "blog Post", node.IPFS("dag", "schema", "ls").Stdout.Trimmed())
+		assert.Contains(t, node.IPFS("dag", "schema", "get", "blog").Stdout.String(), "parent optional &Post")
+
+		res = node.RunIPFS("dag", "schema", "add", "blog", schemaFile)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "already exists")
+		res = node.RunPipeToIPFS(strings.NewReader("type Broken struct {"), "dag", "schema", "add", "broken")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "invalid schema")
+
+		// Not validated: a bad parent behind an untyped put.
+		bad := node.PipeStrToIPFS(`{"title":1,"tags":[]}`, "dag", "put").Stdout.Trimmed()
+		parent := node.PipeStrToIPFS(`{"title":"first","tags":[]}`, "dag", "put", "--schema", "blog").Stdout.Trimmed()
+		post := node.PipeStrToIPFS(`{"title":"second","tags":["x"],"parent":{"/":"`+parent+`"}}`, "dag", "put", "--schema", "blog", "--schema-type", "Post").Stdout.Trimmed()
+
+		res = node.RunPipeToIPFS(strings.NewReader(`{"title":"x","tags":[1]}`), "dag", "put", "--schema", "blog")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "at tags/0")
+		res = node.RunPipeToIPFS(strings.NewReader(`{"tags":[]}`), "dag", "put", "--schema", "blog")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "missing required fields: title")
+		res = node.RunPipeToIPFS(strings.NewReader(`{"title":"x","tags":[]}`), "dag", "put", "--schema", "nope")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), `schema "nope" not found`)
+
+		res = node.IPFS("dag", "validate", "--schema", "blog", post)
+		assert.Equal(t, post+" conforms to Post (2 blocks)", res.Stdout.Trimmed())
+		orphan := node.PipeStrToIPFS(`{"title":"third","tags":[],"parent":{"/":"`+bad+`"}}`, "dag", "put", "--schema", "blog").Stdout.Trimmed()
+		res = node.RunIPFS("dag", "validate", "--schema", "blog", orphan)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), bad+" does not conform to Post")
+
+		// Import of a CAR whose root links to a non-conforming block.
+		car := node.IPFS("dag", "export", orphan).Stdout.Bytes()
+		other := harness.NewT(t).NewNode().Init()
+		other.IPFS("dag", "schema", "add", "blog", schemaFile)
+		res = other.RunPipeToIPFS(bytes.NewReader(car), "dag", "import", "--schema", "blog")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "does not conform to Post")
+		assert.NotContains(t, other.IPFS("pin", "ls", "--type=recursive").Stdout.String(), orphan)
+
+		car = node.IPFS("dag", "export", post).Stdout.Bytes()
+		res = other.RunPipeToIPFS(bytes.NewReader(car), "dag", "import", "--schema", "blog")
+		assert.NoError(t, res.Err)
+		assert.Contains(t, res.Stdout.String(), "Pinned root\t"+post)
+
+		node.IPFS("dag", "schema", "rm", "blog")
+		assert.Empty(t, node.IPFS("dag", "schema", "ls").Stdout.Trimmed())
+	})
 }
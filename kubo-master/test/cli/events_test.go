@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipfs/kubo/client/rpc"
+	"github.com/ipfs/kubo/core/events"
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	node := harness.NewT(t).NewNode().Init().StartDaemon()
+	defer node.StopDaemon()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	api, err := rpc.NewApi(node.APIAddr())
+	require.NoError(t, err)
+
+	sub, err := api.Events(ctx, []string{"pin.*", "gc.*"}, "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	cid := node.IPFSAddStr("evented")
+	node.IPFS("pin", "rm", cid)
+	node.IPFS("pin", "add", cid)
+	node.IPFS("repo", "gc")
+
+	var received []events.Event
+	for len(received) < 4 {
+		ev, err := sub.Next()
+		require.NoError(t, err)
+		received = append(received, ev)
+	}
+	types := make([]string, len(received))
+	for i, ev := range received {
+		types[i] = ev.Type
+	}
+	assert.Equal(t, []string{events.PinRemoved, events.PinAdded, events.GCStarted, events.GCCompleted}, types)
+	assert.Equal(t, events.PinEvent{Path: "/ipfs/" + cid, Cid: cid, Recursive: true}, received[1].Data)
+	assert.Equal(t, received[3].ID, sub.LastEventID())
+
+	t.Run("the CLI resumes after the last event received", func(t *testing.T) {
+		// the stream only ends with the timeout
+		res := node.RunIPFS("events", "--timeout=2s", "--type=gc.*", "--enc=json", "--last-event-id="+received[1].ID)
+		lines := strings.Split(strings.TrimSpace(res.Stdout.String()), "\n")
+		require.Len(t, lines, 2)
+		var ev events.Event
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &ev))
+		assert.Equal(t, received[3].ID, ev.ID)
+		assert.IsType(t, events.GCEvent{}, ev.Data)
+
+		res = node.RunIPFS("events", "--timeout=2s", "--last-event-id=00000000-1")
+		assert.Contains(t, res.Stdout.String(), events.Lost)
+	})
+
+	t.Run("events are streamed as Server-Sent Events", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, node.APIURL()+"/api/v0/events?type=pin.added", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Last-Event-ID", received[0].ID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		scanner := bufio.NewScanner(resp.Body)
+		var lines []string
+		for len(lines) < 3 && scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		require.Len(t, lines, 3)
+		assert.Equal(t, "id: "+received[1].ID, lines[0])
+		assert.Equal(t, "event: "+events.PinAdded, lines[1])
+		var ev events.Event
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &ev))
+		assert.Equal(t, received[1].Data, ev.Data)
+	})
+
+	t.Run("Server-Sent Events are only streamed to allowed origins", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, node.APIURL()+"/api/v0/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Origin", "https://example.com")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestProvideProgressEvents(t *testing.T) {
+	t.Parallel()
+
+	nodes := harness.NewT(t).NewNodes(2).Init().StartDaemons().Connect()
+	defer nodes.StopDaemons()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	api, err := rpc.NewApi(nodes[0].APIAddr())
+	require.NoError(t, err)
+	sub, err := api.Events(ctx, []string{events.ProvideProgress}, "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// a reprovide of less than a full batch is reported complete
+	nodes[0].IPFSAddStr("reprovided")
+	nodes[0].IPFS("routing", "reprovide")
+
+	for {
+		ev, err := sub.Next()
+		require.NoError(t, err)
+		progress := ev.Data.(events.ProvideEvent)
+		assert.NotZero(t, progress.Provided)
+		if progress.Complete {
+			break
+		}
+	}
+}
+
+func TestEventsWithoutDaemon(t *testing.T) {
+	t.Parallel()
+	node := harness.NewT(t).NewNode().Init()
+	res := node.RunIPFS("events")
+	assert.Error(t, res.Err)
+}
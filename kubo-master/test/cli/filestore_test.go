@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/ipfs/kubo/test/cli/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilestore(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) (*harness.Node, string) {
+		node := harness.NewT(t).NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.Experimental.FilestoreEnabled = true
+		})
+		// filestore paths must be inside the parent of the repo
+		return node, filepath.Dir(node.Dir)
+	}
+
+	writeFile := func(t *testing.T, path string, size int) {
+		data := testutils.RandomBytes(size)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+
+	t.Run("reindex updates pins of a changed file", func(t *testing.T) {
+		t.Parallel()
+		node, root := setup(t)
+
+		dir := filepath.Join(root, "dir")
+		require.NoError(t, os.Mkdir(dir, 0o755))
+		file := filepath.Join(dir, "file")
+		writeFile(t, file, 600*1024)
+		writeFile(t, filepath.Join(dir, "other"), 100)
+
+		fileCid := node.IPFS("add", "-Q", "--nocopy", file).Stdout.Trimmed()
+		node.IPFS("pin", "add", "--name", "named", fileCid)
+		dirCid := node.IPFS("add", "-Q", "-r", "--nocopy", dir).Stdout.Trimmed()
+
+		writeFile(t, file, 700*1024)
+		res := node.RunIPFS("filestore", "verify")
+		assert.Contains(t, res.Stdout.String(), "changed")
+
+		out := node.IPFS("filestore", "reindex", file).Stdout.String()
+		assert.Contains(t, out, fileCid)
+		assert.Contains(t, out, dirCid)
+
+		pins := node.IPFS("pin", "ls", "--type=recursive", "--names").Stdout.String()
+		assert.NotContains(t, pins, fileCid)
+		assert.NotContains(t, pins, dirCid)
+		assert.Contains(t, pins, "named")
+
+		node.IPFS("pin", "verify")
+		assert.NotContains(t, node.IPFS("filestore", "verify").Stdout.String(), "changed")
+
+		newDirCid := node.IPFS("add", "-Q", "-r", "--nocopy", "--pin=false", dir).Stdout.Trimmed()
+		assert.Contains(t, pins, newDirCid)
+	})
+
+	t.Run("daemon reindexes files changed together in a pinned directory", func(t *testing.T) {
+		t.Parallel()
+		node, root := setup(t)
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.Experimental.FilestoreAutoReindex = true
+		})
+
+		dir := filepath.Join(root, "dir")
+		require.NoError(t, os.Mkdir(dir, 0o755))
+		a := filepath.Join(dir, "a")
+		b := filepath.Join(dir, "b")
+		writeFile(t, a, 600*1024)
+		writeFile(t, b, 600*1024)
+		dirCid := node.IPFS("add", "-Q", "-r", "--nocopy", dir).Stdout.Trimmed()
+
+		node.StartDaemon()
+		defer node.StopDaemon()
+
+		// both files are reindexed in the same debounce window, and update
+		// the same pin
+		writeFile(t, a, 700*1024)
+		writeFile(t, b, 700*1024)
+		newDirCid := node.IPFS("add", "-Q", "-r", "--nocopy", "--only-hash", dir).Stdout.Trimmed()
+		require.NotEqual(t, dirCid, newDirCid)
+
+		assert.Eventually(t, func() bool {
+			pins := node.IPFS("pin", "ls", "--type=recursive", "--quiet").Stdout.String()
+			return strings.Contains(pins, newDirCid) && !strings.Contains(pins, dirCid)
+		}, time.Minute, 500*time.Millisecond)
+		node.IPFS("pin", "verify")
+		assert.NotContains(t, node.IPFS("filestore", "verify").Stdout.String(), "changed")
+	})
+
+	t.Run("gc removes entries of missing files", func(t *testing.T) {
+		t.Parallel()
+		node, root := setup(t)
+
+		kept := filepath.Join(root, "kept")
+		gone := filepath.Join(root, "gone")
+		writeFile(t, kept, 100)
+		writeFile(t, gone, 100)
+		keptCid := node.IPFS("add", "-Q", "--raw-leaves", "--nocopy", kept).Stdout.Trimmed()
+		goneCid := node.IPFS("add", "-Q", "--raw-leaves", "--nocopy", gone).Stdout.Trimmed()
+		require.NoError(t, os.Remove(gone))
+
+		out := node.IPFS("filestore", "gc").Stdout.String()
+		assert.Contains(t, out, goneCid)
+		assert.NotContains(t, out, keptCid)
+
+		ls := node.IPFS("filestore", "ls").Stdout.String()
+		assert.Contains(t, ls, keptCid)
+		assert.NotContains(t, ls, goneCid)
+	})
+
+	t.Run("rm removes entries by cid and by path", func(t *testing.T) {
+		t.Parallel()
+		node, root := setup(t)
+
+		a := filepath.Join(root, "a")
+		b := filepath.Join(root, "b")
+		writeFile(t, a, 100)
+		writeFile(t, b, 600*1024)
+		aCid := node.IPFS("add", "-Q", "--raw-leaves", "--nocopy", a).Stdout.Trimmed()
+		node.IPFS("add", "-Q", "--nocopy", b)
+
+		node.IPFS("filestore", "rm", aCid)
+		node.IPFS("filestore", "rm", b)
+		assert.Empty(t, node.IPFS("filestore", "ls").Stdout.Trimmed())
+
+		res := node.RunIPFS("filestore", "rm", b)
+		assert.Contains(t, res.Stderr.String(), "no filestore entries")
+	})
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	oldcmds "github.com/ipfs/kubo/commands"
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs(t *testing.T) {
+	t.Parallel()
+
+	// a CID which cannot be fetched by nodes without peers
+	const missingCID = "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"
+
+	jobStatus := func(t *testing.T, node *harness.Node, id string) oldcmds.Job {
+		var job oldcmds.Job
+		res := node.IPFS("jobs", "status", id, "--enc=json")
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &job))
+		return job
+	}
+
+	t.Run("pin add --async", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init().StartDaemon()
+		defer node.StopDaemon()
+
+		cid := node.IPFSAddStr("background job")
+		node.IPFS("pin", "rm", cid)
+
+		id := node.IPFS("pin", "add", "--async", cid).Stdout.Trimmed()
+		require.Len(t, id, 16)
+
+		res := node.IPFS("jobs", "wait", id)
+		assert.Contains(t, res.Stdout.String(), "succeeded")
+
+		job := jobStatus(t, node, id)
+		assert.Equal(t, "pin/add", job.Command)
+		assert.Equal(t, []string{cid}, job.Args)
+		require.NotEmpty(t, job.Output)
+		assert.Contains(t, string(job.Output[len(job.Output)-1]), cid)
+		assert.Contains(t, node.IPFS("pin", "ls", "--type=recursive").Stdout.String(), cid)
+
+		res = node.IPFS("jobs", "ls")
+		assert.Contains(t, res.Stdout.String(), id)
+		assert.Contains(t, res.Stdout.String(), "pin add "+cid)
+	})
+
+	t.Run("dag import --async", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init().StartDaemon()
+		defer node.StopDaemon()
+
+		cid := node.IPFSAddStr("imported in the background")
+		carPath := filepath.Join(node.Dir, "data.car")
+		node.WriteBytes("data.car", node.IPFS("dag", "export", cid).Stdout.Bytes())
+		node.IPFS("pin", "rm", cid)
+
+		id := node.IPFS("dag", "import", "--async", carPath).Stdout.Trimmed()
+		node.IPFS("jobs", "wait", id)
+
+		job := jobStatus(t, node, id)
+		assert.Equal(t, oldcmds.JobSucceeded, job.Status)
+		assert.Contains(t, node.IPFS("pin", "ls", "--type=recursive").Stdout.String(), cid)
+	})
+
+	t.Run("jobs can be canceled, and are listed by diag cmds", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init().StartDaemon()
+		defer node.StopDaemon()
+
+		id := node.IPFS("pin", "add", "--async", "--progress", missingCID).Stdout.Trimmed()
+		assert.Equal(t, oldcmds.JobRunning, jobStatus(t, node, id).Status)
+		assert.Contains(t, node.IPFS("diag", "cmds").Stdout.String(), "pin/add")
+
+		node.IPFS("jobs", "cancel", id)
+		res := node.RunIPFS("jobs", "wait", id)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stdout.String(), oldcmds.JobCanceled)
+
+		res = node.RunIPFS("jobs", "cancel", id)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "not running")
+
+		res = node.RunIPFS("jobs", "status", "unknown")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "job not found")
+	})
+
+	t.Run("jobs are persisted", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init().StartDaemon()
+
+		cid := node.IPFSAddStr("persisted job")
+		done := node.IPFS("pin", "add", "--async", cid).Stdout.Trimmed()
+		node.IPFS("jobs", "wait", done)
+		running := node.IPFS("pin", "add", "--async", missingCID).Stdout.Trimmed()
+		node.StopDaemon()
+
+		// the jobs are readable without the daemon
+		assert.Equal(t, oldcmds.JobSucceeded, jobStatus(t, node, done).Status)
+		assert.Equal(t, oldcmds.JobFailed, jobStatus(t, node, running).Status)
+
+		node.StartDaemon()
+		defer node.StopDaemon()
+		res := node.IPFS("jobs", "ls", "--enc=json")
+		var list struct{ Jobs []oldcmds.Job }
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &list))
+		require.Len(t, list.Jobs, 2)
+		assert.Equal(t, done, list.Jobs[0].ID)
+		assert.Equal(t, running, list.Jobs[1].ID)
+	})
+
+	t.Run("--async requires the daemon", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+
+		res := node.RunIPFS("pin", "add", "--async", missingCID)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "requires a running daemon")
+	})
+
+	t.Run("wait honors --timeout", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init().StartDaemon()
+		defer node.StopDaemon()
+
+		id := node.IPFS("pin", "add", "--async", missingCID).Stdout.Trimmed()
+		start := time.Now()
+		res := node.RunIPFS("jobs", "wait", id, "--timeout=1s")
+		assert.Error(t, res.Err)
+		assert.Less(t, time.Since(start), 30*time.Second)
+		assert.True(t, strings.Contains(res.Stderr.String(), "deadline"), res.Stderr.String())
+	})
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedKeystore(t *testing.T) {
+	t.Parallel()
+
+	h := harness.NewT(t)
+	node := h.NewNode().Init()
+	peerID := node.PeerID().String()
+	fooID := node.IPFS("key", "gen", "foo").Stdout.Trimmed()
+
+	passFile := filepath.Join(node.Dir, "passphrase")
+	require.NoError(t, os.WriteFile(passFile, []byte("correct horse\n"), 0o600))
+
+	t.Run("encrypt-store", func(t *testing.T) {
+		node.IPFS("key", "encrypt-store", "--passphrase-file", passFile)
+		assert.Empty(t, node.ReadConfig().Identity.PrivKey)
+		assert.FileExists(t, filepath.Join(node.Dir, "keystore", ".encryption"))
+
+		res := node.RunIPFS("key", "encrypt-store", "--passphrase-file", passFile)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "keystore is already encrypted")
+	})
+
+	t.Run("commands need the passphrase", func(t *testing.T) {
+		res := node.RunIPFS("key", "list")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "reading keystore passphrase")
+
+		node.Runner.Env["IPFS_KEYSTORE_PASSPHRASE"] = "wrong"
+		res = node.RunIPFS("key", "list")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "incorrect passphrase")
+
+		node.Runner.Env["IPFS_KEYSTORE_PASSPHRASE"] = "correct horse"
+		assert.Equal(t, "self\nfoo\n", node.IPFS("key", "list").Stdout.String())
+		assert.Equal(t, peerID, node.IPFS("id", "-f", "<id>").Stdout.Trimmed())
+		node.IPFS("name", "publish", "--allow-offline", "--key", "foo", "/ipfs/bafkqaaa")
+
+		// changing the config does not write the identity back
+		node.IPFS("config", "Datastore.StorageMax", "20GB")
+		assert.Empty(t, node.ReadConfig().Identity.PrivKey)
+		delete(node.Runner.Env, "IPFS_KEYSTORE_PASSPHRASE")
+	})
+
+	t.Run("daemon", func(t *testing.T) {
+		node.StartDaemon("--keystore-passphrase-file", passFile)
+		defer node.StopDaemon()
+		assert.Equal(t, peerID, node.IPFS("id", "-f", "<id>").Stdout.Trimmed())
+		node.IPFS("key", "gen", "bar")
+		node.IPFS("key", "rm", "bar")
+	})
+
+	t.Run("password-protected export", func(t *testing.T) {
+		exportPass := filepath.Join(node.Dir, "export-passphrase")
+		require.NoError(t, os.WriteFile(exportPass, []byte("export secret"), 0o600))
+		out := filepath.Join(node.Dir, "foo.key.enc")
+
+		node.Runner.Env["IPFS_KEYSTORE_PASSPHRASE_FILE"] = passFile
+		node.IPFS("key", "export", "foo", "--format=libp2p-protobuf-encrypted", "--passphrase-file", exportPass, "-o", out)
+		delete(node.Runner.Env, "IPFS_KEYSTORE_PASSPHRASE_FILE")
+
+		other := h.NewNode().Init()
+		res := other.RunIPFS("key", "import", "foo", out)
+		assert.Error(t, res.Err)
+		res = other.RunIPFS("key", "import", "foo", "--format=libp2p-protobuf-encrypted", "--passphrase-file", passFile, out)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "incorrect passphrase")
+		res = other.IPFS("key", "import", "foo", "--format=libp2p-protobuf-encrypted", "--passphrase-file", exportPass, out)
+		assert.Equal(t, fooID, res.Stdout.Trimmed())
+	})
+
+	t.Run("decrypt-store", func(t *testing.T) {
+		node.IPFS("key", "decrypt-store", "--passphrase-file", passFile)
+		assert.NotEmpty(t, node.ReadConfig().Identity.PrivKey)
+		assert.NoFileExists(t, filepath.Join(node.Dir, "keystore", ".encryption"))
+		assert.Equal(t, "self\nfoo\n", node.IPFS("key", "list").Stdout.String())
+		assert.Equal(t, peerID, node.IPFS("id", "-f", "<id>").Stdout.Trimmed())
+	})
+}
+
+func TestKeyMnemonic(t *testing.T) {
+	t.Parallel()
+
+	h := harness.NewT(t)
+	node := h.NewNode().Init()
+	fooID := node.IPFS("key", "gen", "foo").Stdout.Trimmed()
+
+	mnemonicFile := filepath.Join(node.Dir, "foo.mnemonic")
+	node.IPFS("key", "export", "foo", "--format=mnemonic", "-o", mnemonicFile)
+	mnemonic, err := os.ReadFile(mnemonicFile)
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(string(mnemonic)), 24)
+
+	other := h.NewNode().Init()
+
+	t.Run("import", func(t *testing.T) {
+		res := other.IPFS("key", "import", "foo", "--format=mnemonic", mnemonicFile)
+		assert.Equal(t, fooID, res.Stdout.Trimmed())
+	})
+
+	t.Run("gen --from-mnemonic", func(t *testing.T) {
+		res := other.IPFS("key", "gen", "bar", "--from-mnemonic", mnemonicFile)
+		assert.Equal(t, fooID, res.Stdout.Trimmed())
+
+		res = other.RunIPFS("key", "gen", "baz", "--type=rsa", "--from-mnemonic", mnemonicFile)
+		assert.Error(t, res.Err)
+
+		res = other.RunIPFS("key", "gen", "baz", "--from-mnemonic")
+		assert.Error(t, res.Err)
+
+		res = other.RunIPFS("key", "gen", "baz", mnemonicFile)
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "requires --from-mnemonic")
+
+		// the checksum of this mnemonic is incorrect
+		res = other.RunPipeToIPFS(strings.NewReader(strings.Repeat("abandon ", 24)), "key", "gen", "baz", "--from-mnemonic", "-")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "invalid mnemonic")
+	})
+
+	t.Run("gen --from-mnemonic with daemon", func(t *testing.T) {
+		other.StartDaemon()
+		defer other.StopDaemon()
+		res := other.PipeStrToIPFS(string(mnemonic), "key", "gen", "qux", "--from-mnemonic", "-")
+		assert.Equal(t, fooID, res.Stdout.Trimmed())
+	})
+
+	t.Run("rsa keys cannot be exported", func(t *testing.T) {
+		node.IPFS("key", "gen", "--type=rsa", "rsa")
+		res := node.RunIPFS("key", "export", "rsa", "--format=mnemonic")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "only Ed25519 keys")
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ipfs/boxo/ipns"
 	"github.com/ipfs/kubo/core/commands/name"
@@ -263,4 +264,132 @@ func TestName(t *testing.T) {
 		require.NoError(t, err)
 		require.False(t, val.Validation.Valid)
 	})
+
+	t.Run("History and rollback", func(t *testing.T) {
+		t.Parallel()
+
+		node := makeDaemon(t, nil)
+		ipnsName := ipns.NameFromPeer(node.PeerID()).String()
+		path0 := "/ipfs/" + fixtureCid
+		path1 := "/ipfs/" + fixtureCid + "/hello"
+
+		node.IPFS("name", "publish", "--allow-offline", path0)
+		node.IPFS("name", "publish", "--allow-offline", "--ttl=30m", path1)
+
+		res := node.IPFS("name", "history", "self", "--enc=json")
+		var hist name.IpnsHistory
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &hist))
+		require.Equal(t, ipnsName, hist.Name)
+		require.Len(t, hist.Entries, 2)
+		seq := hist.Entries[0].Sequence
+		require.Equal(t, path0, hist.Entries[0].Value)
+		require.Equal(t, seq+1, hist.Entries[1].Sequence)
+		require.Equal(t, path1, hist.Entries[1].Value)
+		require.Equal(t, 30*time.Minute, hist.Entries[1].TTL)
+
+		res = node.RunIPFS("name", "rollback", "self", fmt.Sprintf("--to=%d", seq+1), "--allow-offline")
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), "already points to")
+
+		res = node.RunIPFS("name", "rollback", "self", fmt.Sprintf("--to=%d", seq+5), "--allow-offline")
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), "no record with sequence number")
+
+		res = node.IPFS("name", "rollback", "self", fmt.Sprintf("--to=%d", seq), "--allow-offline")
+		require.Equal(t, fmt.Sprintf("Rolled back %s to %s (sequence %d)\n", ipnsName, path0, seq+2), res.Stdout.String())
+
+		res = node.IPFS("name", "resolve", "/ipns/"+ipnsName)
+		require.Equal(t, path0+"\n", res.Stdout.String())
+
+		res = node.IPFS("name", "history", ipnsName)
+		lines := strings.Split(strings.TrimSpace(res.Stdout.String()), "\n")
+		require.Len(t, lines, 3)
+		require.True(t, strings.HasPrefix(lines[2], fmt.Sprintf("%d ", seq+2)))
+		require.True(t, strings.HasSuffix(lines[2], path0))
+	})
+
+	t.Run("Record export, import and sign", func(t *testing.T) {
+		t.Parallel()
+
+		signer := makeDaemon(t, nil)
+		publisher := makeDaemon(t, nil)
+		publishPath := "/ipfs/" + fixtureCid
+		signedPath := "/ipfs/" + fixtureCid + "/hello"
+
+		res := signer.IPFS("key", "gen", "--type=ed25519", "mykey")
+		keyName, err := ipns.NameFromString(strings.TrimSpace(res.Stdout.String()))
+		require.NoError(t, err)
+		signer.IPFS("name", "publish", "--allow-offline", "--key=mykey", publishPath)
+
+		// Export and import the published record.
+		record := signer.IPFS("name", "record", "export", "mykey").Stdout.Bytes()
+		res = publisher.PipeToIPFS(bytes.NewReader(record), "name", "inspect", "--verify="+keyName.String(), "--enc=json")
+		var val name.IpnsInspectResult
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &val))
+		require.True(t, val.Validation.Valid)
+		require.Equal(t, publishPath, val.Entry.Value)
+
+		res = publisher.RunPipeToIPFS(bytes.NewReader(record), "name", "record", "import")
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), "does not embed its public key")
+
+		res = publisher.RunPipeToIPFS(bytes.NewReader(record), "name", "record", "import", "--name="+publisher.PeerID().String())
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), "invalid record")
+
+		res = publisher.PipeToIPFS(bytes.NewReader(record), "name", "record", "import", "--name="+keyName.String())
+		require.Equal(t, fmt.Sprintf("Imported record for %s: %s\n", keyName, publishPath), res.Stdout.String())
+		require.Equal(t, record, publisher.IPFS("name", "record", "export", keyName.String()).Stdout.Bytes())
+
+		// Sign a newer record offline, and put it to routing from the other node.
+		signed := signer.IPFS("name", "record", "sign", "--key=mykey", signedPath).Stdout.Bytes()
+		res = publisher.PipeToIPFS(bytes.NewReader(signed), "name", "inspect", "--verify="+keyName.String(), "--enc=json")
+		val = name.IpnsInspectResult{}
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &val))
+		require.True(t, val.Validation.Valid)
+		require.Equal(t, signedPath, val.Entry.Value)
+		require.Equal(t, *val.Entry.Sequence, uint64(1))
+
+		res = publisher.PipeToIPFS(bytes.NewReader(signed), "name", "record", "import", "--name="+keyName.String(), "--put", "--allow-offline")
+		require.Equal(t, fmt.Sprintf("Imported and put record for %s: %s\n", keyName, signedPath), res.Stdout.String())
+		res = publisher.IPFS("name", "resolve", "--offline", "/ipns/"+keyName.String())
+		require.Equal(t, signedPath+"\n", res.Stdout.String())
+
+		// Older records are rejected.
+		res = publisher.RunPipeToIPFS(bytes.NewReader(record), "name", "record", "import", "--name="+keyName.String())
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), "older than the stored record")
+	})
+
+	t.Run("Publish policy tracks an MFS path", func(t *testing.T) {
+		t.Parallel()
+
+		node := makeDaemon(t, nil)
+		node.Runner.Env["IPNS_PUBLISH_POLICY_POLL_INTERVAL"] = "50ms"
+		res := node.IPFS("key", "gen", "site")
+		siteName := strings.TrimSpace(res.Stdout.String())
+		node.IPFS("config", "--json", "Ipns.PublishPolicies", `{"site": {"MFS": "/published/site", "Debounce": "0s", "MinInterval": "0s"}}`)
+
+		res = node.IPFS("name", "publish-policy", "ls")
+		require.Equal(t, "site  /published/site  not published yet\n", res.Stdout.String())
+
+		node.StartDaemon()
+		defer node.StopDaemon()
+
+		node.IPFS("files", "mkdir", "-p", "/published/site")
+		node.PipeStrToIPFS("hello", "files", "write", "--create", "/published/site/index.html")
+		dirCid := node.IPFS("files", "stat", "--hash", "/published/site").Stdout.Trimmed()
+
+		var policies name.IpnsPublishPolicyList
+		require.Eventually(t, func() bool {
+			res := node.IPFS("name", "publish-policy", "ls", "--enc=json")
+			require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &policies))
+			return len(policies.Policies) == 1 && policies.Policies[0].Value == "/ipfs/"+dirCid
+		}, 10*time.Second, 50*time.Millisecond)
+		require.Equal(t, "site", policies.Policies[0].Key)
+		require.Equal(t, "/published/site", policies.Policies[0].MFS)
+
+		res = node.IPFS("name", "resolve", "--offline", "/ipns/"+siteName)
+		require.Equal(t, "/ipfs/"+dirCid+"\n", res.Stdout.String())
+	})
 }
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ipfs/kubo/core/commands/openapi"
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the CLI outputs the document without a repo", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode()
+		res := node.IPFS("commands", "openapi")
+
+		var doc openapi.Document
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &doc))
+		assert.Equal(t, openapi.Version, doc.OpenAPI)
+		require.Contains(t, doc.Paths, "/api/v0/pin/add")
+		assert.Equal(t, "pinAdd", doc.Paths["/api/v0/pin/add"].Post.OperationID)
+		assert.NotContains(t, doc.Paths, "/api/v0/commands/completion/bash")
+	})
+
+	t.Run("the daemon serves the document", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init().StartDaemon()
+		defer node.StopDaemon()
+
+		resp, err := http.Get(node.APIURL() + "/api/v0/openapi.json")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		served, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		var doc openapi.Document
+		require.NoError(t, json.Unmarshal(served, &doc))
+		require.Contains(t, doc.Paths, "/api/v0/id")
+
+		// the CLI generates the same document
+		assert.JSONEq(t, node.IPFS("commands", "openapi").Stdout.String(), string(served))
+	})
+}
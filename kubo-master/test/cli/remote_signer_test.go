@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+
+	keystore "github.com/ipfs/boxo/keystore"
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/commands"
+	"github.com/ipfs/kubo/keystore/remotesigner"
+	"github.com/ipfs/kubo/test/cli/harness"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	t.Parallel()
+
+	h := harness.NewT(t)
+	node := h.NewNode().Init()
+	peerID := node.PeerID().String()
+
+	// move the identity and a key to the signer
+	backend := keystore.NewMemKeystore()
+	self, err := node.ReadConfig().Identity.DecodePrivateKey("")
+	require.NoError(t, err)
+	require.NoError(t, backend.Put(remotesigner.SelfKey, self))
+	foo, _, err := ci.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	require.NoError(t, backend.Put("foo", foo))
+	fooID, err := peer.IDFromPrivateKey(foo)
+	require.NoError(t, err)
+
+	socket := filepath.Join(node.Dir, "signer.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go remotesigner.Serve(l, backend)
+
+	node.UpdateConfig(func(cfg *config.Config) {
+		cfg.Identity.PrivKey = ""
+		cfg.Keystore.RemoteSigner = config.NewOptionalString(socket)
+	})
+
+	t.Run("keys and signatures", func(t *testing.T) {
+		assert.Equal(t, "self\nfoo\n", node.IPFS("key", "list").Stdout.String())
+		assert.Equal(t, peerID, node.IPFS("id", "-f", "<id>").Stdout.Trimmed())
+
+		res := node.RunIPFS("key", "gen", "bar")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "keys are managed by the remote signer")
+
+		res = node.PipeStrToIPFS("hello", "key", "sign", "--key", "foo", "--enc=json")
+		var signed commands.KeySignOutput
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &signed))
+		res = node.PipeStrToIPFS("hello", "key", "verify", "--key", "foo", "--signature", signed.Signature, "--enc=json")
+		var verified commands.KeyVerifyOutput
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &verified))
+		assert.True(t, verified.SignatureValid)
+
+		node.IPFS("name", "publish", "--allow-offline", "--key", "foo", "/ipfs/bafkqaaa")
+		node.IPFS("name", "publish", "--allow-offline", "/ipfs/bafkqaaa")
+		assert.Equal(t, "/ipfs/bafkqaaa", node.IPFS("name", "resolve", "--offline", fooID.String()).Stdout.Trimmed())
+	})
+
+	t.Run("daemon", func(t *testing.T) {
+		node.StartDaemon()
+		defer node.StopDaemon()
+		assert.Equal(t, peerID, node.IPFS("id", "-f", "<id>").Stdout.Trimmed())
+		node.PipeStrToIPFS("hello", "key", "sign", "--key", "foo")
+	})
+
+	t.Run("signer unavailable", func(t *testing.T) {
+		l.Close()
+		res := node.RunPipeToIPFS(nil, "key", "list")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "connecting to remote signer")
+	})
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/auditlog"
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCAuditLog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("requests are logged and queryable", func(t *testing.T) {
+		t.Parallel()
+
+		node := harness.NewT(t).NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.API.Authorizations = map[string]*config.RPCAuthScope{
+				"admin": {
+					AuthSecret:   "bearer:admin-token",
+					AllowedPaths: []string{"/api/v0"},
+				},
+				"ci": {
+					AuthSecret:   "bearer:ci-token",
+					AllowedPaths: []string{"/api/v0/pin"},
+				},
+			}
+			cfg.API.AuditLog.Enabled = config.True
+		})
+		node.StartDaemonWithAuthorization("Bearer admin-token")
+		defer node.StopDaemon()
+
+		admin := func(args ...string) *harness.RunResult {
+			return node.RunIPFS(append(args, "--api-auth", "bearer:admin-token")...)
+		}
+		ci := func(args ...string) *harness.RunResult {
+			return node.RunIPFS(append(args, "--api-auth", "bearer:ci-token")...)
+		}
+
+		require.NoError(t, ci("pin", "add", "bafkqaaa").Err)
+		require.NoError(t, ci("pin", "rm", "bafkqaaa").Err)
+		require.Error(t, ci("key", "rm", "self").Err)
+		require.NoError(t, admin("pin", "remote", "service", "add", "svc", "https://pin.example.com", "s3cr3t").Err)
+		require.Error(t, admin("config", "Pinning.RemoteServices.svc.API.Key", "s3cr3t").Err)
+
+		res := admin("diag", "audit", "--user", "ci", "--command", "pin/rm", "--enc", "json")
+		require.NoError(t, res.Err)
+		var entry auditlog.Entry
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &entry))
+		require.Equal(t, "ci", entry.User)
+		require.Equal(t, "pin/rm", entry.Command)
+		require.Equal(t, []string{"bafkqaaa"}, entry.Args)
+		require.Equal(t, auditlog.OutcomeSuccess, entry.Outcome)
+
+		res = admin("diag", "audit", "--outcome", "denied")
+		require.NoError(t, res.Err)
+		lines := strings.Split(strings.TrimSpace(res.Stdout.String()), "\n")
+		require.Len(t, lines, 1)
+		require.Contains(t, lines[0], " ci denied key/rm 403 ")
+
+		res = admin("diag", "audit", "--user", "admin", "--command", "config")
+		require.NoError(t, res.Err)
+		require.Contains(t, res.Stdout.String(), " admin error config 500 ")
+		require.Contains(t, res.Stdout.String(), auditlog.Redacted)
+		require.NotContains(t, res.Stdout.String(), "s3cr3t")
+
+		res = admin("diag", "audit", "--command", "pin/remote/service/add", "--enc", "json")
+		require.NoError(t, res.Err)
+		entry = auditlog.Entry{}
+		require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &entry))
+		require.Equal(t, []string{"svc", "https://pin.example.com", auditlog.Redacted}, entry.Args)
+		require.Equal(t, []string{auditlog.Redacted}, entry.Options["api-auth"])
+		require.NotContains(t, res.Stdout.String(), "admin-token")
+
+		// the log is also readable when the daemon is stopped
+		node.StopDaemon()
+		res = node.RunIPFS("diag", "audit", "--command", "pin", "--since", "1h")
+		require.NoError(t, res.Err)
+		require.Len(t, strings.Split(strings.TrimSpace(res.Stdout.String()), "\n"), 3)
+	})
+
+	t.Run("disabled audit log", func(t *testing.T) {
+		t.Parallel()
+
+		node := harness.NewT(t).NewNode().Init()
+		res := node.RunIPFS("diag", "audit")
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), "API.AuditLog.Enabled")
+	})
+}
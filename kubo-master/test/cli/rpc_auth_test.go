@@ -1,8 +1,17 @@
 package cli
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
 
 	"github.com/ipfs/kubo/client/rpc/auth"
 	"github.com/ipfs/kubo/config"
@@ -128,6 +137,135 @@ func TestRPCAuth(t *testing.T) {
 		node.StopDaemon()
 	})
 
+	t.Run("Roles restrict commands and arguments", func(t *testing.T) {
+		t.Parallel()
+
+		h := harness.NewT(t)
+		node := h.NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.API.Authorizations = map[string]*config.RPCAuthScope{
+				"test-node-starter": {
+					AuthSecret:   "bearer:test-node-starter",
+					AllowedPaths: []string{"/api/v0"},
+				},
+				"ci": {
+					AuthSecret: "bearer:ci-token",
+					Roles:      []string{"ci"},
+				},
+			}
+			cfg.API.Roles = map[string]*config.RPCRole{
+				"ci": {
+					Allow: []config.RPCRule{
+						{Path: "/api/v0/pin", Methods: []string{"POST"}},
+						{Path: "/api/v0/files", Args: []string{"/ci", "/ci/*"}},
+					},
+					Deny: []config.RPCRule{
+						{Path: "/api/v0/pin/rm"},
+					},
+				},
+			}
+		})
+		node.StartDaemonWithAuthorization("Bearer test-node-starter")
+		defer node.StopDaemon()
+
+		ci := func(args ...string) *harness.RunResult {
+			return node.RunIPFS(append(args, "--api-auth", "bearer:ci-token")...)
+		}
+
+		require.NoError(t, ci("pin", "add", "bafkqaaa").Err)
+		res := ci("pin", "rm", "bafkqaaa")
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), rpcDeniedMsg)
+
+		require.NoError(t, ci("files", "mkdir", "-p", "/ci/build").Err)
+		require.NoError(t, ci("files", "ls", "/ci").Err)
+		for _, args := range [][]string{
+			{"files", "ls"},
+			{"files", "ls", "/"},
+			{"files", "mkdir", "/other"},
+			{"files", "cp", "/ci/build", "/other"},
+			{"files", "ls", "/ci/../other"},
+			{"id"},
+		} {
+			res := ci(args...)
+			require.Error(t, res.Err, args)
+			require.Contains(t, res.Stderr.String(), rpcDeniedMsg, args)
+		}
+	})
+
+	t.Run("JWT verified against a local JWKS", func(t *testing.T) {
+		t.Parallel()
+
+		_, sk, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: sk.Public(), KeyID: "k1", Algorithm: string(jose.EdDSA), Use: "sig"},
+		}})
+		require.NoError(t, err)
+
+		node := harness.NewT(t).NewNode().Init()
+		jwksPath := filepath.Join(node.Dir, "jwks.json")
+		require.NoError(t, os.WriteFile(jwksPath, jwks, 0o600))
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.API.Authorizations = map[string]*config.RPCAuthScope{
+				"test-node-starter": {
+					AuthSecret:   "bearer:test-node-starter",
+					AllowedPaths: []string{"/api/v0"},
+				},
+				"drive": {
+					AllowedPaths: []string{"/api/v0/id"},
+					JWT: &config.RPCJWT{
+						JWKS:       jwksPath,
+						Issuer:     "https://idp.example.com",
+						Audience:   "kubo",
+						PathsClaim: config.NewOptionalString("kubo_paths"),
+					},
+				},
+			}
+		})
+		node.StartDaemonWithAuthorization("Bearer test-node-starter")
+		defer node.StopDaemon()
+
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.EdDSA, Key: sk},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "k1"))
+		require.NoError(t, err)
+		sign := func(expiry time.Time, paths ...string) string {
+			token, err := jwt.Signed(signer).Claims(jwt.Claims{
+				Issuer:   "https://idp.example.com",
+				Subject:  "alice",
+				Audience: jwt.Audience{"kubo"},
+				Expiry:   jwt.NewNumericDate(expiry),
+			}).Claims(map[string]interface{}{"kubo_paths": paths}).Serialize()
+			require.NoError(t, err)
+			return "bearer:" + token
+		}
+
+		token := sign(time.Now().Add(time.Hour), "/api/v0/config/show")
+		require.NoError(t, node.RunIPFS("id", "--api-auth", token).Err)
+		require.NoError(t, node.RunIPFS("config", "show", "--api-auth", token).Err)
+		res := node.RunIPFS("config", "Addresses", "--api-auth", token)
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), rpcDeniedMsg)
+
+		res = node.RunIPFS("id", "--api-auth", sign(time.Now().Add(-time.Hour)))
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), rpcDeniedMsg)
+	})
+
+	t.Run("Undefined role prevents the daemon from starting", func(t *testing.T) {
+		t.Parallel()
+
+		node := harness.NewT(t).NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.API.Authorizations = map[string]*config.RPCAuthScope{
+				"ci": {AuthSecret: "bearer:ci-token", Roles: []string{"missing"}},
+			}
+		})
+		res := node.RunIPFS("daemon")
+		require.Error(t, res.Err)
+		require.Contains(t, res.Stderr.String(), `role "missing"`)
+	})
+
 	t.Run("API.Authorizations set to nil disables Authorization header check", func(t *testing.T) {
 		t.Parallel()
 
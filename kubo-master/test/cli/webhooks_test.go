@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core/commands"
+	"github.com/ipfs/kubo/core/events"
+	"github.com/ipfs/kubo/core/webhooks"
+	"github.com/ipfs/kubo/test/cli/harness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookEvent struct {
+	webhooks.Event
+	Signature string
+	Valid     bool
+}
+
+// runWebhookReceiver runs an HTTP server recording the events it receives,
+// signed with the given secret.
+func runWebhookReceiver(t *testing.T, secret string) (func() []webhookEvent, string) {
+	var lock sync.Mutex
+	var received []webhookEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var ev webhookEvent
+		require.NoError(t, json.Unmarshal(body, &ev))
+		ev.Signature = r.Header.Get(webhooks.SignatureHeader)
+		ev.Valid = ev.Signature == webhooks.Sign([]byte(secret), body)
+		lock.Lock()
+		received = append(received, ev)
+		lock.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	return func() []webhookEvent {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]webhookEvent(nil), received...)
+	}, srv.URL
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+
+	waitEvent := func(t *testing.T, received func() []webhookEvent, typ string) webhookEvent {
+		t.Helper()
+		var found webhookEvent
+		require.Eventually(t, func() bool {
+			for _, ev := range received() {
+				if ev.Type == typ {
+					found = ev
+					return true
+				}
+			}
+			return false
+		}, 20*time.Second, 50*time.Millisecond, "no %s event", typ)
+		return found
+	}
+
+	t.Run("the daemon sends signed events", func(t *testing.T) {
+		t.Parallel()
+		received, url := runWebhookReceiver(t, "s3cr3t")
+		_, svcURL := runPinningService(t, "token")
+		node := harness.NewT(t).NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.Webhooks = map[string]*config.Webhook{
+				"backend": {URL: url, Secret: "s3cr3t"},
+			}
+		})
+		node.IPFS("pin", "remote", "service", "add", "svc", svcURL, "token")
+		node.StartDaemon()
+		defer node.StopDaemon()
+
+		cid := node.IPFSAddStr("notified")
+		node.IPFS("pin", "rm", cid)
+		node.IPFS("pin", "add", "--name=mypin", cid)
+		ev := waitEvent(t, received, events.PinAdded)
+		assert.True(t, ev.Valid)
+		assert.Equal(t, node.PeerID().String(), ev.Node)
+		pinned := ev.Data.(events.PinEvent)
+		assert.Equal(t, cid, pinned.Cid)
+		assert.Equal(t, "mypin", pinned.Name)
+
+		res := node.RunIPFS("pin", "add", "--timeout=1s", "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+		assert.Error(t, res.Err)
+		ev = waitEvent(t, received, events.PinFailed)
+		assert.NotEmpty(t, ev.Data.(events.PinEvent).Error)
+
+		node.IPFS("pin", "rm", cid)
+		node.IPFS("repo", "gc")
+		ev = waitEvent(t, received, events.GCCompleted)
+		assert.GreaterOrEqual(t, ev.Data.(events.GCEvent).Removed, 1)
+
+		node.IPFS("pin", "remote", "add", "--service=svc", "--background", cid)
+		ev = waitEvent(t, received, events.RemotePinStatus)
+		remote := ev.Data.(events.RemotePinEvent)
+		assert.Equal(t, "svc", remote.Service)
+		assert.Equal(t, cid, remote.Cid)
+		assert.Equal(t, "queued", remote.Status)
+
+		var st webhooks.Status
+		require.Eventually(t, func() bool {
+			var list commands.WebhookList
+			res := node.IPFS("webhooks", "ls", "--enc=json")
+			require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &list))
+			require.Len(t, list.Webhooks, 1)
+			st = list.Webhooks[0]
+			return st.Delivered == len(received())
+		}, 20*time.Second, 100*time.Millisecond)
+		assert.Equal(t, "backend", st.Name)
+		assert.Zero(t, st.Failed)
+
+		res = node.IPFS("webhooks", "ls", "-v")
+		assert.Contains(t, res.Stdout.String(), events.GCCompleted)
+		assert.Contains(t, res.Stdout.String(), webhooks.DeliverySucceeded)
+
+		// the secret is concealed
+		assert.NotContains(t, node.IPFS("config", "show").Stdout.String(), "s3cr3t")
+	})
+
+	t.Run("events are filtered, and failed deliveries are listed", func(t *testing.T) {
+		t.Parallel()
+		received, url := runWebhookReceiver(t, "")
+		node := harness.NewT(t).NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.Webhooks = map[string]*config.Webhook{
+				"gc": {URL: url, Events: []string{"gc.*"}},
+				"down": {
+					URL:          "http://127.0.0.1:1/unreachable",
+					Events:       []string{events.PinAdded, events.GCCompleted},
+					MaxRetries:   config.NewOptionalInteger(1),
+					RetryBackoff: config.NewOptionalDuration(10 * time.Millisecond),
+				},
+			}
+		})
+		node.StartDaemon()
+		defer node.StopDaemon()
+
+		node.IPFS("pin", "add", node.IPFSAddStr("filtered"))
+		node.IPFS("repo", "gc")
+		ev := waitEvent(t, received, events.GCCompleted)
+		assert.Empty(t, ev.Signature)
+		for _, ev := range received() {
+			assert.Contains(t, []string{events.GCStarted, events.GCCompleted}, ev.Type)
+		}
+
+		var down webhooks.Status
+		require.Eventually(t, func() bool {
+			var list commands.WebhookList
+			res := node.IPFS("webhooks", "ls", "--enc=json")
+			require.NoError(t, json.Unmarshal(res.Stdout.Bytes(), &list))
+			require.Len(t, list.Webhooks, 2)
+			down = list.Webhooks[0]
+			return down.Failed == 2
+		}, 20*time.Second, 100*time.Millisecond)
+		assert.Equal(t, "down", down.Name)
+		assert.Zero(t, down.Delivered)
+		assert.Equal(t, 2, down.Recent[0].Attempts)
+		assert.NotEmpty(t, down.Recent[0].Error)
+	})
+
+	t.Run("commands without a daemon do not send events", func(t *testing.T) {
+		t.Parallel()
+		received, url := runWebhookReceiver(t, "")
+		node := harness.NewT(t).NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.Webhooks = map[string]*config.Webhook{"all": {URL: url}}
+		})
+
+		node.IPFS("repo", "gc")
+		res := node.RunIPFS("webhooks", "ls")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), "running daemon")
+		assert.Empty(t, received())
+	})
+
+	t.Run("invalid webhooks prevent the daemon from starting", func(t *testing.T) {
+		t.Parallel()
+		node := harness.NewT(t).NewNode().Init()
+		node.UpdateConfig(func(cfg *config.Config) {
+			cfg.Webhooks = map[string]*config.Webhook{"bad": {URL: "ftp://example.com"}}
+		})
+
+		res := node.RunIPFS("daemon")
+		assert.Error(t, res.Err)
+		assert.Contains(t, res.Stderr.String(), `webhook "bad": invalid URL`)
+	})
+}